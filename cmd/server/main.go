@@ -46,6 +46,9 @@ const (
 	TosEnvVar     = "EKO_SERVER_TOS_FILE"
 	PrivacyEnvVar = "EKO_SERVER_PRIVACY_FILE"
 	LogDirEnvVar  = "EKO_SERVER_LOG_DIR"
+
+	BlobDirEnvVar            = "EKO_SERVER_BLOB_DIR"
+	MaxAttachmentBytesEnvVar = "EKO_SERVER_MAX_ATTACHMENT_BYTES"
 )
 
 var prod = true
@@ -91,6 +94,14 @@ func main() {
 		return
 	}
 
+	if ok := setupBlobStore(); !ok {
+		return
+	}
+
+	if ok := server.ReloadRequestCosts(); !ok {
+		return
+	}
+
 	api.ConnectToDatabase()
 	assert.AddFlush(api.DB())
 	defer api.DB().Close()
@@ -112,6 +123,7 @@ func handleReload() {
 		for range c {
 			slog.Info("SIGHUP received, reloading...")
 			reloadTosAndPrivacy()
+			server.ReloadRequestCosts()
 			slog.Info("reload completed")
 		}
 	}()
@@ -182,6 +194,28 @@ func setupLogging() {
 	}()
 }
 
+func setupBlobStore() bool {
+	if blobDir := os.Getenv(BlobDirEnvVar); blobDir != "" {
+		api.BlobDir = blobDir
+	}
+	if maxBytes := os.Getenv(MaxAttachmentBytesEnvVar); maxBytes != "" {
+		n, err := strconv.ParseInt(maxBytes, 10, 64)
+		if err != nil || n < 0 {
+			slog.Error("invalid max attachment bytes", "env", MaxAttachmentBytesEnvVar, "value", maxBytes)
+			return false
+		}
+		api.MaxAttachmentBytes = n
+	}
+
+	if err := api.SetupBlobStore(); err != nil {
+		slog.Error("unable to setup blob store", "error", err, "dir", api.BlobDir)
+		return false
+	}
+
+	slog.Info("blob store ready", "dir", api.BlobDir, "max_attachment_bytes", api.MaxAttachmentBytes)
+	return true
+}
+
 func reloadTosAndPrivacy() bool {
 	if embeds.TosPrivacyHash.Load() == nil {
 		if prod {
@@ -86,6 +86,7 @@
             src = src;
             buildInputs = with pkgs; [ goose go-tools gosec ];
             ldflags = ldflags;
+            tags = [ "sqlite_fts5" ]; # Message search
             modRoot = "./.";
             subPackages = [ "cmd/server" ];
             doCheck = false;
@@ -21,9 +21,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 
 	"github.com/kyren223/eko/pkg/assert"
 )
@@ -32,12 +34,18 @@ type Cache struct {
 	// Key is a server URL like "eko.kyren.codes" and value is the base 16 hash
 	TosHashes map[string]string `json:"tos_hashes"`
 	DeviceID  string            `json:"device_id"`
+	// Key is a server host and port like "eko.kyren.codes:7223" and value is
+	// the base 16 SHA-256 of its certificate
+	// Only populated for servers using TrustOnFirstUse
+	PinnedCertificates map[string]string `json:"pinned_certificates"`
 }
 
 func DefaultCache() Cache {
 	return Cache{
 		TosHashes: map[string]string{},
 		DeviceID:  GenerateDeviceID(),
+
+		PinnedCertificates: map[string]string{},
 	}
 }
 
@@ -45,6 +53,23 @@ func VerifyAndFixCache(cache *Cache) error {
 	if cache.DeviceID == "" {
 		cache.DeviceID = GenerateDeviceID()
 	}
+	if cache.TosHashes == nil {
+		cache.TosHashes = map[string]string{}
+	}
+	if cache.PinnedCertificates == nil {
+		cache.PinnedCertificates = map[string]string{}
+	}
+	// Pins used to be keyed by host alone, when every server used the default port
+	for key, fingerprint := range cache.PinnedCertificates {
+		if _, _, err := net.SplitHostPort(key); err == nil {
+			continue
+		}
+		delete(cache.PinnedCertificates, key)
+		address := net.JoinHostPort(key, strconv.Itoa(DefaultPort))
+		if _, ok := cache.PinnedCertificates[address]; !ok {
+			cache.PinnedCertificates[address] = fingerprint
+		}
+	}
 	return nil
 }
 
@@ -20,15 +20,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 
 	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/pkg/assert"
 )
 
 type Config struct {
-	ServerName               string   `json:"server_name"`
+	// Name of the selected profile in Servers
+	ServerName string `json:"server_name"`
+	// Private key used for servers that don't remember their own
 	PrivateKeyPath           string   `json:"private_key_path"`
 	InsertModeTabToSpace     bool     `json:"insert_mode_tab_to_space"`
 	InsertModeSpacesPerTab   uint8    `json:"insert_mode_spaces_per_tab"`
@@ -36,6 +41,79 @@ type Config struct {
 	Colors                   []string `json:"colors"`
 	AnonymousDeviceAnalytics bool     `json:"anonymous_device_analytics"`
 	ScreenBorders            bool     `json:"screen_borders"`
+	MarkdownMessages         bool     `json:"markdown_messages"`
+	// Key is a server host like "eko.kyren.codes", hosts not present use TrustSystem
+	ServerTrust map[string]ServerTrust `json:"server_trust"`
+	Servers     []ServerProfile        `json:"servers"`
+}
+
+const DefaultPort = 7223
+
+type ServerProfile struct {
+	// Unique name shown in the server picker
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+	// Private key remembered for this server, falls back to Config.PrivateKeyPath
+	PrivateKeyPath string `json:"private_key_path"`
+	// Username prefilled when signing up
+	Username string `json:"username"`
+}
+
+func (p ServerProfile) Address() string {
+	return net.JoinHostPort(p.Host, strconv.Itoa(int(p.Port)))
+}
+
+// Server returns the selected server profile
+func (c Config) Server() ServerProfile {
+	for _, server := range c.Servers {
+		if server.Name == c.ServerName {
+			return server
+		}
+	}
+	assert.Never("selected server should always exist", "server", c.ServerName)
+	return ServerProfile{}
+}
+
+// PrivateKey returns the private key path remembered for the selected server
+func (c Config) PrivateKey() string {
+	if path := c.Server().PrivateKeyPath; path != "" {
+		return path
+	}
+	return c.PrivateKeyPath
+}
+
+// RememberPrivateKey sets the private key path of the selected server
+func (c *Config) RememberPrivateKey(path string) {
+	for i := range c.Servers {
+		if c.Servers[i].Name == c.ServerName {
+			c.Servers[i].PrivateKeyPath = path
+		}
+	}
+}
+
+const (
+	// Only trust the certificate bundled with eko (the official server)
+	TrustEmbedded = "embedded"
+	// Trust any certificate signed by one of the operating system's CAs
+	TrustSystem = "system"
+	// Trust any certificate signed by the CA at ServerTrust.CAPath
+	TrustCustomCA = "ca"
+	// Trust on first use, pin the certificate fingerprint and refuse changes
+	TrustOnFirstUse = "tofu"
+)
+
+type ServerTrust struct {
+	Mode   string `json:"mode"`
+	CAPath string `json:"ca_path,omitempty"`
+}
+
+func (c Config) Trust(host string) ServerTrust {
+	trust, ok := c.ServerTrust[host]
+	if !ok {
+		return ServerTrust{Mode: TrustSystem}
+	}
+	return trust
 }
 
 func DefaultConfig() Config {
@@ -48,6 +126,15 @@ func DefaultConfig() Config {
 		Colors:                   nil,
 		AnonymousDeviceAnalytics: true,
 		ScreenBorders:            true,
+		MarkdownMessages:         true,
+		ServerTrust: map[string]ServerTrust{
+			"eko.kyren.codes": {Mode: TrustEmbedded},
+		},
+		Servers: []ServerProfile{{
+			Name: "eko.kyren.codes",
+			Host: "eko.kyren.codes",
+			Port: DefaultPort,
+		}},
 	}
 }
 
@@ -56,6 +143,50 @@ func VerifyAndFixConfig(config *Config) error {
 		config.ServerName = DefaultConfig().ServerName
 	}
 
+	names := map[string]bool{}
+	for i, server := range config.Servers {
+		if server.Host == "" {
+			return fmt.Errorf("server at %v has no host", i)
+		}
+		if server.Name == "" {
+			server.Name = server.Host
+		}
+		if server.Port == 0 {
+			server.Port = DefaultPort
+		}
+		if names[server.Name] {
+			return fmt.Errorf("server name %v is used by more than one server", server.Name)
+		}
+		names[server.Name] = true
+		config.Servers[i] = server
+	}
+	if !names[config.ServerName] {
+		// Configs from before server profiles only have a server name
+		config.Servers = append(config.Servers, ServerProfile{
+			Name: config.ServerName,
+			Host: config.ServerName,
+			Port: DefaultPort,
+		})
+	}
+
+	if config.ServerTrust == nil {
+		config.ServerTrust = map[string]ServerTrust{}
+	}
+	for server, trust := range config.ServerTrust {
+		switch trust.Mode {
+		case TrustEmbedded, TrustSystem, TrustOnFirstUse:
+		case TrustCustomCA:
+			if trust.CAPath == "" {
+				return fmt.Errorf("server trust for %v uses %q but has no ca_path", server, TrustCustomCA)
+			}
+		default:
+			return fmt.Errorf(
+				"server trust for %v has unknown mode %q, expected one of %q, %q, %q or %q",
+				server, trust.Mode, TrustEmbedded, TrustSystem, TrustCustomCA, TrustOnFirstUse,
+			)
+		}
+	}
+
 	if config.Colors != nil {
 		for i, color := range config.Colors {
 			if !colors.IsHex(color) {
@@ -0,0 +1,133 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package e2ee implements end-to-end encryption of direct messages.
+//
+// Each user's ed25519 identity is converted to an X25519 key, the shared
+// secret between two users is used to derive an XChaCha20-Poly1305 key.
+// Encrypted content is the base64 encoding of the nonce followed by
+// the ciphertext and tag.
+package e2ee
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	ErrInvalidContent   = errors.New("invalid encrypted content")
+
+	keyInfo = []byte("eko e2ee direct message key")
+)
+
+// SharedKey derives the symmetric key shared between the owner of the
+// private key and the owner of the public key, both sides derive the same key
+func SharedKey(privKey ed25519.PrivateKey, peer ed25519.PublicKey) ([]byte, error) {
+	peerX25519, err := x25519PublicKey(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := curve25519.X25519(x25519PrivateKey(privKey), peerX25519)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind the key to both identities, sorted so both sides agree
+	self := privKey.Public().(ed25519.PublicKey)
+	salt := slices.Concat([]byte(self), []byte(peer))
+	if slices.Compare([]byte(self), []byte(peer)) > 0 {
+		salt = slices.Concat([]byte(peer), []byte(self))
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	_, err = io.ReadFull(hkdf.New(sha256.New, secret, salt, keyInfo), key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt encrypts the plaintext, returning the content to send
+func Encrypt(key []byte, plaintext string) (string, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts content previously returned by Encrypt
+func Decrypt(key []byte, content string) (string, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", ErrInvalidContent
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return "", ErrInvalidContent
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// x25519PrivateKey returns the clamped X25519 scalar of the ed25519
+// private key, which is the same scalar ed25519 signs with (RFC 8032)
+func x25519PrivateKey(privKey ed25519.PrivateKey) []byte {
+	hash := sha512.Sum512(privKey.Seed())
+	scalar := hash[:curve25519.ScalarSize]
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+	return scalar
+}
+
+// x25519PublicKey converts the ed25519 point to its montgomery form
+func x25519PublicKey(pubKey ed25519.PublicKey) ([]byte, error) {
+	point, err := new(edwards25519.Point).SetBytes(pubKey)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return point.BytesMontgomery(), nil
+}
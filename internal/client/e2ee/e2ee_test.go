@@ -0,0 +1,135 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package e2ee
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return pubKey, privKey
+}
+
+func TestX25519Conversion(t *testing.T) {
+	for range 10 {
+		pubKey, privKey := generateKey(t)
+
+		expected, err := curve25519.X25519(x25519PrivateKey(privKey), curve25519.Basepoint)
+		require.NoError(t, err)
+		actual, err := x25519PublicKey(pubKey)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+}
+
+func TestSharedKey(t *testing.T) {
+	alicePub, alicePriv := generateKey(t)
+	bobPub, bobPriv := generateKey(t)
+	evePub, _ := generateKey(t)
+
+	aliceKey, err := SharedKey(alicePriv, bobPub)
+	require.NoError(t, err)
+	bobKey, err := SharedKey(bobPriv, alicePub)
+	require.NoError(t, err)
+	require.Equal(t, aliceKey, bobKey)
+
+	eveKey, err := SharedKey(alicePriv, evePub)
+	require.NoError(t, err)
+	require.NotEqual(t, aliceKey, eveKey)
+}
+
+func TestSharedKeyInvalidPublicKey(t *testing.T) {
+	_, privKey := generateKey(t)
+
+	_, err := SharedKey(privKey, ed25519.PublicKey{1, 2, 3})
+	require.ErrorIs(t, err, ErrInvalidPublicKey)
+
+	// y = 2 isn't on the curve
+	notOnCurve := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	notOnCurve[0] = 2
+	_, err = SharedKey(privKey, notOnCurve)
+	require.ErrorIs(t, err, ErrInvalidPublicKey)
+
+	// The identity point is low order, there is no shared secret
+	identity := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	identity[0] = 1
+	_, err = SharedKey(privKey, identity)
+	require.Error(t, err)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	_, alicePriv := generateKey(t)
+	bobPub, _ := generateKey(t)
+	key, err := SharedKey(alicePriv, bobPub)
+	require.NoError(t, err)
+
+	for _, plaintext := range []string{"", "hello", "multi\nline ✨ message"} {
+		content, err := Encrypt(key, plaintext)
+		require.NoError(t, err)
+		require.NotContains(t, content, plaintext+"\x00")
+
+		decrypted, err := Decrypt(key, content)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+
+	// Nonces are random, the same plaintext never encrypts the same way
+	first, err := Encrypt(key, "hello")
+	require.NoError(t, err)
+	second, err := Encrypt(key, "hello")
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	_, alicePriv := generateKey(t)
+	bobPub, _ := generateKey(t)
+	key, err := SharedKey(alicePriv, bobPub)
+	require.NoError(t, err)
+
+	content, err := Encrypt(key, "hello")
+	require.NoError(t, err)
+	raw, err := base64.StdEncoding.DecodeString(content)
+	require.NoError(t, err)
+
+	for i := range raw {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+		_, err := Decrypt(key, base64.StdEncoding.EncodeToString(tampered))
+		require.Error(t, err, "tampered byte %v", i)
+	}
+
+	_, err = Decrypt(key, base64.StdEncoding.EncodeToString(raw[:len(raw)-1]))
+	require.Error(t, err)
+	_, err = Decrypt(key, "not base64!")
+	require.ErrorIs(t, err, ErrInvalidContent)
+	_, err = Decrypt(key, base64.StdEncoding.EncodeToString(raw[:10]))
+	require.ErrorIs(t, err, ErrInvalidContent)
+
+	otherKey := append([]byte(nil), key...)
+	otherKey[0] ^= 0x01
+	_, err = Decrypt(otherKey, content)
+	require.Error(t, err)
+}
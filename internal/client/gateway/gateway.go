@@ -19,16 +19,15 @@ package gateway
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"log"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
-	"github.com/kyren223/eko/embeds"
 	"github.com/kyren223/eko/internal/client/config"
 	"github.com/kyren223/eko/internal/client/ui"
 	"github.com/kyren223/eko/internal/packet"
@@ -72,32 +71,29 @@ func connect(ctx context.Context) error {
 	go func() {
 		framer = packet.NewFramer()
 
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(embeds.ServerCertificate) {
-			log.Fatalln("failed to append server certificate")
-		}
-
-		tlsConfig := &tls.Config{
-			RootCAs:    certPool,
-			ServerName: config.ReadConfig().ServerName,
-			MinVersion: tls.VersionTLS12,
-			// This is fine, it's always false by default
-			// The user may change the config, the name should be clear enough
-			// that this is insecure (valid use cases are for testing purposes)
-			InsecureSkipVerify: config.ReadConfig().InsecureDebugMode, // #nosec 402
+		server := config.ReadConfig().Server()
+		tlsConfig, err := trustConfig(server.Host, server.Port)
+		if err != nil {
+			errChan <- err
+			return
 		}
 
-		address := config.ReadConfig().ServerName
+		address := server.Address()
 		if config.ReadConfig().InsecureDebugMode {
-			address = "localhost"
+			address = net.JoinHostPort("localhost", strconv.Itoa(int(server.Port)))
 		}
 
-		connection, err := tls.Dial("tcp4", address+":7223", tlsConfig)
+		connection, err := tls.Dial("tcp", address, tlsConfig)
 		if err != nil {
 			errChan <- err
 			return
 		}
-		log.Println("established connection with the server")
+		if err := pinCertificate(server.Host, server.Port, connection); err != nil {
+			_ = connection.Close()
+			errChan <- err
+			return
+		}
+		log.Println("established connection with", address)
 
 		connChan <- connection
 	}()
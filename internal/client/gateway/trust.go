@@ -0,0 +1,135 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/kyren223/eko/embeds"
+	"github.com/kyren223/eko/internal/client/config"
+)
+
+// CertificateMismatch is returned when a server using trust on first use
+// presents a different certificate than the one pinned in the cache.
+// This may mean someone is intercepting the connection, so it is never retried.
+type CertificateMismatch struct {
+	Server   string
+	Expected string
+	Actual   string
+}
+
+func (e *CertificateMismatch) Error() string {
+	return fmt.Sprintf(
+		"certificate of %v changed, expected SHA256 %v but got %v",
+		e.Server, e.Expected, e.Actual,
+	)
+}
+
+func Fingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return fmt.Sprintf("%x", hash)
+}
+
+// pinKey returns the key of the server's pinned certificate, servers
+// on the same host but different ports may have different certificates
+func pinKey(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func trustConfig(host string, port uint16) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+		// This is fine, it's always false by default
+		// The user may change the config, the name should be clear enough
+		// that this is insecure (valid use cases are for testing purposes)
+		InsecureSkipVerify: config.ReadConfig().InsecureDebugMode, // #nosec 402
+	}
+
+	trust := config.ReadConfig().Trust(host)
+	switch trust.Mode {
+	case config.TrustEmbedded:
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(embeds.ServerCertificate) {
+			return nil, errors.New("failed to append server certificate")
+		}
+		tlsConfig.RootCAs = certPool
+
+	case config.TrustSystem:
+		// A nil RootCAs uses the system pool
+
+	case config.TrustCustomCA:
+		pem, err := os.ReadFile(trust.CAPath) // #nosec 304
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA for %v: %w", host, err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %v", trust.CAPath)
+		}
+		tlsConfig.RootCAs = certPool
+
+	case config.TrustOnFirstUse:
+		// The chain is not verified, instead the leaf certificate must match
+		// the pinned fingerprint (if there is one), just like ssh known_hosts
+		tlsConfig.InsecureSkipVerify = true // #nosec 402
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			expected, ok := config.ReadCache().PinnedCertificates[pinKey(host, port)]
+			actual := Fingerprint(state.PeerCertificates[0])
+			if ok && expected != actual {
+				return &CertificateMismatch{
+					Server:   pinKey(host, port),
+					Expected: expected,
+					Actual:   actual,
+				}
+			}
+			return nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown trust mode %q for %v", trust.Mode, host)
+	}
+
+	return tlsConfig, nil
+}
+
+func pinCertificate(host string, port uint16, conn *tls.Conn) error {
+	if config.ReadConfig().Trust(host).Mode != config.TrustOnFirstUse {
+		return nil
+	}
+	key := pinKey(host, port)
+	if _, ok := config.ReadCache().PinnedCertificates[key]; ok {
+		return nil
+	}
+
+	fingerprint := Fingerprint(conn.ConnectionState().PeerCertificates[0])
+	log.Println("pinned certificate of", key, "with SHA256", fingerprint)
+	return config.UseCache(func(cache *config.Cache) {
+		cache.PinnedCertificates[key] = fingerprint
+	})
+}
@@ -0,0 +1,172 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package sshagent signs in through the ssh-agent listening on SSH_AUTH_SOCK,
+// so private keys kept in the agent never enter eko's memory.
+//
+// A new connection to the agent is made for every operation,
+// so the agent being restarted doesn't invalidate a signer.
+package sshagent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kyren223/eko/pkg/sshkey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Prefix of a private key path that refers to an identity held by the agent,
+// optionally followed by a colon and the identity's comment or fingerprint
+const Prefix = "ssh-agent"
+
+var (
+	ErrNoAgent      = errors.New("SSH_AUTH_SOCK is not set")
+	ErrNoIdentities = errors.New("agent has no supported identities")
+	ErrNotFound     = errors.New("no matching identity in agent")
+)
+
+type Identity struct {
+	PublicKey sshkey.PublicKey
+	Comment   string
+}
+
+// IsAgentPath returns whether the private key path refers to the agent
+func IsAgentPath(path string) bool {
+	return path == Prefix || strings.HasPrefix(path, Prefix+":")
+}
+
+func dial() (net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, ErrNoAgent
+	}
+	return net.Dial("unix", socket)
+}
+
+// Identities returns the identities held by the agent
+// whose key types are supported, see sshkey.PublicKey.Validate
+func Identities() ([]Identity, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, err
+	}
+
+	identities := []Identity{}
+	for _, key := range keys {
+		publicKey := sshkey.PublicKey(key.Marshal())
+		if publicKey.Validate() != nil {
+			continue
+		}
+		identities = append(identities, Identity{PublicKey: publicKey, Comment: key.Comment})
+	}
+	return identities, nil
+}
+
+// Find returns the identity the agent path refers to, a bare prefix
+// is only valid if the agent holds exactly one supported identity
+// and a comment only if exactly one identity has it
+func Find(path string) (Identity, error) {
+	identities, err := Identities()
+	if err != nil {
+		return Identity{}, err
+	}
+	if len(identities) == 0 {
+		return Identity{}, ErrNoIdentities
+	}
+
+	name, ok := strings.CutPrefix(path, Prefix+":")
+	if !ok {
+		if len(identities) != 1 {
+			return Identity{}, fmt.Errorf(
+				"%v identities, pick one with %v:<comment>",
+				len(identities), Prefix,
+			)
+		}
+		return identities[0], nil
+	}
+
+	var matches []Identity
+	for _, identity := range identities {
+		if identity.Comment == name || identity.PublicKey.Fingerprint() == name {
+			matches = append(matches, identity)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Identity{}, ErrNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return Identity{}, fmt.Errorf(
+			"%v identities match %q, pick one with %v:<fingerprint>",
+			len(matches), name, Prefix,
+		)
+	}
+}
+
+// Signer signs with an identity held by the agent
+type Signer struct {
+	publicKey ssh.PublicKey
+}
+
+var _ ssh.AlgorithmSigner = (*Signer)(nil)
+
+func NewSigner(identity Identity) (*Signer, error) {
+	publicKey, err := identity.PublicKey.SSH()
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{publicKey: publicKey}, nil
+}
+
+func (s *Signer) PublicKey() ssh.PublicKey {
+	return s.publicKey
+}
+
+func (s *Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+// SignWithAlgorithm asks the agent to sign the data, rand is ignored
+func (s *Signer) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var flags agent.SignatureFlags
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		flags = agent.SignatureFlagRsaSha256
+	case ssh.KeyAlgoRSASHA512:
+		flags = agent.SignatureFlagRsaSha512
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return agent.NewClient(conn).SignWithFlags(s.publicKey, data, flags)
+}
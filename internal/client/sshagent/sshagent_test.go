@@ -0,0 +1,198 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sshagent
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/kyren223/eko/pkg/sshkey"
+)
+
+// serveAgent serves an in memory agent holding the given keys,
+// keyed by comment, and points SSH_AUTH_SOCK at it
+func serveAgent(t *testing.T, keys []agent.AddedKey) {
+	t.Helper()
+
+	keyring := agent.NewKeyring()
+	for _, key := range keys {
+		require.NoError(t, keyring.Add(key))
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+}
+
+func generateKeys(t *testing.T) (ed25519.PrivateKey, *ecdsa.PrivateKey, *rsa.PrivateKey, *rsa.PrivateKey) {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024) // #nosec G403 -- must be rejected
+	require.NoError(t, err)
+	return edKey, ecKey, rsaKey, weakKey
+}
+
+func publicKey(t *testing.T, privateKey any) sshkey.PublicKey {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+	return sshkey.FromSSH(signer.PublicKey())
+}
+
+func TestNoAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, err := Identities()
+	require.ErrorIs(t, err, ErrNoAgent)
+	_, err = Find(Prefix)
+	require.ErrorIs(t, err, ErrNoAgent)
+}
+
+func TestIdentities(t *testing.T) {
+	edKey, ecKey, rsaKey, weakKey := generateKeys(t)
+	serveAgent(t, []agent.AddedKey{
+		{PrivateKey: edKey, Comment: "laptop"},
+		{PrivateKey: ecKey, Comment: "work"},
+		{PrivateKey: rsaKey, Comment: "old"},
+		{PrivateKey: weakKey, Comment: "weak"},
+	})
+
+	identities, err := Identities()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Identity{
+		{PublicKey: publicKey(t, edKey), Comment: "laptop"},
+		{PublicKey: publicKey(t, ecKey), Comment: "work"},
+		{PublicKey: publicKey(t, rsaKey), Comment: "old"},
+	}, identities, "the weak rsa key isn't supported")
+}
+
+func TestFind(t *testing.T) {
+	edKey, ecKey, rsaKey, _ := generateKeys(t)
+	serveAgent(t, []agent.AddedKey{
+		{PrivateKey: edKey, Comment: "laptop"},
+		{PrivateKey: ecKey, Comment: "shared"},
+		{PrivateKey: rsaKey, Comment: "shared"},
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		want      sshkey.PublicKey
+		wantErr   error
+		ambiguous bool
+	}{
+		{"comment", Prefix + ":laptop", publicKey(t, edKey), nil, false},
+		{"fingerprint", Prefix + ":" + publicKey(t, ecKey).Fingerprint(), publicKey(t, ecKey), nil, false},
+		{"fingerprint of a shared comment", Prefix + ":" + publicKey(t, rsaKey).Fingerprint(), publicKey(t, rsaKey), nil, false},
+		{"ambiguous comment", Prefix + ":shared", nil, nil, true},
+		{"ambiguous bare prefix", Prefix, nil, nil, true},
+		{"unknown comment", Prefix + ":desktop", nil, ErrNotFound, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity, err := Find(test.path)
+			if test.ambiguous {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, ErrNotFound)
+				return
+			}
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, identity.PublicKey)
+		})
+	}
+}
+
+func TestFindSingleIdentity(t *testing.T) {
+	edKey, _, _, weakKey := generateKeys(t)
+	serveAgent(t, []agent.AddedKey{
+		{PrivateKey: edKey, Comment: "laptop"},
+		{PrivateKey: weakKey, Comment: "weak"},
+	})
+
+	// The bare prefix only counts supported identities
+	identity, err := Find(Prefix)
+	require.NoError(t, err)
+	require.Equal(t, publicKey(t, edKey), identity.PublicKey)
+}
+
+func TestFindEmptyAgent(t *testing.T) {
+	serveAgent(t, nil)
+
+	_, err := Find(Prefix)
+	require.ErrorIs(t, err, ErrNoIdentities)
+}
+
+func TestSigner(t *testing.T) {
+	edKey, ecKey, rsaKey, _ := generateKeys(t)
+	serveAgent(t, []agent.AddedKey{
+		{PrivateKey: edKey, Comment: "ed25519"},
+		{PrivateKey: ecKey, Comment: "ecdsa"},
+		{PrivateKey: rsaKey, Comment: "rsa"},
+	})
+
+	for _, comment := range []string{"ed25519", "ecdsa", "rsa"} {
+		t.Run(comment, func(t *testing.T) {
+			identity, err := Find(Prefix + ":" + comment)
+			require.NoError(t, err)
+			signer, err := NewSigner(identity)
+			require.NoError(t, err)
+			require.Equal(t, []byte(identity.PublicKey), signer.PublicKey().Marshal())
+
+			message := []byte("hello")
+			signature, err := sshkey.Sign(signer, message)
+			require.NoError(t, err)
+			require.True(t, sshkey.Verify(identity.PublicKey, message, signature))
+			require.False(t, sshkey.Verify(identity.PublicKey, []byte("hellO"), signature))
+		})
+	}
+}
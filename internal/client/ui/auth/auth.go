@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -33,12 +34,14 @@ import (
 	"golang.org/x/crypto/ssh"
 
 	"github.com/kyren223/eko/internal/client/config"
+	"github.com/kyren223/eko/internal/client/sshagent"
 	"github.com/kyren223/eko/internal/client/ui"
 	"github.com/kyren223/eko/internal/client/ui/choicepopup"
 	"github.com/kyren223/eko/internal/client/ui/colors"
 	"github.com/kyren223/eko/internal/client/ui/core"
 	authfield "github.com/kyren223/eko/internal/client/ui/field"
 	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 const (
@@ -47,7 +50,7 @@ const (
 	passphraseField
 	passphraseConfirmField
 	authWidth  = 52
-	authHeight = 21
+	authHeight = 23
 )
 
 var (
@@ -134,7 +137,7 @@ func New() Model {
 				return nil
 			}
 		case privateKeyField:
-			field.Input.Placeholder = "Path to Private Key"
+			field.Input.Placeholder = "Path to Private Key or ssh-agent"
 			field.Input.CharLimit = 100
 			field.Input.Validate = func(privKey string) error {
 				if len(privKey) == 0 {
@@ -184,6 +187,8 @@ func (m Model) View() string {
 
 	builder.WriteString(title)
 	builder.WriteString("\n\n")
+	builder.WriteString(serverView())
+	builder.WriteString("\n\n")
 
 	if !m.signup {
 		builder.WriteString("\n")
@@ -276,6 +281,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmd := m.SetSignup(!m.signup)
 			return m, cmd
 
+		case tea.KeyCtrlN:
+			if m.popup == nil {
+				m.nextServer()
+			}
+			return m, nil
+
 		case tea.KeyCtrlT:
 			if m.popup == nil && (m.focusIndex == passphraseField || m.focusIndex == passphraseConfirmField) {
 				m.fields[m.focusIndex].SetRevealed(!m.fields[m.focusIndex].Revealed())
@@ -394,12 +405,60 @@ func (m *Model) SetSignup(signup bool) tea.Cmd {
 	m.focusIndex = -1
 	m.CycleForward()
 
-	privateKey := config.ReadConfig().PrivateKeyPath
+	m.loadServer()
+
+	return m.updateFocus()
+}
+
+// loadServer fills the fields with what is remembered for the selected server
+func (m *Model) loadServer() {
+	privateKey := config.ReadConfig().PrivateKey()
 	if !m.signup && privateKey != "" {
 		m.fields[privateKeyField].Input.SetValue(privateKey)
 	}
 
-	return m.updateFocus()
+	username := config.ReadConfig().Server().Username
+	if m.signup && username != "" && m.fields[usernameField].Input.Value() == "" {
+		m.fields[usernameField].Input.SetValue(username)
+	}
+}
+
+func (m *Model) nextServer() {
+	servers := config.ReadConfig().Servers
+	index := slices.IndexFunc(servers, func(server config.ServerProfile) bool {
+		return server.Name == config.ReadConfig().ServerName
+	})
+	next := servers[(index+1)%len(servers)]
+
+	err := config.UseConfig(func(config *config.Config) {
+		config.ServerName = next.Name
+	})
+	if err != nil {
+		log.Println("unable to save selected server:", err)
+	}
+
+	m.fields[usernameField].Input.SetValue("")
+	m.fields[privateKeyField].Input.SetValue("")
+	for i := range m.fields {
+		m.fields[i].Input.Err = nil
+	}
+	m.loadServer()
+}
+
+func serverView() string {
+	style := lipgloss.NewStyle().Background(colors.Background)
+	server := config.ReadConfig().Server()
+
+	view := headerStyle().Inherit(style).Render("Server ") +
+		style.Foreground(colors.White).Render(server.Name)
+	if server.Name != server.Host || server.Port != config.DefaultPort {
+		view += style.Foreground(colors.Gray).Render(" (" + server.Address() + ")")
+	}
+	if len(config.ReadConfig().Servers) > 1 {
+		view += style.Foreground(colors.Gray).Render(" ctrl+n")
+	}
+
+	return centerStyle.Background(colors.Background).Render(view)
 }
 
 func (m *Model) ButtonPressed(msg tea.Msg) tea.Cmd {
@@ -446,6 +505,10 @@ func (m *Model) Signup() tea.Cmd {
 		return nil
 	}
 
+	if sshagent.IsAgentPath(m.fields[privateKeyField].Input.Value()) {
+		return m.agentSignin(username)
+	}
+
 	privateKeyFilepath := expandPath(m.fields[privateKeyField].Input.Value())
 	err := os.MkdirAll(filepath.Dir(privateKeyFilepath), 0o750)
 	if err != nil {
@@ -500,10 +563,16 @@ func (m *Model) Signup() tea.Cmd {
 		return nil
 	}
 
-	return ui.Transition(core.New(privKey, username))
+	signer, err := ssh.NewSignerFromKey(privKey)
+	assert.NoError(err, "ed25519 keys are always valid signers")
+	return ui.Transition(core.New(signer, privKey, username))
 }
 
 func (m *Model) signin() tea.Cmd {
+	if sshagent.IsAgentPath(m.fields[privateKeyField].Input.Value()) {
+		return m.agentSignin("")
+	}
+
 	privateKeyFilepath := expandPath(m.fields[privateKeyField].Input.Value())
 	file, err := os.ReadFile(privateKeyFilepath) // #nosec 304
 	if errors.Is(err, os.ErrNotExist) {
@@ -551,21 +620,56 @@ func (m *Model) signin() tea.Cmd {
 		}
 	}
 
-	privKey, ok := privateKey.(*ed25519.PrivateKey)
-	if !ok {
-		m.fields[privateKeyField].Input.Err = errors.New("must be ed25519")
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err == nil {
+		err = sshkey.FromSSH(signer.PublicKey()).Validate()
+	}
+	if err != nil {
+		m.fields[privateKeyField].Input.Err = errors.New("must be ed25519, ecdsa or rsa (2048+ bits)")
 		keyType := reflect.TypeOf(privateKey)
-		log.Println("incorrect private key type, got:", keyType.String(), reflect.ValueOf(privateKey).String())
+		log.Println("unsupported private key type, got:", keyType.String(), "error:", err)
 		return nil
 	}
 
+	// Encryption keys can only be derived from ed25519 keys
+	var privKey ed25519.PrivateKey
+	if key, ok := privateKey.(*ed25519.PrivateKey); ok {
+		privKey = *key
+	}
+
 	if m.remember {
 		_ = config.UseConfig(func(config *config.Config) {
-			config.PrivateKeyPath = privateKeyFilepath
+			config.RememberPrivateKey(privateKeyFilepath)
 		})
 	}
 
-	return ui.Transition(core.New(*privKey, ""))
+	return ui.Transition(core.New(signer, privKey, ""))
+}
+
+// agentSignin signs in with an identity held by ssh-agent,
+// the private key never leaves the agent
+func (m *Model) agentSignin(username string) tea.Cmd {
+	path := m.fields[privateKeyField].Input.Value()
+	identity, err := sshagent.Find(path)
+	if err != nil {
+		m.fields[privateKeyField].Input.Err = err
+		log.Println("ssh-agent error:", err)
+		return nil
+	}
+
+	if !m.signup && m.remember {
+		_ = config.UseConfig(func(config *config.Config) {
+			config.RememberPrivateKey(path)
+		})
+	}
+
+	signer, err := sshagent.NewSigner(identity)
+	if err != nil {
+		m.fields[privateKeyField].Input.Err = err
+		return nil
+	}
+
+	return ui.Transition(core.New(signer, nil, username))
 }
 
 func (m Model) ButtonIndex() int {
@@ -0,0 +1,213 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package attachmentpath
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/transfer"
+	"github.com/kyren223/eko/internal/client/ui/field"
+	"github.com/kyren223/eko/internal/client/ui/layouts/flex"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+var (
+	width = 48
+
+	blurredButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White)
+	}
+	focusedButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White)
+	}
+)
+
+const (
+	PathField = iota
+	ButtonField
+	FieldCount
+)
+
+type Model struct {
+	path        field.Model
+	button      string
+	buttonStyle lipgloss.Style
+
+	// Upload to a frequency/receiver, or save the attachment
+	frequency  *snowflake.ID
+	receiver   *snowflake.ID
+	attachment *data.Attachment
+
+	selected  int
+	pathWidth int
+}
+
+func NewUpload(frequency, receiver *snowflake.ID) Model {
+	m := newModel("File to Upload", "Upload")
+	m.frequency = frequency
+	m.receiver = receiver
+	return m
+}
+
+func NewSave(attachment data.Attachment) Model {
+	m := newModel("Save "+attachment.Name+" To", "Save")
+	m.attachment = &attachment
+	m.path.Input.SetValue(attachment.Name)
+	m.path.Input.CursorEnd()
+	return m
+}
+
+func newModel(header, button string) Model {
+	headerStyle := lipgloss.NewStyle().Foreground(colors.Turquoise)
+
+	blurredTextStyle := lipgloss.NewStyle().
+		Background(colors.Background).Foreground(colors.White)
+	focusedTextStyle := blurredTextStyle.Foreground(colors.Focus)
+
+	fieldBlurredStyle := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.DarkCyan).
+		BorderBackground(colors.Background).
+		Background(colors.Background)
+	fieldFocusedStyle := fieldBlurredStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.Focus)
+
+	path := field.New(width)
+	path.Header = header
+	path.HeaderStyle = headerStyle
+	path.FocusedStyle = fieldFocusedStyle
+	path.BlurredStyle = fieldBlurredStyle
+	path.FocusedTextStyle = focusedTextStyle
+	path.BlurredTextStyle = blurredTextStyle
+	path.ErrorStyle = lipgloss.NewStyle().Background(colors.Background).Foreground(colors.Error)
+	path.Input.CharLimit = 4096
+	path.Focus()
+	path.Input.Validate = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("cannot be empty")
+		}
+		return nil
+	}
+
+	pathWidth := lipgloss.Width(path.View())
+
+	return Model{
+		path:        path,
+		button:      button,
+		buttonStyle: blurredButtonStyle(),
+		selected:    PathField,
+		pathWidth:   pathWidth,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	path := m.path.View()
+
+	button := lipgloss.NewStyle().
+		Width(m.pathWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.buttonStyle.Render(m.button))
+
+	content := flex.NewVertical(path, button).WithGap(1).View()
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 4).
+		Align(lipgloss.Center, lipgloss.Center).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(content)
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		key := msg.Type
+		switch key {
+		case tea.KeyTab:
+			return m, m.cycle(1)
+		case tea.KeyShiftTab:
+			return m, m.cycle(-1)
+
+		default:
+			var cmd tea.Cmd
+			switch m.selected {
+			case PathField:
+				m.path, cmd = m.path.Update(msg)
+			}
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) cycle(step int) tea.Cmd {
+	m.selected += step
+	if m.selected < 0 {
+		m.selected = FieldCount - 1
+	} else {
+		m.selected %= FieldCount
+	}
+	return m.updateFocus()
+}
+
+func (m *Model) updateFocus() tea.Cmd {
+	m.path.Blur()
+	m.buttonStyle = blurredButtonStyle()
+	switch m.selected {
+	case PathField:
+		return m.path.Focus()
+	case ButtonField:
+		m.buttonStyle = focusedButtonStyle()
+		return nil
+	default:
+		assert.Never("missing switch statement field in update focus", "selected", m.selected)
+		return nil
+	}
+}
+
+// Select starts the transfer, returns false if the path is invalid
+func (m *Model) Select() (tea.Cmd, bool) {
+	m.path.Input.Err = m.path.Input.Validate(m.path.Input.Value())
+	if m.path.Input.Err != nil {
+		return nil, false
+	}
+	path := strings.TrimSpace(m.path.Input.Value())
+
+	if m.attachment != nil {
+		return transfer.StartDownload(*m.attachment, path), true
+	}
+	return transfer.StartUpload(path, m.frequency, m.receiver), true
+}
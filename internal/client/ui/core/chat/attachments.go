@@ -0,0 +1,119 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/core/transfer"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const (
+	AttachmentSymbol    = "📎 "
+	AttachmentSeparator = " · "
+	UnknownAttachment   = "attachment"
+)
+
+// renderBody renders the message's content followed by its attachment
+func renderBody(message data.Message, backgroundStyle lipgloss.Style, opts contentOptions) string {
+	content := renderContent(packet.WithLegacyPing(message.Content, message.Ping), backgroundStyle, opts)
+	if message.AttachmentID == nil {
+		return content
+	}
+
+	attachment := renderAttachment(*message.AttachmentID, backgroundStyle)
+	if strings.TrimSpace(message.Content) == "" {
+		return attachment
+	}
+	return content + "\n" + attachment
+}
+
+func renderAttachment(id snowflake.ID, backgroundStyle lipgloss.Style) string {
+	attachment, ok := state.State.Attachments[id]
+	if !ok {
+		return backgroundStyle.Render(AttachmentSymbol + UnknownAttachment)
+	}
+
+	nameStyle := backgroundStyle.Foreground(colors.LightBlue)
+	infoStyle := backgroundStyle.Foreground(colors.LightGray)
+
+	info := AttachmentSeparator + formatBytes(attachment.Size)
+	if download := transfer.GetDownload(id); download != nil {
+		info += AttachmentSeparator + downloadStatus(download)
+	}
+
+	return backgroundStyle.Render(AttachmentSymbol) +
+		nameStyle.Render(attachment.Name) + infoStyle.Render(info)
+}
+
+func downloadStatus(download *transfer.Download) string {
+	switch {
+	case download.Err != nil:
+		return "failed to save: " + download.Err.Error()
+	case download.Done:
+		return "saved to " + download.Path
+	default:
+		return "saving " + percentage(download.Received, download.Attachment.Size)
+	}
+}
+
+// renderUploadStatus returns the status of the current upload,
+// or an empty string if there is nothing worth showing
+func renderUploadStatus(style lipgloss.Style, width int) string {
+	upload := transfer.CurrentUpload()
+	if upload == nil || upload.Done {
+		return ""
+	}
+
+	status := AttachmentSymbol + "uploading " + upload.Name
+	if upload.Err != nil {
+		status = AttachmentSymbol + "failed to upload " + upload.Name + ": " + upload.Err.Error()
+		style = style.Foreground(colors.Red)
+	} else if upload.Size != 0 {
+		status += AttachmentSeparator + percentage(upload.Sent, upload.Size)
+	}
+	return style.Render(ansi.Truncate(status, width, "…"))
+}
+
+func percentage(done, total int64) string {
+	if total == 0 {
+		return "100%"
+	}
+	return fmt.Sprintf("%d%%", done*100/total)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -18,16 +18,21 @@ package chat
 
 import (
 	"bytes"
+	"fmt"
 	"log"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/btree"
 
 	"github.com/kyren223/eko/internal/client/config"
+	"github.com/kyren223/eko/internal/client/e2ee"
 	"github.com/kyren223/eko/internal/client/gateway"
 	"github.com/kyren223/eko/internal/client/ui"
 	"github.com/kyren223/eko/internal/client/ui/colors"
@@ -93,6 +98,20 @@ var (
 	SelectSignalOrFrequency = "Cannot send messages, select a signal or frequency first"
 	BlockedPlaceholder      = "You have blocked this user"
 	BlockingPlaceholder     = "You have been blocked by this user"
+	EncryptedPlaceholder    = "Send an encrypted message..."
+	UntrustedPlaceholder    = "Trust this user to send encrypted messages"
+	KeyMismatchPlaceholder  = "This user's key changed, verify and re-trust them to send encrypted messages"
+	DeviceKeyPlaceholder    = "Encrypted messages can only be sent when signed in with your account key"
+	NoEncryptionPlaceholder = "Encrypted messages can only be sent when signed in with an ed25519 key file"
+	UnsupportedPlaceholder  = "This user's key type doesn't support encrypted messages"
+
+	UnsignedIndicator = func() string {
+		return lipgloss.NewStyle().Foreground(colors.LightGray).Render(" (unsigned)")
+	}
+	InvalidSignatureIndicator = func() string {
+		return lipgloss.NewStyle().Foreground(colors.Red).Bold(true).Render(" (invalid signature)")
+	}
+	LockSymbol = func() string { return lipgloss.NewStyle().Foreground(colors.Turquoise).Render(" 󰌾") }
 
 	EditedIndicator = func(bg lipgloss.Color) string {
 		return lipgloss.NewStyle().Background(bg).
@@ -107,12 +126,16 @@ var (
 
 	MutedSymbol = func() string { return lipgloss.NewStyle().Foreground(colors.Red).Render(" 󱡣") }
 
-	PingPrefix      = "@"
-	PingedAdmins    = func() string { return lipgloss.NewStyle().Foreground(colors.Red).SetString("@admins ").String() }
-	PingedEveryone  = func() string { return lipgloss.NewStyle().Foreground(colors.Purple).Render("@everyone ") }
-	PingedUserStyle = func() lipgloss.Style { return lipgloss.NewStyle().Foreground(colors.Gold) }
+	PinnedSymbol = func(bg lipgloss.Color) string {
+		return lipgloss.NewStyle().Background(bg).Foreground(colors.Gold).Render("󰐃 ")
+	}
+
+	MentionAdminsStyle   = func() lipgloss.Style { return lipgloss.NewStyle().Foreground(colors.Red) }
+	MentionEveryoneStyle = func() lipgloss.Style { return lipgloss.NewStyle().Foreground(colors.Purple) }
+	MentionUserStyle     = func() lipgloss.Style { return lipgloss.NewStyle().Foreground(colors.Gold) }
 
 	NewText       = "━━ NEW ━━"
+	SeenText      = "Seen"
 	HorizontalSep = "━"
 	VerticalSep   = "┃"
 )
@@ -123,6 +146,12 @@ const (
 
 	TimeGap = 7 * 60 * 1000 // 7 minutes in millis
 
+	// How long to wait for the message to arrive when jumping to it
+	JumpTimeout = 5 * time.Second
+
+	// Minimum time between typing events sent to the server
+	TypingInterval = 3 * time.Second
+
 	SnapToBottom = -1
 	Unselected   = -1
 )
@@ -143,8 +172,15 @@ type Model struct {
 	selectedMessage *data.Message
 	editingMessage  *data.Message
 
+	jumpTo     *snowflake.ID
+	jumpStart  time.Time
+	jumpBottom int
+	jumpHeight int
+
 	outdatedLastReadMsg *snowflake.ID
 
+	lastTyping time.Time
+
 	messagesHeight    int
 	maxMessagesHeight int
 	messagesCache     *string
@@ -158,6 +194,7 @@ type Model struct {
 
 func New() Model {
 	vi := viminput.New()
+	vi.CompletionTrigger = rune(packet.MentionPrefix[0])
 
 	return Model{
 		vi:                  vi,
@@ -172,7 +209,12 @@ func New() Model {
 		index:               Unselected,
 		selectedMessage:     nil,
 		editingMessage:      nil,
+		jumpTo:              nil,
+		jumpStart:           time.Time{},
+		jumpBottom:          -1,
+		jumpHeight:          -1,
 		outdatedLastReadMsg: nil,
+		lastTyping:          time.Time{},
 		messagesHeight:      0,
 		maxMessagesHeight:   -1,
 		messagesCache:       nil,
@@ -250,6 +292,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		}
 
 		m.hasReadAccess = frequency.Perms != packet.PermNoAccess || member.IsAdmin
+		m.vi.Completer = mentionCompleter(*networkId)
 		m.hasWriteAccess = !member.IsMuted && (frequency.Perms == packet.PermReadWrite || member.IsAdmin)
 
 		if member.IsMuted {
@@ -297,6 +340,13 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 		m.hasReadAccess = true
 		m.hasWriteAccess = true
+		m.vi.Completer = nil
+
+		isEncrypted := state.IsEncrypted(receiverId) && !state.IsGroupSignal(receiverId)
+		placeholder := SendMessagePlaceholder
+		if isEncrypted {
+			placeholder = EncryptedPlaceholder
+		}
 
 		if _, ok := state.State.BlockedUsers[receiverId]; ok {
 			m.hasWriteAccess = false
@@ -312,8 +362,25 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.style = redStyle()
 			m.vi.SetInactive(true)
 			m.locked = false
+		} else if isEncrypted && state.EncryptionKey(receiverId) == nil {
+			// Never fall back to sending plaintext
+			m.hasWriteAccess = false
+			m.vi.Placeholder = UntrustedPlaceholder
+			if state.KeyMismatch(receiverId) {
+				m.vi.Placeholder = KeyMismatchPlaceholder
+			} else if state.IsDeviceKey() {
+				m.vi.Placeholder = DeviceKeyPlaceholder
+			} else if state.LacksEncryptionKey() {
+				m.vi.Placeholder = NoEncryptionPlaceholder
+			} else if !state.SupportsEncryption(receiverId) {
+				m.vi.Placeholder = UnsupportedPlaceholder
+			}
+			m.borderStyle = ViRedBorder()
+			m.style = redStyle()
+			m.vi.SetInactive(true)
+			m.locked = false
 		} else if m.locked {
-			m.vi.Placeholder = SendMessagePlaceholder
+			m.vi.Placeholder = placeholder
 			m.borderStyle = ViFocusedBorder()
 			m.style = focusStyle()
 			m.vi.SetInactive(false)
@@ -323,7 +390,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.style = editStyle()
 			}
 		} else {
-			m.vi.Placeholder = SendMessagePlaceholder
+			m.vi.Placeholder = placeholder
 			m.borderStyle = ViBlurredBorder()
 			m.style = blurStyle()
 			m.vi.SetInactive(false)
@@ -364,6 +431,12 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, nil
 			}
 
+			if key.Type == tea.KeyEnter && m.vi.Completing() {
+				m.vi, _ = m.vi.Update(msg)
+				m.Prerender()
+				return m, nil
+			}
+
 			if key.Type == tea.KeyEnter {
 				var cmd tea.Cmd
 
@@ -385,8 +458,12 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			}
 		}
 
+		content := m.vi.String()
 		var cmd tea.Cmd
 		m.vi, cmd = m.vi.Update(msg)
+		if m.vi.Mode() == viminput.InsertMode && m.vi.String() != content {
+			cmd = tea.Batch(cmd, m.typing())
+		}
 		m.Prerender()
 		return m, cmd
 	}
@@ -441,6 +518,104 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 
+		case "m":
+			if m.selectedMessage == nil {
+				return m, nil
+			}
+
+			networkId := state.NetworkId(m.networkIndex)
+			if m.frequencyIndex != -1 && networkId != nil {
+				member := state.State.Members[*networkId][*state.UserID]
+				if !member.IsAdmin {
+					return m, nil
+				}
+			}
+
+			return m, gateway.Send(&packet.PinMessage{
+				Message: m.selectedMessage.ID,
+				Pin:     m.selectedMessage.PinnedAt == nil,
+			})
+
+		case "'":
+			source := m.source()
+			if source == nil {
+				return m, nil
+			}
+
+			return m, func() tea.Msg {
+				return ui.PinsPopupMsg{
+					Source: *source,
+				}
+			}
+
+		case "/":
+			popup := ui.SearchPopupMsg{}
+			networkId := state.NetworkId(m.networkIndex)
+			if m.frequencyIndex != -1 && networkId != nil {
+				network := *networkId
+				frequencyId := state.State.Frequencies[network][m.frequencyIndex].ID
+				popup.Network = &network
+				popup.Frequency = &frequencyId
+			} else if m.receiverIndex != -1 {
+				receiverId := state.Data.Signals[m.receiverIndex]
+				popup.Receiver = &receiverId
+			}
+
+			return m, func() tea.Msg {
+				return popup
+			}
+
+		case "y":
+			link := m.link()
+			if link != nil {
+				_ = clipboard.WriteAll(link.String())
+			}
+
+		case "o":
+			if m.selectedMessage == nil {
+				return m, nil
+			}
+			links := packet.FindLinks(m.selectedMessage.Content)
+			if len(links) == 0 {
+				return m, nil
+			}
+
+			return m, func() tea.Msg {
+				return ui.OpenLinkMsg{
+					Link: links[0],
+				}
+			}
+
+		case "a":
+			if !m.hasWriteAccess {
+				return m, nil
+			}
+			popup := ui.UploadAttachmentPopupMsg{}
+			networkId := state.NetworkId(m.networkIndex)
+			if m.frequencyIndex != -1 && networkId != nil {
+				frequencyId := state.State.Frequencies[*networkId][m.frequencyIndex].ID
+				popup.Frequency = &frequencyId
+			} else if m.receiverIndex != -1 {
+				receiverId := state.Data.Signals[m.receiverIndex]
+				popup.Receiver = &receiverId
+			}
+
+			return m, func() tea.Msg {
+				return popup
+			}
+
+		case "S":
+			if m.selectedMessage == nil || m.selectedMessage.AttachmentID == nil {
+				return m, nil
+			}
+			attachment := *m.selectedMessage.AttachmentID
+
+			return m, func() tea.Msg {
+				return ui.SaveAttachmentPopupMsg{
+					Attachment: attachment,
+				}
+			}
+
 		case "x", "d":
 			if m.selectedMessage == nil {
 				return m, nil
@@ -462,7 +637,8 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.editingMessage = m.selectedMessage
 
 			m.vi.Reset()
-			m.vi.SetString(m.selectedMessage.Content)
+			// Legacy pings are kept by editing them into the content
+			m.vi.SetString(packet.WithLegacyPing(m.selectedMessage.Content, m.selectedMessage.Ping))
 
 			m.locked = true
 			m.vi.SetMode(viminput.InsertMode)
@@ -750,44 +926,28 @@ func (m Model) Locked() bool {
 func (m *Model) sendMessage() tea.Cmd {
 	message := m.vi.String()
 
-	var ping *snowflake.ID = nil
-
 	var receiverId *snowflake.ID = nil
 	if m.receiverIndex != -1 {
 		receiverId = &state.Data.Signals[m.receiverIndex]
 	}
 
 	var frequencyId *snowflake.ID = nil
+	var ping *snowflake.ID = nil
 	networkId := state.NetworkId(m.networkIndex)
 	if m.frequencyIndex != -1 && networkId != nil {
 		frequencies := state.State.Frequencies[*networkId]
 		frequencyId = &frequencies[m.frequencyIndex].ID
 
-		// Parse ping
-		if strings.HasPrefix(message, PingPrefix) {
-			index := strings.IndexAny(message, "\n ")
-			if index != -1 {
-				value := message[len(PingPrefix):index]
-				switch value {
-				case "everyone":
-					member := state.State.Members[*networkId][*state.UserID]
-					if member.IsAdmin {
-						pingValue := packet.PingEveryone
-						ping = &pingValue
-						message = message[index+1:]
-					}
-				case "admins":
-					pingValue := packet.PingAdmins
-					ping = &pingValue
-					message = message[index+1:]
-				default:
-					num, err := strconv.ParseInt(value, 10, 64)
-					if err == nil {
-						ping = (*snowflake.ID)(&num)
-						message = message[index+1:]
-					}
-				}
-			}
+		mentions := packet.ParseMentions(message)
+		if len(mentions) > packet.MaxMentions {
+			return nil
+		}
+		member := state.State.Members[*networkId][*state.UserID]
+		if slices.Contains(mentions, packet.PingEveryone) && !member.IsAdmin {
+			return nil
+		}
+		if len(mentions) != 0 {
+			ping = &mentions[0]
 		}
 	}
 
@@ -798,17 +958,88 @@ func (m *Model) sendMessage() tea.Cmd {
 		return nil
 	}
 
+	message = strings.TrimSpace(message) // The server trims it, which would break the signature
+	encrypted := false
+	if receiverId != nil && state.IsEncrypted(*receiverId) && !state.IsGroupSignal(*receiverId) {
+		key := state.EncryptionKey(*receiverId)
+		if key == nil {
+			return nil
+		}
+		ciphertext, err := e2ee.Encrypt(key, message)
+		if err != nil {
+			log.Println("failed to encrypt message:", err)
+			return nil
+		}
+		message = ciphertext
+		encrypted = true
+	}
+
+	chat := frequencyId
+	if chat == nil {
+		chat = receiverId
+	}
+	if chat == nil {
+		return nil
+	}
+
 	m.vi.Reset()
 	m.base = SnapToBottom
+	m.lastTyping = time.Time{}
 
 	return gateway.Send(&packet.SendMessage{
 		ReceiverID:  receiverId,
 		FrequencyID: frequencyId,
 		Content:     message,
 		Ping:        ping,
+		Encrypted:   encrypted,
+		Signature:   state.SignMessage(*chat, nil, message),
+	})
+}
+
+func (m *Model) typing() tea.Cmd {
+	if m.editingMessage != nil || m.vi.String() == "" {
+		return nil
+	}
+	if time.Since(m.lastTyping) < TypingInterval {
+		return nil
+	}
+
+	receiverId, frequencyId := m.chatIds()
+	if receiverId == nil && frequencyId == nil {
+		return nil
+	}
+
+	m.lastTyping = time.Now()
+	return gateway.Send(&packet.Typing{
+		ReceiverID:  receiverId,
+		FrequencyID: frequencyId,
+	})
+}
+
+// ViewChat tells the server which chat is currently open,
+// so it knows who to send typing indicators to
+func (m *Model) ViewChat() tea.Cmd {
+	receiverId, frequencyId := m.chatIds()
+	return gateway.Send(&packet.ViewChat{
+		ReceiverID:  receiverId,
+		FrequencyID: frequencyId,
 	})
 }
 
+func (m *Model) chatIds() (receiverId, frequencyId *snowflake.ID) {
+	if m.receiverIndex != -1 {
+		return &state.Data.Signals[m.receiverIndex], nil
+	}
+	networkId := state.NetworkId(m.networkIndex)
+	if m.frequencyIndex != -1 && networkId != nil {
+		frequencies := state.State.Frequencies[*networkId]
+		if m.frequencyIndex < len(frequencies) {
+			return nil, &frequencies[m.frequencyIndex].ID
+		}
+	}
+	return nil, nil
+}
+
 func (m *Model) SetReceiver(receiverIndex int) tea.Cmd {
 	if m.receiverIndex == receiverIndex && m.frequencyIndex == -1 {
 		return nil
@@ -838,6 +1069,7 @@ func (m *Model) ResetBeforeSwitch() {
 		m.SetIndex(Unselected)
 		m.maxMessagesHeight = -1
 		m.outdatedLastReadMsg = nil
+		m.lastTyping = time.Time{}
 	}()
 
 	networkId := state.NetworkId(m.networkIndex)
@@ -866,6 +1098,10 @@ func (m *Model) ResetBeforeSwitch() {
 }
 
 func (m *Model) RestoreAfterSwitch() tea.Cmd {
+	return tea.Batch(m.ViewChat(), m.restoreChat())
+}
+
+func (m *Model) restoreChat() tea.Cmd {
 	msgs := state.State.ChatState
 	networkId := state.NetworkId(m.networkIndex)
 	if m.frequencyIndex != -1 && networkId != nil {
@@ -919,6 +1155,20 @@ func (m *Model) RestoreAfterSwitch() tea.Cmd {
 func (m *Model) renderMessageBox() string {
 	var builder strings.Builder
 
+	if completions := m.vi.CompletionView(); completions != "" {
+		// Align with the text inside of the message box
+		builder.WriteString(lipgloss.NewStyle().PaddingLeft(2).
+			Background(colors.Background).Render(completions))
+		builder.WriteByte('\n')
+	}
+
+	statusStyle := lipgloss.NewStyle().Background(colors.Background).Foreground(colors.LightGray)
+	if status := renderUploadStatus(statusStyle, m.width-WidthWithoutVi); status != "" {
+		builder.WriteString(lipgloss.NewStyle().PaddingLeft(2).
+			Background(colors.Background).Render(status))
+		builder.WriteByte('\n')
+	}
+
 	input := m.borderStyle.Render(m.vi.View())
 	builder.WriteString(input)
 	builder.WriteByte('\n')
@@ -1028,6 +1278,18 @@ func (m *Model) renderMessages(screenHeight int) string {
 		// bcz base is an index (0 to n-1) but we want "length"
 		// we need to add one so it's N
 	}
+	jumping := false
+	if m.jumpTo != nil {
+		if btree.Has(data.Message{ID: *m.jumpTo}) {
+			jumping = true
+		} else if time.Since(m.jumpStart) > JumpTimeout {
+			m.jumpTo = nil // Give up, the message doesn't exist
+		}
+	}
+	if jumping {
+		// Render everything so the position of the message is known
+		height = math.MaxInt32
+	}
 	remainingHeight := height
 
 	renderedGroups := []string{}
@@ -1037,6 +1299,11 @@ func (m *Model) renderMessages(screenHeight int) string {
 	assert.Assert(notEmpty, "Empty btree should've been handled earlier in this function")
 	readThreshold := m.outdatedLastReadMsg
 
+	var seenThreshold *snowflake.ID
+	if m.receiverIndex != -1 {
+		seenThreshold = state.ReadReceipt(*chatId)
+	}
+
 	lastRenderedMsgId := snowflake.ID(0)
 	btree.Descend(func(message data.Message) bool {
 		lastRenderedMsgId = message.ID
@@ -1050,6 +1317,33 @@ func (m *Model) renderMessages(screenHeight int) string {
 			readThreshold = nil
 		}
 
+		// Same as above, <= so it's shown even if the message was deleted
+		if seenThreshold != nil && message.ID <= *seenThreshold {
+			if len(group) != 0 {
+				renderedGroup := m.renderMessageGroup(group, &remainingHeight, height)
+				renderedGroups = append(renderedGroups, renderedGroup)
+				group = []data.Message{}
+
+				if isLastReadMsg {
+					newMsgSep := m.NewMsgSep(height, remainingHeight)
+					renderedGroups = append(renderedGroups, newMsgSep+"\n")
+					remainingHeight--
+					readThreshold = nil
+				}
+			}
+
+			seenMarker := m.SeenMarker(height, remainingHeight)
+			renderedGroups = append(renderedGroups, seenMarker+"\n")
+			remainingHeight--
+
+			// Only the last read message is marked
+			seenThreshold = nil
+
+			if remainingHeight <= 0 {
+				return false
+			}
+		}
+
 		if len(group) == 0 {
 			group = append(group, message)
 			return true
@@ -1113,6 +1407,18 @@ func (m *Model) renderMessages(screenHeight int) string {
 		}
 	}
 
+	if jumping {
+		m.jumpTo = nil
+		if m.jumpBottom != -1 {
+			m.SetIndex(m.jumpBottom + m.jumpHeight/2)
+			top := m.jumpBottom + m.jumpHeight
+			if m.base != SnapToBottom && m.base < top && top < m.maxMessagesHeight {
+				m.base = top // Show the message from its first line
+			}
+		}
+		return m.renderMessages(screenHeight)
+	}
+
 	var builder strings.Builder
 
 	// Add blank newline to fill any remaining height
@@ -1211,49 +1517,24 @@ func (m *Model) renderMessageGroup(group []data.Message, remaining *int, height
 
 	for i := len(group) - 1; i >= 0; i-- {
 		extra := ""
-		messageStyle := messageStyle
 		backgroundStyle := lipgloss.NewStyle().Background(colors.Background).Foreground(colors.White)
-		if m.frequencyIndex != -1 && group[i].Ping != nil {
-			members := state.State.Members[*state.NetworkId(m.networkIndex)]
-			switch *group[i].Ping {
-			case packet.PingEveryone:
-				extra = PingedEveryone()
-				messageStyle = pingedMessageStyle.
-					BorderForeground(colors.Purple).
-					Background(colors.MutedPurple)
-				backgroundStyle = backgroundStyle.Background(colors.MutedPurple)
-			case packet.PingAdmins:
-				extra = PingedAdmins()
-				if members[*state.UserID].IsAdmin {
-					messageStyle = pingedMessageStyle.
-						BorderForeground(colors.Red).
-						Background(colors.MutedRed)
-					backgroundStyle = backgroundStyle.Background(colors.MutedRed)
-				}
-			default:
-				name := "@Unknown "
-				if user, ok := state.State.Users[*group[i].Ping]; ok {
-					name = "@" + user.Name + " "
-				}
-				extra = PingedUserStyle().Render(name)
-				if *group[i].Ping == *state.UserID {
-					messageStyle = pingedMessageStyle.
-						BorderForeground(colors.Gold).
-						Background(colors.MutedGold)
-					backgroundStyle = backgroundStyle.Background(colors.MutedGold)
-				}
-			}
+		messageStyle, backgroundStyle := m.pingStyles(group[i], false, messageStyle, pingedMessageStyle, backgroundStyle)
+		opts := m.contentOptions(false)
+
+		if group[i].PinnedAt != nil {
+			extra = PinnedSymbol(backgroundStyle.GetBackground().(lipgloss.Color)) + extra
 		}
 
 		if _, ok := state.State.BlockedUsers[group[i].SenderID]; ok {
 			extra = ""
+			opts = contentOptions{}
 			messageStyle = pingedMessageStyle.
 				BorderForeground(colors.Gray).
 				Background(colors.DarkGray)
 			backgroundStyle = backgroundStyle.Background(colors.DarkGray).Foreground(colors.DarkGray)
 		}
 
-		rawContent := extra + backgroundStyle.Render(group[i].Content)
+		rawContent := extra + renderBody(group[i], backgroundStyle, opts)
 		content := messageStyle.Render(rawContent)
 		heights[i] = lipgloss.Height(content)
 		if group[i].Edited {
@@ -1289,6 +1570,10 @@ func (m *Model) renderMessageGroup(group []data.Message, remaining *int, height
 		if bottom <= m.index && m.index <= top {
 			selectedIndex = i
 		}
+		if m.jumpTo != nil && group[i].ID == *m.jumpTo {
+			m.jumpBottom = bottom
+			m.jumpHeight = h
+		}
 	}
 	*remaining-- // For the header
 
@@ -1305,41 +1590,18 @@ func (m *Model) renderMessageGroup(group []data.Message, remaining *int, height
 		selectedPingedStyle := pingedMessageStyle.Background(colors.BackgroundDim)
 		selectedBackgroundStyle := lipgloss.NewStyle().Background(colors.BackgroundDim).Foreground(colors.White)
 
+		selectedStyle, selectedBackgroundStyle = m.pingStyles(
+			group[selectedIndex], true,
+			selectedStyle, selectedPingedStyle, selectedBackgroundStyle,
+		)
+
 		extra := ""
-		if m.frequencyIndex != -1 && group[selectedIndex].Ping != nil {
-			members := state.State.Members[*state.NetworkId(m.networkIndex)]
-			switch *group[selectedIndex].Ping {
-			case packet.PingEveryone:
-				extra = PingedEveryone()
-				selectedStyle = selectedPingedStyle.
-					BorderForeground(colors.Purple).
-					Background(colors.DarkMutedPurple)
-				selectedBackgroundStyle = selectedBackgroundStyle.Background(colors.DarkMutedPurple)
-			case packet.PingAdmins:
-				extra = PingedAdmins()
-				if members[*state.UserID].IsAdmin {
-					selectedStyle = selectedPingedStyle.
-						BorderForeground(colors.Red).
-						Background(colors.DarkMutedRed)
-					selectedBackgroundStyle = selectedBackgroundStyle.Background(colors.DarkMutedRed)
-				}
-			default:
-				name := "@Unknown "
-				if user, ok := state.State.Users[*group[selectedIndex].Ping]; ok {
-					name = "@" + user.Name + " "
-				}
-				extra = PingedUserStyle().Render(name)
-				if *group[selectedIndex].Ping == *state.UserID {
-					selectedStyle = selectedPingedStyle.
-						BorderForeground(colors.Gold).
-						Background(colors.DarkMutedGold)
-					selectedBackgroundStyle = selectedBackgroundStyle.Background(colors.DarkMutedGold)
-				}
-			}
+		if group[selectedIndex].PinnedAt != nil {
+			color := selectedBackgroundStyle.GetBackground().(lipgloss.Color)
+			extra = PinnedSymbol(color) + extra
 		}
 
-		rawContent := group[selectedIndex].Content
-		rawContent = selectedBackgroundStyle.Render(rawContent)
+		rawContent := renderBody(group[selectedIndex], selectedBackgroundStyle, m.contentOptions(true))
 		rawContent = extra + rawContent
 		content := selectedStyle.Render(rawContent)
 		if group[selectedIndex].Edited {
@@ -1358,42 +1620,14 @@ func (m *Model) renderMessageGroup(group []data.Message, remaining *int, height
 		// Redraw rest
 		for i := selectedIndex - 1; i >= 0; i-- {
 			extra := ""
-			messageStyle := messageStyle
 			backgroundStyle := lipgloss.NewStyle().Background(colors.Background).Foreground(colors.White)
-			if m.frequencyIndex != -1 && group[i].Ping != nil {
-
-				members := state.State.Members[*state.NetworkId(m.networkIndex)]
-				switch *group[i].Ping {
-				case packet.PingEveryone:
-					extra = PingedEveryone()
-					messageStyle = pingedMessageStyle.
-						BorderForeground(colors.Purple).
-						Background(colors.MutedPurple)
-					backgroundStyle = backgroundStyle.Background(colors.MutedPurple)
-				case packet.PingAdmins:
-					extra = PingedAdmins()
-					if members[*state.UserID].IsAdmin {
-						messageStyle = pingedMessageStyle.
-							BorderForeground(colors.Red).
-							Background(colors.MutedRed)
-						backgroundStyle = backgroundStyle.Background(colors.MutedRed)
-					}
-				default:
-					name := "@Unknown "
-					if user, ok := state.State.Users[*group[i].Ping]; ok {
-						name = "@" + user.Name + " "
-					}
-					extra = PingedUserStyle().Render(name)
-					if *group[i].Ping == *state.UserID {
-						messageStyle = pingedMessageStyle.
-							BorderForeground(colors.Gold).
-							Background(colors.MutedGold)
-						backgroundStyle = backgroundStyle.Background(colors.MutedGold)
-					}
-				}
+			messageStyle, backgroundStyle := m.pingStyles(group[i], false, messageStyle, pingedMessageStyle, backgroundStyle)
+
+			if group[i].PinnedAt != nil {
+				extra = PinnedSymbol(backgroundStyle.GetBackground().(lipgloss.Color)) + extra
 			}
 
-			rawContent := extra + backgroundStyle.Render(group[i].Content)
+			rawContent := extra + renderBody(group[i], backgroundStyle, m.contentOptions(false))
 			content := messageStyle.Render(rawContent)
 			heights[i] = lipgloss.Height(content)
 			if group[i].Edited {
@@ -1416,6 +1650,133 @@ func (m *Model) renderMessageGroup(group []data.Message, remaining *int, height
 	return string(buf)
 }
 
+func (m *Model) contentOptions(selected bool) contentOptions {
+	return contentOptions{
+		highlightMentions: m.frequencyIndex != -1,
+		renderLinks:       true,
+		markdown:          config.ReadConfig().MarkdownMessages,
+		revealSpoilers:    selected,
+	}
+}
+
+// pingStyles returns the message and background styles to use for the
+// message, highlighting it if it mentions the user
+func (m *Model) pingStyles(
+	message data.Message, selected bool,
+	messageStyle, pingedStyle, backgroundStyle lipgloss.Style,
+) (lipgloss.Style, lipgloss.Style) {
+	networkId := state.NetworkId(m.networkIndex)
+	if m.frequencyIndex == -1 || networkId == nil {
+		return messageStyle, backgroundStyle
+	}
+
+	isAdmin := state.State.Members[*networkId][*state.UserID].IsAdmin
+	pingedUser, pingedAdmins, pingedEveryone := false, false, false
+	for _, mention := range packet.ParseMentions(packet.WithLegacyPing(message.Content, message.Ping)) {
+		switch mention {
+		case packet.PingEveryone:
+			pingedEveryone = true
+		case packet.PingAdmins:
+			pingedAdmins = pingedAdmins || isAdmin
+		case *state.UserID:
+			pingedUser = true
+		}
+	}
+
+	var border, background lipgloss.Color
+	switch {
+	case pingedUser:
+		border, background = colors.Gold, colors.MutedGold
+		if selected {
+			background = colors.DarkMutedGold
+		}
+	case pingedAdmins:
+		border, background = colors.Red, colors.MutedRed
+		if selected {
+			background = colors.DarkMutedRed
+		}
+	case pingedEveryone:
+		border, background = colors.Purple, colors.MutedPurple
+		if selected {
+			background = colors.DarkMutedPurple
+		}
+	default:
+		return messageStyle, backgroundStyle
+	}
+
+	return pingedStyle.BorderForeground(border).Background(background),
+		backgroundStyle.Background(background)
+}
+
+// renderContent renders the content with the background style,
+// optionally highlighting any mentions inline and rendering markdown
+func renderContent(content string, backgroundStyle lipgloss.Style, opts contentOptions) string {
+	// Render each line on it's own, otherwise lipgloss pads
+	// all lines to the same width
+	render := func(s string, style lipgloss.Style) string {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = style.Render(line)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	text := render
+	if opts.highlightMentions {
+		text = func(s string, style lipgloss.Style) string {
+			return renderMentions(s, style, render)
+		}
+	}
+	if opts.renderLinks {
+		withMentions := text
+		text = func(s string, style lipgloss.Style) string {
+			return renderLinks(s, style, withMentions)
+		}
+	}
+
+	if !opts.markdown {
+		return text(content, backgroundStyle)
+	}
+
+	md := markdown{text: text, revealSpoilers: opts.revealSpoilers}
+	return md.Render(content, backgroundStyle)
+}
+
+type contentOptions struct {
+	highlightMentions bool
+	renderLinks       bool
+	markdown          bool
+	revealSpoilers    bool
+}
+
+func renderMentions(content string, style lipgloss.Style, render func(string, lipgloss.Style) string) string {
+	var builder strings.Builder
+	last := 0
+	for _, mention := range packet.FindMentions(content) {
+		builder.WriteString(render(content[last:mention.Start], style))
+		last = mention.End
+
+		var mentionStyle lipgloss.Style
+		text := content[mention.Start:mention.End]
+		switch mention.ID {
+		case packet.PingEveryone:
+			mentionStyle = MentionEveryoneStyle().Inherit(style)
+		case packet.PingAdmins:
+			mentionStyle = MentionAdminsStyle().Inherit(style)
+		default:
+			mentionStyle = MentionUserStyle().Inherit(style)
+			text = packet.MentionPrefix + "Unknown"
+			if user, ok := state.State.Users[mention.ID]; ok {
+				text = packet.MentionPrefix + user.Name
+			}
+		}
+		builder.WriteString(mentionStyle.Render(text))
+	}
+	builder.WriteString(render(content[last:], style))
+
+	return builder.String()
+}
+
 func (m *Model) renderHeader(message data.Message, selected bool) []byte {
 	var buf []byte
 	buf = append(buf, Padding...)
@@ -1432,9 +1793,12 @@ func (m *Model) renderHeader(message data.Message, selected bool) []byte {
 		member := members[message.SenderID]
 		user := state.State.Users[message.SenderID]
 		trustedPublicKey, isTrusted := state.State.TrustedUsers[user.ID]
-		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey)
+		isRotated := state.KeyRotated(user.ID)
+		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey) || isRotated
 
-		if isTrusted && !keysMatch {
+		if isRotated {
+			buf = append(buf, ui.KeyRotatedSymbol()...)
+		} else if isTrusted && !keysMatch {
 			buf = append(buf, ui.UntrustedSymbol()...)
 		}
 
@@ -1475,9 +1839,12 @@ func (m *Model) renderHeader(message data.Message, selected bool) []byte {
 	} else if m.receiverIndex != -1 {
 		user := state.State.Users[message.SenderID]
 		trustedPublicKey, isTrusted := state.State.TrustedUsers[user.ID]
-		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey)
+		isRotated := state.KeyRotated(user.ID)
+		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey) || isRotated
 
-		if isTrusted && !keysMatch {
+		if isRotated {
+			buf = append(buf, ui.KeyRotatedSymbol()...)
+		} else if isTrusted && !keysMatch {
 			buf = append(buf, ui.UntrustedSymbol()...)
 		}
 
@@ -1491,11 +1858,22 @@ func (m *Model) renderHeader(message data.Message, selected bool) []byte {
 		sender := senderStyle.Render(user.Name)
 		buf = append(buf, sender...)
 
+		if message.IsEncrypted {
+			buf = append(buf, LockSymbol()...)
+		}
+
 		if _, ok := state.State.BlockedUsers[user.ID]; ok {
 			buf = append(buf, blockStyle.Render(ui.BlockedSymbol())...)
 		}
 	}
 
+	switch state.State.Signatures[message.ID] {
+	case state.SignatureMissing:
+		buf = append(buf, UnsignedIndicator()...)
+	case state.SignatureInvalid:
+		buf = append(buf, InvalidSignatureIndicator()...)
+	}
+
 	// Render header time format
 	now := time.Now()
 	unixTime := time.UnixMilli(message.ID.Time()).Local()
@@ -1578,6 +1956,26 @@ func (m *Model) SetIndex(index int) {
 	}
 }
 
+// JumpToMessage selects the given message in the current chat,
+// scrolling to it on the next render
+func (m *Model) JumpToMessage(message snowflake.ID) {
+	m.jumpTo = &message
+	m.jumpStart = time.Now()
+	m.jumpBottom = -1
+	m.jumpHeight = -1
+}
+
+func (m *Model) source() *snowflake.ID {
+	networkId := state.NetworkId(m.networkIndex)
+	if m.frequencyIndex != -1 && networkId != nil {
+		frequencies := state.State.Frequencies[*networkId]
+		return &frequencies[m.frequencyIndex].ID
+	} else if m.receiverIndex != -1 {
+		return &state.Data.Signals[m.receiverIndex]
+	}
+	return nil
+}
+
 func (m *Model) editMessage() tea.Cmd {
 	message := m.vi.String()
 	if len(message) > MaxCharCount {
@@ -1587,9 +1985,33 @@ func (m *Model) editMessage() tea.Cmd {
 		return nil
 	}
 
+	message = strings.TrimSpace(message) // The server trims it, which would break the signature
+	if m.editingMessage.IsEncrypted && m.editingMessage.ReceiverID != nil {
+		key := state.EncryptionKey(*m.editingMessage.ReceiverID)
+		if key == nil {
+			return nil
+		}
+		ciphertext, err := e2ee.Encrypt(key, message)
+		if err != nil {
+			log.Println("failed to encrypt message:", err)
+			return nil
+		}
+		message = ciphertext
+	}
+
+	chat := m.editingMessage.FrequencyID
+	if chat == nil {
+		chat = m.editingMessage.GroupID
+	}
+	if chat == nil {
+		chat = m.editingMessage.ReceiverID
+	}
+	signature := state.SignMessage(*chat, m.editingMessage.AttachmentID, message)
+
 	return gateway.Send(&packet.EditMessage{
-		Message: m.editingMessage.ID,
-		Content: message,
+		Message:   m.editingMessage.ID,
+		Content:   message,
+		Signature: signature,
 	})
 }
 
@@ -1600,17 +2022,20 @@ func (m *Model) SetWidth(width int) {
 func (m *Model) renderFrequencyName() string {
 	name := ""
 	var color lipgloss.Color
+	var chatId *snowflake.ID
 
 	networkId := state.NetworkId(m.networkIndex)
 	if m.frequencyIndex != -1 && networkId != nil {
 		frequency := state.State.Frequencies[*networkId][m.frequencyIndex]
 		color = lipgloss.Color(frequency.HexColor)
 		name = frequency.Name
+		chatId = &frequency.ID
 	} else if m.receiverIndex != -1 {
 		signal := state.Data.Signals[m.receiverIndex]
 		user := state.State.Users[signal]
 		trustedPublicKey, isTrusted := state.State.TrustedUsers[user.ID]
-		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey)
+		isRotated := state.KeyRotated(user.ID)
+		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey) || isRotated
 
 		color = colors.Purple
 		if isTrusted && keysMatch {
@@ -1620,10 +2045,48 @@ func (m *Model) renderFrequencyName() string {
 		}
 
 		name = user.Name
+		if group, ok := state.State.GroupSignals[signal]; ok {
+			color = colors.Orange
+			name = group.Name
+		} else if isRotated {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Gold).Render(" 󰌆 Key rotated, signed by previous key")
+			if state.IsEncrypted(signal) {
+				name += lipgloss.NewStyle().Background(colors.Background).
+					Foreground(colors.Turquoise).Render(" 󰌾 Encrypted")
+			}
+		} else if state.IsEncrypted(signal) && state.KeyMismatch(signal) {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Red).Render(" 󰌿 Key changed, encryption paused")
+		} else if state.IsEncrypted(signal) && state.IsDeviceKey() {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Red).Render(" 󰌿 Signed in with a device key, encryption paused")
+		} else if state.IsEncrypted(signal) && state.LacksEncryptionKey() {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Red).Render(" 󰌿 Signed in without an ed25519 key file, encryption paused")
+		} else if state.IsEncrypted(signal) && !state.SupportsEncryption(signal) {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Red).Render(" 󰌿 Key type doesn't support encryption")
+		} else if state.IsEncrypted(signal) && state.EncryptionKey(signal) != nil {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Turquoise).Render(" 󰌾 Encrypted")
+		} else if state.IsEncrypted(signal) {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.Red).Render(" 󰌿 Not trusted, encryption paused")
+		}
+		chatId = &signal
 	} else {
 		name = ""
 	}
 
+	if chatId != nil {
+		typing := renderTyping(state.TypingUsers(*chatId))
+		if typing != "" && lipgloss.Width(name+"  "+typing) <= m.width {
+			name += lipgloss.NewStyle().Background(colors.Background).
+				Foreground(colors.LightGray).Render("  " + typing)
+		}
+	}
+
 	nameStyle := lipgloss.NewStyle().Width(m.width).
 		Background(colors.Background).Foreground(color).
 		AlignHorizontal(lipgloss.Center).
@@ -1652,6 +2115,17 @@ func (m *Model) NewMsgSep(height, remainingHeight int) string {
 	return newMsgStyle.Render(line + NewText)
 }
 
+// SeenMarker is shown under the last message the receiver has read
+func (m *Model) SeenMarker(height, remainingHeight int) string {
+	seenStyle := lipgloss.NewStyle().Foreground(colors.LightGray).
+		Width(m.width).Align(lipgloss.Right)
+	if m.index == height-remainingHeight {
+		seenStyle = seenStyle.Background(colors.BackgroundDim)
+	}
+
+	return seenStyle.Render(SeenText)
+}
+
 func (m *Model) OnNewMessageReceived(info *packet.MessagesInfo) {
 	assert.Assert(len(info.Messages) == 1, "expected only a single message", "len", len(info.Messages))
 
@@ -1684,3 +2158,23 @@ func (m *Model) OnNewMessageReceived(info *packet.MessagesInfo) {
 		}
 	}
 }
+
+func renderTyping(users []snowflake.ID) string {
+	names := make([]string, 0, len(users))
+	for _, id := range users {
+		if user, ok := state.State.Users[id]; ok {
+			names = append(names, user.Name)
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0] + " is typing…"
+	case 2:
+		return names[0] + " and " + names[1] + " are typing…"
+	default:
+		return fmt.Sprintf("%s and %d others are typing…", names[0], len(names)-1)
+	}
+}
@@ -0,0 +1,99 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chat
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/packet"
+)
+
+const (
+	LinkSeparator       = " › "
+	LinkMessage         = "message"
+	LinkUnknown         = "unknown"
+	LinkFrequencyPrefix = "#"
+)
+
+var LinkStyle = func() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(colors.LightBlue).Underline(true)
+}
+
+// link returns a link to the selected message, or to the
+// current frequency if no message is selected.
+// DMs can't be linked to so nil is returned.
+func (m *Model) link() *packet.Link {
+	networkId := state.NetworkId(m.networkIndex)
+	if m.frequencyIndex == -1 || networkId == nil {
+		return nil
+	}
+	frequencyId := state.State.Frequencies[*networkId][m.frequencyIndex].ID
+
+	link := &packet.Link{Network: *networkId, Frequency: &frequencyId}
+	if m.selectedMessage != nil {
+		link.Message = &m.selectedMessage.ID
+	}
+	return link
+}
+
+// linkText returns the human readable name of the link
+func linkText(link packet.Link) string {
+	network, ok := state.State.Networks[link.Network]
+	if !ok {
+		return LinkUnknown
+	}
+
+	var builder strings.Builder
+	builder.WriteString(network.Name)
+
+	if link.Frequency != nil {
+		name := LinkUnknown
+		for _, frequency := range state.State.Frequencies[link.Network] {
+			if frequency.ID == *link.Frequency {
+				name = frequency.Name
+				break
+			}
+		}
+		builder.WriteString(LinkSeparator)
+		builder.WriteString(LinkFrequencyPrefix)
+		builder.WriteString(name)
+	}
+
+	if link.Message != nil {
+		builder.WriteString(LinkSeparator)
+		builder.WriteString(LinkMessage)
+	}
+
+	return builder.String()
+}
+
+func renderLinks(content string, style lipgloss.Style, render func(string, lipgloss.Style) string) string {
+	var builder strings.Builder
+	last := 0
+	for _, link := range packet.FindLinks(content) {
+		builder.WriteString(render(content[last:link.Start], style))
+		last = link.End
+		builder.WriteString(LinkStyle().Inherit(style).Render(linkText(link)))
+	}
+	builder.WriteString(render(content[last:], style))
+
+	return builder.String()
+}
@@ -0,0 +1,388 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chat
+
+import (
+	"container/list"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyren223/eko/internal/client/ui/colors"
+)
+
+const (
+	CodeFence      = "```"
+	CodeTabWidth   = 4
+	CodeBlockStyle = "catppuccin-mocha"
+	QuoteBar       = "┃ "
+	ListBullet     = "• "
+
+	maxCodeBlockCache = 128
+)
+
+type codeBlockKey struct {
+	code       string
+	lang       string
+	background string
+}
+
+type codeBlockEntry struct {
+	key   codeBlockKey
+	lines []string
+}
+
+// Highlighting is expensive and messages are re-rendered on every update,
+// the least recently rendered code blocks are evicted first
+var codeBlockCache = newCodeBlockLRU(maxCodeBlockCache)
+
+type codeBlockLRU struct {
+	capacity int
+	entries  map[codeBlockKey]*list.Element
+	// Most recently used at the front
+	lru *list.List
+}
+
+func newCodeBlockLRU(capacity int) *codeBlockLRU {
+	return &codeBlockLRU{
+		capacity: capacity,
+		entries:  map[codeBlockKey]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+func (c *codeBlockLRU) Get(key codeBlockKey) ([]string, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return element.Value.(*codeBlockEntry).lines, true
+}
+
+func (c *codeBlockLRU) Put(key codeBlockKey, lines []string) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*codeBlockEntry).lines = lines
+		c.lru.MoveToFront(element)
+		return
+	}
+	if c.lru.Len() >= c.capacity {
+		oldest := c.lru.Back()
+		delete(c.entries, oldest.Value.(*codeBlockEntry).key)
+		c.lru.Remove(oldest)
+	}
+	c.entries[key] = c.lru.PushFront(&codeBlockEntry{key: key, lines: lines})
+}
+
+func (c *codeBlockLRU) Len() int {
+	return c.lru.Len()
+}
+
+// markdown renders the subset of markdown supported in messages.
+// Every piece of text is rendered on top of the style it's given so
+// backgrounds (selection, pings) are preserved, and the result contains
+// no padding, so it can be measured and wrapped like plain text.
+type markdown struct {
+	// text renders a plain text segment (no markdown) with the given style
+	text           func(s string, style lipgloss.Style) string
+	revealSpoilers bool
+}
+
+func (md markdown) Render(content string, style lipgloss.Style) string {
+	lines := strings.Split(content, "\n")
+	rendered := make([]string, 0, len(lines))
+
+	for i := 0; i < len(lines); i++ {
+		lang, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), CodeFence)
+		if ok && !strings.Contains(lang, "`") {
+			end := -1
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == CodeFence {
+					end = j
+					break
+				}
+			}
+			if end != -1 {
+				code := strings.Join(lines[i+1:end], "\n")
+				rendered = append(rendered, md.codeBlock(code, strings.TrimSpace(lang), style)...)
+				i = end
+				continue
+			}
+		}
+
+		rendered = append(rendered, md.line(lines[i], style))
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+func (md markdown) line(line string, style lipgloss.Style) string {
+	markerStyle := style.Foreground(colors.LightGray)
+
+	trimmed := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(trimmed)]
+
+	if rest, ok := strings.CutPrefix(trimmed, ">"); ok {
+		rest = strings.TrimPrefix(rest, " ")
+		return md.text(indent, style) + markerStyle.Render(QuoteBar) + md.line(rest, style)
+	}
+
+	for _, bullet := range []string{"- ", "* ", "+ "} {
+		if rest, ok := strings.CutPrefix(trimmed, bullet); ok {
+			return md.text(indent, style) + markerStyle.Render(ListBullet) + md.inline(rest, style)
+		}
+	}
+
+	digits := 0
+	for digits < len(trimmed) && '0' <= trimmed[digits] && trimmed[digits] <= '9' {
+		digits++
+	}
+	if 0 < digits && digits <= 9 && strings.HasPrefix(trimmed[digits:], ". ") {
+		marker := trimmed[:digits+2]
+		return md.text(indent, style) + markerStyle.Render(marker) + md.inline(trimmed[digits+2:], style)
+	}
+
+	return md.inline(line, style)
+}
+
+func (md markdown) inline(s string, style lipgloss.Style) string {
+	var builder strings.Builder
+
+	last := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c == '\\' && i+1 < len(s) && isEscapable(s[i+1]) {
+			builder.WriteString(md.text(s[last:i], style))
+			last = i + 1 // Keep the escaped char as literal text
+			i += 2
+			continue
+		}
+
+		if c == '`' {
+			n := runLength(s, i, '`')
+			delimiter := s[i : i+n]
+			end := strings.Index(s[i+n:], delimiter)
+			if end == -1 || end == 0 {
+				i += n
+				continue
+			}
+			code := s[i+n : i+n+end]
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+
+			builder.WriteString(md.text(s[last:i], style))
+			builder.WriteString(style.Background(colors.DarkGray).Foreground(colors.Orange).Render(code))
+			i += n + end + n
+			last = i
+			continue
+		}
+
+		delimiter, inner, ok := emphasis(s, i)
+		if !ok {
+			i++
+			continue
+		}
+		builder.WriteString(md.text(s[last:i], style))
+
+		switch delimiter {
+		case "**":
+			builder.WriteString(md.inline(inner, style.Bold(true)))
+		case "~~":
+			builder.WriteString(md.inline(inner, style.Strikethrough(true)))
+		case "*", "_":
+			builder.WriteString(md.inline(inner, style.Italic(true)))
+		case "||":
+			spoilerStyle := style.Background(colors.DarkGray)
+			spoiler := md.inline(inner, spoilerStyle)
+			if !md.revealSpoilers {
+				// Same width as the revealed text, so heights don't change
+				spoiler = spoilerStyle.Foreground(colors.DarkGray).Render(ansi.Strip(spoiler))
+			}
+			builder.WriteString(spoiler)
+		}
+
+		i += 2*len(delimiter) + len(inner)
+		last = i
+	}
+	builder.WriteString(md.text(s[last:], style))
+
+	return builder.String()
+}
+
+// emphasis checks if there is an emphasis (bold, italic, strikethrough
+// or spoiler) starting at i, returning the delimiter and inner text.
+func emphasis(s string, i int) (delimiter, inner string, ok bool) {
+	c := s[i]
+	if c != '*' && c != '_' && c != '~' && c != '|' {
+		return "", "", false
+	}
+
+	n := runLength(s, i, c)
+	switch {
+	case (c == '~' || c == '|' || c == '*') && n == 2:
+		delimiter = s[i : i+2]
+	case (c == '*' || c == '_') && n == 1:
+		delimiter = s[i : i+1]
+	default:
+		return "", "", false
+	}
+
+	start := i + len(delimiter)
+	if start >= len(s) || s[start] == ' ' {
+		return "", "", false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", "", false // snake_case
+	}
+
+	for j := start; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] == '`' {
+			// Delimiters inside inline code don't count
+			n := runLength(s, j, '`')
+			if end := strings.Index(s[j+n:], s[j:j+n]); end != -1 {
+				j += n + end + n - 1
+			} else {
+				j += n - 1
+			}
+			continue
+		}
+		if s[j] != c {
+			continue
+		}
+
+		n := runLength(s, j, c)
+		if n != len(delimiter) {
+			j += n - 1
+			continue
+		}
+		if j == start || s[j-1] == ' ' {
+			continue
+		}
+		if c == '_' && j+1 < len(s) && isWordByte(s[j+1]) {
+			continue
+		}
+
+		return delimiter, s[start:j], true
+	}
+
+	return "", "", false
+}
+
+func (md markdown) codeBlock(code, lang string, style lipgloss.Style) []string {
+	key := codeBlockKey{code, lang, string(colors.BackgroundDimmer)}
+	if lines, ok := codeBlockCache.Get(key); ok {
+		return lines
+	}
+
+	lines := highlightCode(code, lang, style.Background(colors.BackgroundDimmer).Foreground(colors.White))
+	codeBlockCache.Put(key, lines)
+	return lines
+}
+
+func highlightCode(code, lang string, codeStyle lipgloss.Style) []string {
+	code = strings.ReplaceAll(code, "\t", strings.Repeat(" ", CodeTabWidth))
+
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	theme := styles.Get(CodeBlockStyle)
+
+	lines := []string{""}
+	widths := []int{0}
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		iterator = chroma.Literator(chroma.Token{Type: chroma.Text, Value: code})
+	}
+	for _, token := range iterator.Tokens() {
+		tokenStyle := codeStyle
+		entry := theme.Get(token.Type)
+		if entry.Colour.IsSet() {
+			tokenStyle = tokenStyle.Foreground(lipgloss.Color(entry.Colour.String()))
+		}
+		if entry.Bold == chroma.Yes {
+			tokenStyle = tokenStyle.Bold(true)
+		}
+		if entry.Italic == chroma.Yes {
+			tokenStyle = tokenStyle.Italic(true)
+		}
+
+		for i, part := range strings.Split(token.Value, "\n") {
+			if i != 0 {
+				lines = append(lines, "")
+				widths = append(widths, 0)
+			}
+			if part == "" {
+				continue
+			}
+			lines[len(lines)-1] += tokenStyle.Render(part)
+			widths[len(widths)-1] += ansi.StringWidth(part)
+		}
+	}
+
+	// Chroma ensures a trailing newline
+	if len(lines) > 1 && widths[len(widths)-1] == 0 {
+		lines = lines[:len(lines)-1]
+		widths = widths[:len(widths)-1]
+	}
+
+	maxWidth := 0
+	for _, width := range widths {
+		maxWidth = max(maxWidth, width)
+	}
+	for i := range lines {
+		padding := strings.Repeat(" ", maxWidth-widths[i]+1)
+		lines[i] = codeStyle.Render(" ") + lines[i] + codeStyle.Render(padding)
+	}
+
+	return lines
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isEscapable(c byte) bool {
+	return strings.IndexByte("\\`*_~|>-+#.", c) != -1
+}
+
+func isWordByte(c byte) bool {
+	if c >= utf8.RuneSelf {
+		return true
+	}
+	return unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
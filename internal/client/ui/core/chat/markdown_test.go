@@ -0,0 +1,188 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kyren223/eko/internal/client/ui/colors"
+)
+
+// testMarkdown marks each plain text segment with the emphasis it's
+// rendered with, like [bi:text] for bold italic text
+func testMarkdown(revealSpoilers bool) markdown {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	return markdown{
+		text: func(s string, style lipgloss.Style) string {
+			if s == "" {
+				return ""
+			}
+			tags := ""
+			if style.GetBold() {
+				tags += "b"
+			}
+			if style.GetItalic() {
+				tags += "i"
+			}
+			if style.GetStrikethrough() {
+				tags += "s"
+			}
+			if style.GetBackground() == colors.DarkGray {
+				tags += "p"
+			}
+			if tags == "" {
+				return s
+			}
+			return fmt.Sprintf("[%v:%v]", tags, s)
+		},
+		revealSpoilers: revealSpoilers,
+	}
+}
+
+func TestMarkdownInline(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"bold", "a **b** c", "a [b:b] c"},
+		{"italic star", "a *b* c", "a [i:b] c"},
+		{"italic underscore", "a _b_ c", "a [i:b] c"},
+		{"strikethrough", "~~gone~~", "[s:gone]"},
+		{"spoiler", "||secret||", "[p:secret]"},
+		{"bold in italic", "*a **b** c*", "[i:a ][bi:b][i: c]"},
+		{"italic in bold", "**a _b_ c**", "[b:a ][bi:b][b: c]"},
+		{"strikethrough in spoiler", "||~~x~~||", "[sp:x]"},
+		{"snake case", "snake_case_name", "snake_case_name"},
+		{"space after opening", "a ** b**", "a ** b**"},
+		{"space before closing", "a **b **", "a **b **"},
+
+		{"code", "a `b` c", "a b c"},
+		{"code keeps markers", "`**b**`", "**b**"},
+		{"double backtick code", "``a ` b``", "a ` b"},
+		{"code padding", "`` `b` ``", "`b`"},
+		{"emphasis around code", "**a `*` b**", "[b:a ]*[b: b]"},
+
+		{"escaped star", `\*a\*`, "*a*"},
+		{"escaped backtick", "\\`a\\`", "`a`"},
+		{"escaped backslash", `\\`, `\`},
+		{"escaped closing", `*a\*b*`, `[i:a][i:*b]`},
+		{"not escapable", `\a`, `\a`},
+
+		{"unterminated bold", "**a", "**a"},
+		{"unterminated italic", "a *b c", "a *b c"},
+		{"unterminated spoiler", "||a", "||a"},
+		{"unterminated code", "`a", "`a"},
+		{"mismatched delimiters", "**a*", "*[i:a]"},
+		{"lone markers", "a * ~ | _", "a * ~ | _"},
+	}
+
+	md := testMarkdown(true)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, md.Render(test.content, lipgloss.NewStyle()))
+		})
+	}
+}
+
+func TestMarkdownLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"quote", "> **a**", QuoteBar + "[b:a]"},
+		{"nested quote", "> > a", QuoteBar + QuoteBar + "a"},
+		{"bullet", "- a", ListBullet + "a"},
+		{"indented bullet", "  * a", "  " + ListBullet + "a"},
+		{"numbered", "12. a", "12. a"},
+		{"not numbered", "12.a", "12.a"},
+		{"multiple lines", "*a*\n*b*", "[i:a]\n[i:b]"},
+		{"emphasis doesn't span lines", "**a\nb**", "**a\nb**"},
+	}
+
+	md := testMarkdown(true)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, md.Render(test.content, lipgloss.NewStyle()))
+		})
+	}
+}
+
+func TestMarkdownCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"fenced", "```\na\n```", []string{" a "}},
+		{"fenced with language", "```go\nx := 1\n```", []string{" x := 1 "}},
+		{"padded to widest line", "```\nab\na\n```", []string{" ab ", " a  "}},
+		{"tabs", "```\n\ta\n```", []string{"     a "}},
+		{"markers are literal", "```\n**a**\n```", []string{" **a** "}},
+		{"text around", "a\n```\nb\n```\n*c*", []string{"a", " b ", "[i:c]"}},
+		{"unterminated", "```\n**a**", []string{"```", "[b:a]"}},
+		{"backticks after fence", "```a`\nb\n```", []string{"```a`", "b", "```"}},
+	}
+
+	md := testMarkdown(true)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered := ansi.Strip(md.Render(test.content, lipgloss.NewStyle()))
+			require.Equal(t, test.want, strings.Split(rendered, "\n"))
+		})
+	}
+}
+
+func TestMarkdownHiddenSpoiler(t *testing.T) {
+	revealed := testMarkdown(true).Render("a ||**secret**||", lipgloss.NewStyle())
+	hidden := testMarkdown(false).Render("a ||**secret**||", lipgloss.NewStyle())
+	require.Equal(t, ansi.StringWidth(revealed), ansi.StringWidth(hidden))
+}
+
+func TestCodeBlockLRU(t *testing.T) {
+	cache := newCodeBlockLRU(2)
+	a := codeBlockKey{code: "a"}
+	b := codeBlockKey{code: "b"}
+	c := codeBlockKey{code: "c"}
+
+	cache.Put(a, []string{"a"})
+	cache.Put(b, []string{"b"})
+	_, ok := cache.Get(a)
+	require.True(t, ok)
+
+	// b is the least recently used
+	cache.Put(c, []string{"c"})
+	require.Equal(t, 2, cache.Len())
+	_, ok = cache.Get(b)
+	require.False(t, ok)
+
+	lines, ok := cache.Get(a)
+	require.True(t, ok)
+	require.Equal(t, []string{"a"}, lines)
+	lines, ok = cache.Get(c)
+	require.True(t, ok)
+	require.Equal(t, []string{"c"}, lines)
+}
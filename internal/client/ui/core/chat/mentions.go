@@ -0,0 +1,130 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package chat
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/viminput"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+// mentionCompleter returns a completer that fuzzy matches the members
+// of the network, as well as @everyone and @admins.
+// Completions insert the mention token so duplicate names are never ambiguous.
+func mentionCompleter(networkId snowflake.ID) viminput.Completer {
+	return func(word string) []viminput.Completion {
+		type candidate struct {
+			name  string
+			id    snowflake.ID
+			score int
+		}
+
+		members := state.State.Members[networkId]
+		names := map[string]int{}
+		candidates := []candidate{}
+
+		for _, member := range members {
+			if !member.IsMember {
+				continue
+			}
+			user, ok := state.State.Users[member.UserID]
+			if !ok {
+				continue
+			}
+			names[user.Name]++
+			candidates = append(candidates, candidate{name: user.Name, id: user.ID})
+		}
+
+		if members[*state.UserID].IsAdmin {
+			candidates = append(candidates, candidate{name: packet.MentionEveryone, id: packet.PingEveryone})
+		}
+		candidates = append(candidates, candidate{name: packet.MentionAdmins, id: packet.PingAdmins})
+
+		matches := make([]candidate, 0, len(candidates))
+		for _, c := range candidates {
+			score, ok := fuzzyScore(word, c.name)
+			if ok {
+				c.score = score
+				matches = append(matches, c)
+			}
+		}
+
+		slices.SortFunc(matches, func(a, b candidate) int {
+			if a.score != b.score {
+				return b.score - a.score
+			}
+			if len(a.name) != len(b.name) {
+				return len(a.name) - len(b.name)
+			}
+			if a.name != b.name {
+				return strings.Compare(a.name, b.name)
+			}
+			return cmp.Compare(a.id, b.id)
+		})
+
+		completions := make([]viminput.Completion, 0, len(matches))
+		for _, match := range matches {
+			display := packet.MentionPrefix + match.name
+			isUser := match.id != packet.PingEveryone && match.id != packet.PingAdmins
+			if isUser && names[match.name] > 1 {
+				display += " (" + match.id.String() + ")"
+			}
+			completions = append(completions, viminput.Completion{
+				Display: display,
+				Insert:  packet.MentionToken(match.id),
+			})
+		}
+
+		return completions
+	}
+}
+
+// fuzzyScore returns how well the pattern matches s, case insensitive.
+// All characters of the pattern must appear in s in order, matches at
+// the start of s and consecutive matches are scored higher.
+func fuzzyScore(pattern, s string) (score int, ok bool) {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+
+	if pattern == "" {
+		return 0, true
+	}
+
+	i := 0
+	last := -1
+	for j := 0; j < len(s) && i < len(pattern); j++ {
+		if s[j] != pattern[i] {
+			continue
+		}
+
+		score++
+		if j == 0 {
+			score += 10
+		} else if last == j-1 {
+			score += 5
+		}
+		last = j
+		i++
+	}
+
+	return score, i == len(pattern)
+}
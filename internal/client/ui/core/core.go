@@ -17,7 +17,9 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -36,21 +38,29 @@ import (
 	"github.com/kyren223/eko/internal/client/gateway"
 	"github.com/kyren223/eko/internal/client/ui"
 	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/attachmentpath"
 	"github.com/kyren223/eko/internal/client/ui/core/banreason"
 	"github.com/kyren223/eko/internal/client/ui/core/banview"
 	"github.com/kyren223/eko/internal/client/ui/core/chat"
+	"github.com/kyren223/eko/internal/client/ui/core/devices"
 	"github.com/kyren223/eko/internal/client/ui/core/frequencycreation"
 	"github.com/kyren223/eko/internal/client/ui/core/frequencylist"
 	"github.com/kyren223/eko/internal/client/ui/core/frequencyupdate"
+	"github.com/kyren223/eko/internal/client/ui/core/groupsignal"
+	"github.com/kyren223/eko/internal/client/ui/core/keyrotation"
 	"github.com/kyren223/eko/internal/client/ui/core/memberlist"
 	"github.com/kyren223/eko/internal/client/ui/core/networkcreation"
 	"github.com/kyren223/eko/internal/client/ui/core/networkjoin"
 	"github.com/kyren223/eko/internal/client/ui/core/networklist"
 	"github.com/kyren223/eko/internal/client/ui/core/networkupdate"
+	"github.com/kyren223/eko/internal/client/ui/core/pins"
 	"github.com/kyren223/eko/internal/client/ui/core/profile"
+	"github.com/kyren223/eko/internal/client/ui/core/search"
+	"github.com/kyren223/eko/internal/client/ui/core/sessions"
 	"github.com/kyren223/eko/internal/client/ui/core/signaladd"
 	"github.com/kyren223/eko/internal/client/ui/core/signallist"
 	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/core/transfer"
 	"github.com/kyren223/eko/internal/client/ui/core/usersettings"
 	"github.com/kyren223/eko/internal/client/ui/loadscreen"
 	"github.com/kyren223/eko/internal/client/ui/tosscreen"
@@ -59,6 +69,8 @@ import (
 	"github.com/kyren223/eko/internal/packet"
 	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
+	"golang.org/x/crypto/ssh"
 )
 
 const (
@@ -102,8 +114,10 @@ const (
 )
 
 type Model struct {
-	name    string
-	privKey ed25519.PrivateKey
+	name       string
+	signer     ssh.Signer
+	privKey    ed25519.PrivateKey
+	pendingKey *keyrotation.PendingMsg
 
 	loading loadscreen.Model
 	tos     *tosscreen.Model
@@ -123,6 +137,13 @@ type Model struct {
 	banViewPopup           *banview.Model
 	signalAddPopup         *signaladd.Model
 	profilePopup           *profile.Model
+	pinsPopup              *pins.Model
+	searchPopup            *search.Model
+	attachmentPathPopup    *attachmentpath.Model
+	groupSignalPopup       *groupsignal.Model
+	keyRotationPopup       *keyrotation.Model
+	devicesPopup           *devices.Model
+	sessionsPopup          *sessions.Model
 	networkList            networklist.Model
 	signalList             signallist.Model
 	frequencyList          frequencylist.Model
@@ -131,9 +152,13 @@ type Model struct {
 	focus                  int
 }
 
-func New(privKey ed25519.PrivateKey, name string) Model {
+// New creates the core model, the signer is either a private key file
+// or an ssh-agent identity. privKey is only set for ed25519 key files,
+// as encryption keys are derived from it.
+func New(signer ssh.Signer, privKey ed25519.PrivateKey, name string) Model {
 	m := Model{
 		name:                   name,
+		signer:                 signer,
 		privKey:                privKey,
 		loading:                loadscreen.New(ConnectingToServer),
 		tos:                    nil,
@@ -152,6 +177,13 @@ func New(privKey ed25519.PrivateKey, name string) Model {
 		banViewPopup:           nil,
 		signalAddPopup:         nil,
 		profilePopup:           nil,
+		pinsPopup:              nil,
+		searchPopup:            nil,
+		attachmentPathPopup:    nil,
+		groupSignalPopup:       nil,
+		keyRotationPopup:       nil,
+		devicesPopup:           nil,
+		sessionsPopup:          nil,
 		networkList:            networklist.New(),
 		signalList:             signallist.New(),
 		frequencyList:          frequencylist.New(),
@@ -278,6 +310,20 @@ func (m Model) View() string {
 			popup = m.signalAddPopup.View()
 		} else if m.profilePopup != nil {
 			popup = m.profilePopup.View()
+		} else if m.pinsPopup != nil {
+			popup = m.pinsPopup.View()
+		} else if m.searchPopup != nil {
+			popup = m.searchPopup.View()
+		} else if m.attachmentPathPopup != nil {
+			popup = m.attachmentPathPopup.View()
+		} else if m.groupSignalPopup != nil {
+			popup = m.groupSignalPopup.View()
+		} else if m.keyRotationPopup != nil {
+			popup = m.keyRotationPopup.View()
+		} else if m.devicesPopup != nil {
+			popup = m.devicesPopup.View()
+		} else if m.sessionsPopup != nil {
+			popup = m.sessionsPopup.View()
 		} else {
 			assert.Never("missing handling of a popup!")
 		}
@@ -325,6 +371,12 @@ func (m *Model) updateDisconnected(msg tea.Msg) tea.Cmd {
 
 	case gateway.ConnectionFailed:
 		log.Println("failed to connect:", msg)
+		var mismatch *gateway.CertificateMismatch
+		if errors.As(msg, &mismatch) {
+			// Never retry, the user must explicitly decide to trust the new certificate
+			m.loading.SetContent(certificateMismatchWarning(mismatch))
+			return nil
+		}
 		m.timer = newTimer(m.timeout)
 		m.updateLoadScreenContent()
 		return m.timer.Start()
@@ -366,7 +418,7 @@ func (m *Model) updateConnected(message tea.Msg) tea.Cmd {
 	case *packet.TosInfo:
 		m.state = ConnectedReceivedTos
 
-		server := config.ReadConfig().ServerName
+		server := config.ReadConfig().Server().Host
 		hash, ok := config.ReadCache().TosHashes[server]
 		if ok && hash == msg.Hash {
 			// AUTO ACCEPT IF IN CACHE
@@ -396,15 +448,23 @@ func (m *Model) updateConnected(message tea.Msg) tea.Cmd {
 
 	case *packet.NonceInfo:
 		if m.state == ConnectedAcceptedTos {
-			pubKey := ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))
-			copy(pubKey, m.privKey[32:])
-
-			signature := ed25519.Sign(m.privKey, msg.Nonce)
+			signer := m.signer
+			nonce := msg.Nonce
+
+			// Signing may go through ssh-agent, so don't block the UI on it
+			return func() tea.Msg {
+				signature, err := sshkey.Sign(signer, nonce)
+				if err != nil {
+					log.Println("failed signing nonce:", err)
+					gateway.Disconnect()
+					return ui.ModelTransition{Model: ui.NewAuth()}
+				}
 
-			return gateway.Send(&packet.Authenticate{
-				PubKey:    pubKey,
-				Signature: signature,
-			})
+				return gateway.Send(&packet.Authenticate{
+					PubKey:    sshkey.FromSSH(signer.PublicKey()),
+					Signature: signature,
+				})()
+			}
 		}
 
 	case *packet.UsersInfo:
@@ -413,21 +473,25 @@ func (m *Model) updateConnected(message tea.Msg) tea.Cmd {
 
 			m.state = Authenticated
 			state.UserID = &msg.Users[0].ID
+			state.Signer = m.signer
+			state.PrivateKey = m.privKey
 
 			var setName tea.Cmd
 			if m.name != "" {
 				setName = gateway.Send(&packet.SetUserData{
 					Data: nil,
 					User: &data.User{
-						Name:        m.name,
-						Description: "",
-						IsPublicDM:  true,
+						Name:             m.name,
+						Description:      "",
+						IsPublicDM:       true,
+						IsPresencePublic: true,
 					},
 				})
 				m.name = ""
 			}
 
-			return setName
+			// The server forgets what we were viewing on reconnect
+			return tea.Batch(setName, m.chat.ViewChat())
 		}
 
 	case tea.KeyMsg:
@@ -437,7 +501,7 @@ func (m *Model) updateConnected(message tea.Msg) tea.Cmd {
 				m.state = ConnectedAcceptedTos
 				assert.Assert(m.tosHash != "", "hash must have been set by the TosInfo")
 				err := config.UseCache(func(cache *config.Cache) {
-					server := config.ReadConfig().ServerName
+					server := config.ReadConfig().Server().Host
 					cache.TosHashes[server] = m.tosHash
 				})
 				log.Println("unable to cache TOS acceptance: ", err)
@@ -484,15 +548,16 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 
 	case gateway.ConnectionLost:
 		state.UserID = nil
+		transfer.Reset()
 		m.state = Disconnected
 		m.timeout = InitialTimeout
 		return tea.Batch(gateway.Connect(ConnectionTimeout), m.loading.Init())
 
 	case *packet.Error:
-		err := "new connection from another location, closing this one"
-		if err == msg.Error {
+		if msg.Error == packet.ErrDeviceRevoked || msg.Error == packet.ErrSessionTerminated {
 			gateway.Disconnect()
 			state.Reset()
+			transfer.Reset()
 			return ui.Transition(ui.NewAuth())
 		}
 
@@ -553,6 +618,18 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 
 	case *packet.UsersInfo:
 		state.UpdateUsersInfo(msg)
+		m.applyKeyRotation(msg)
+
+	case *packet.KeyRotationsInfo:
+		state.UpdateKeyRotations(msg)
+
+	case *packet.DevicesInfo:
+		state.UpdateDevices(msg)
+
+	case *packet.SessionsInfo:
+		if m.sessionsPopup != nil {
+			m.sessionsPopup.SetSessions(msg.Sessions)
+		}
 
 	case *packet.NotificationsInfo:
 		signals := state.UpdateNotifications(msg)
@@ -576,6 +653,75 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 		popup := profile.New(msg.User)
 		m.profilePopup = &popup
 
+	case ui.PinsPopupMsg:
+		popup := pins.New(msg.Source)
+		m.pinsPopup = &popup
+
+	case ui.SearchPopupMsg:
+		popup := search.New(msg.Network, msg.Frequency, msg.Receiver)
+		m.searchPopup = &popup
+
+	case *packet.SearchResults:
+		if m.searchPopup != nil {
+			m.searchPopup.SetResults(msg)
+		}
+
+	case *packet.AttachmentsInfo:
+		state.UpdateAttachments(msg)
+
+	case *packet.Typing:
+		state.UpdateTyping(msg)
+
+	case *packet.PresenceInfo:
+		state.UpdatePresences(msg)
+
+	case *packet.ReadReceiptsInfo:
+		state.UpdateReadReceipts(msg)
+
+	case *packet.GroupSignalsInfo:
+		state.UpdateGroupSignals(msg)
+		m.signalList.SetIndex(m.signalList.Index())
+
+	case *packet.MessageRequestsInfo:
+		state.UpdateMessageRequests(msg)
+		m.signalList.SetIndex(m.signalList.Index())
+
+	case ui.UploadAttachmentPopupMsg:
+		popup := attachmentpath.NewUpload(msg.Frequency, msg.Receiver)
+		m.attachmentPathPopup = &popup
+
+	case ui.KeyRotationPopupMsg:
+		popup := keyrotation.New()
+		m.keyRotationPopup = &popup
+
+	case ui.DevicesPopupMsg:
+		popup := devices.New()
+		m.devicesPopup = &popup
+
+	case ui.SessionsPopupMsg:
+		popup := sessions.New()
+		m.sessionsPopup = &popup
+		return popup.Init()
+
+	case keyrotation.PendingMsg:
+		m.pendingKey = &msg
+
+	case ui.SaveAttachmentPopupMsg:
+		attachment, ok := state.State.Attachments[msg.Attachment]
+		if ok {
+			popup := attachmentpath.NewSave(attachment)
+			m.attachmentPathPopup = &popup
+		}
+
+	case ui.JumpToMessageMsg:
+		m.chat.JumpToMessage(msg.Message)
+
+	case ui.OpenLinkMsg:
+		m.openLink(msg.Link)
+
+	case ui.OpenSignalMsg:
+		m.openSignal(msg.Signal, msg.Message)
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "n":
@@ -601,6 +747,23 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 					m.frequencyCreationPopup = &popup
 					message = ui.EmptyMsg{}
 				}
+				if !m.HasPopup() && !IsFrequenciesSidebar {
+					popup := groupsignal.NewCreate()
+					m.groupSignalPopup = &popup
+					message = ui.EmptyMsg{}
+				}
+			}
+
+		case "m":
+			isSignalsSidebar := m.networkList.Index() == networklist.SignalsIndex
+			index := m.signalList.Index()
+			if !m.HasPopup() && m.focus == FocusLeftSidebar && isSignalsSidebar && index != -1 {
+				groupId := state.Data.Signals[index]
+				if state.IsGroupSignal(groupId) {
+					popup := groupsignal.NewAddMember(groupId)
+					m.groupSignalPopup = &popup
+					message = ui.EmptyMsg{}
+				}
 			}
 
 		case "a":
@@ -709,6 +872,13 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 				m.banViewPopup = nil
 				m.signalAddPopup = nil
 				m.profilePopup = nil
+				m.pinsPopup = nil
+				m.searchPopup = nil
+				m.attachmentPathPopup = nil
+				m.groupSignalPopup = nil
+				m.keyRotationPopup = nil
+				m.devicesPopup = nil
+				m.sessionsPopup = nil
 			}
 
 		case "enter":
@@ -767,6 +937,42 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 				return cmd
 			} else if m.profilePopup != nil {
 				m.profilePopup = nil
+			} else if m.pinsPopup != nil {
+				cmd := m.pinsPopup.Select()
+				m.pinsPopup = nil
+				return cmd
+			} else if m.searchPopup != nil {
+				cmd, done := m.searchPopup.Select()
+				if done {
+					m.searchPopup = nil
+				}
+				return cmd
+			} else if m.attachmentPathPopup != nil {
+				cmd, ok := m.attachmentPathPopup.Select()
+				if ok {
+					m.attachmentPathPopup = nil
+				}
+				return cmd
+			} else if m.groupSignalPopup != nil {
+				cmd, ok := m.groupSignalPopup.Select()
+				if ok {
+					m.groupSignalPopup = nil
+				}
+				return cmd
+			} else if m.keyRotationPopup != nil {
+				cmd, ok := m.keyRotationPopup.Select()
+				if ok {
+					m.keyRotationPopup = nil
+				}
+				return cmd
+			} else if m.devicesPopup != nil {
+				cmd, ok := m.devicesPopup.Select()
+				if ok {
+					m.devicesPopup = nil
+				}
+				return cmd
+			} else if m.sessionsPopup != nil {
+				return m.sessionsPopup.Select()
 			}
 
 		default:
@@ -788,6 +994,16 @@ func (m *Model) updateAuthenticated(message tea.Msg) tea.Cmd {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
+	// Before popups, transfers continue in the background
+	cmds = append(cmds, transfer.Update(message))
+
+	if _, ok := message.(*packet.Typing); ok {
+		// Re-render once the typing indicator expires
+		cmds = append(cmds, tea.Tick(state.TypingTimeout, func(time.Time) tea.Msg {
+			return ui.EmptyMsg{}
+		}))
+	}
+
 	if m.HasPopup() {
 		cmd := m.updatePopups(message)
 		cmds = append(cmds, cmd)
@@ -834,6 +1050,24 @@ func (m *Model) updateLoadScreenContent() {
 	m.loading.SetContent(fmt.Sprintf(ConnectionFailed, seconds))
 }
 
+func certificateMismatchWarning(mismatch *gateway.CertificateMismatch) string {
+	warning := lipgloss.NewStyle().Bold(true).Foreground(colors.Error).
+		Render("WARNING: THE SERVER CERTIFICATE HAS CHANGED!")
+	return lipgloss.JoinVertical(lipgloss.Left,
+		warning,
+		"",
+		"Someone could be intercepting your connection to "+mismatch.Server+",",
+		"or the server operator may have replaced its certificate.",
+		"",
+		"Pinned SHA256:   "+mismatch.Expected,
+		"Received SHA256: "+mismatch.Actual,
+		"",
+		"If you trust the new certificate, remove "+mismatch.Server,
+		"from \"pinned_certificates\" in "+config.CacheFile,
+		"and restart eko.",
+	)
+}
+
 func newTimer(timeout time.Duration) timer.Model {
 	return timer.NewWithInterval(timeout.Truncate(time.Second)+(time.Second/2), TimerInterval)
 }
@@ -877,6 +1111,52 @@ func (m *Model) move(direction int) {
 	}
 }
 
+// openSignal selects the signal (adding it if needed),
+// optionally jumping to a message in it
+func (m *Model) openSignal(signal snowflake.ID, message *snowflake.ID) {
+	index := slices.Index(state.Data.Signals, signal)
+	if index == -1 {
+		state.Data.Signals = slices.Insert(state.Data.Signals, 0, signal)
+		index = 0
+	}
+	m.networkList.SetIndex(networklist.SignalsIndex)
+	m.signalList.SetIndex(index)
+	if message != nil {
+		m.chat.JumpToMessage(*message)
+	}
+
+	m.focus = FocusChat
+	m.move(0)
+}
+
+// openLink selects the network and frequency of the link,
+// the chat is switched to it afterwards like any other selection
+func (m *Model) openLink(link packet.Link) {
+	networkIndex := slices.Index(state.Data.Networks, link.Network)
+	if networkIndex == -1 {
+		log.Println("cannot open link to unknown network:", link.Network)
+		return
+	}
+	m.networkList.SetIndex(networkIndex)
+	m.frequencyList.SetNetworkIndex(networkIndex)
+
+	if link.Frequency != nil {
+		frequencies := state.State.Frequencies[link.Network]
+		frequencyIndex := slices.IndexFunc(frequencies, func(frequency data.Frequency) bool {
+			return frequency.ID == *link.Frequency
+		})
+		if frequencyIndex != -1 {
+			m.frequencyList.SetIndex(frequencyIndex)
+			if link.Message != nil {
+				m.chat.JumpToMessage(*link.Message)
+			}
+		}
+	}
+
+	m.focus = FocusChat
+	m.move(0)
+}
+
 func (m *Model) updatePopups(msg tea.Msg) tea.Cmd {
 	if m.helpPopup != nil {
 		popup, cmd := m.helpPopup.Update(msg)
@@ -922,6 +1202,34 @@ func (m *Model) updatePopups(msg tea.Msg) tea.Cmd {
 		popup, cmd := m.profilePopup.Update(msg)
 		m.profilePopup = &popup
 		return cmd
+	} else if m.pinsPopup != nil {
+		popup, cmd := m.pinsPopup.Update(msg)
+		m.pinsPopup = &popup
+		return cmd
+	} else if m.searchPopup != nil {
+		popup, cmd := m.searchPopup.Update(msg)
+		m.searchPopup = &popup
+		return cmd
+	} else if m.attachmentPathPopup != nil {
+		popup, cmd := m.attachmentPathPopup.Update(msg)
+		m.attachmentPathPopup = &popup
+		return cmd
+	} else if m.groupSignalPopup != nil {
+		popup, cmd := m.groupSignalPopup.Update(msg)
+		m.groupSignalPopup = &popup
+		return cmd
+	} else if m.keyRotationPopup != nil {
+		popup, cmd := m.keyRotationPopup.Update(msg)
+		m.keyRotationPopup = &popup
+		return cmd
+	} else if m.devicesPopup != nil {
+		popup, cmd := m.devicesPopup.Update(msg)
+		m.devicesPopup = &popup
+		return cmd
+	} else if m.sessionsPopup != nil {
+		popup, cmd := m.sessionsPopup.Update(msg)
+		m.sessionsPopup = &popup
+		return cmd
 	}
 	return nil
 }
@@ -937,7 +1245,47 @@ func (m *Model) HasPopup() bool {
 		m.banReasonPopup != nil ||
 		m.banViewPopup != nil ||
 		m.signalAddPopup != nil ||
-		m.profilePopup != nil
+		m.profilePopup != nil ||
+		m.pinsPopup != nil ||
+		m.searchPopup != nil ||
+		m.attachmentPathPopup != nil ||
+		m.groupSignalPopup != nil ||
+		m.keyRotationPopup != nil ||
+		m.devicesPopup != nil ||
+		m.sessionsPopup != nil
+}
+
+// applyKeyRotation switches to the pending private key
+// once the server confirms the user's public key was rotated to it
+func (m *Model) applyKeyRotation(info *packet.UsersInfo) {
+	if m.pendingKey == nil || state.UserID == nil {
+		return
+	}
+
+	signer, err := ssh.NewSignerFromKey(m.pendingKey.PrivateKey)
+	assert.NoError(err, "ed25519 keys are always valid signers")
+	publicKey := sshkey.FromSSH(signer.PublicKey())
+	for _, user := range info.Users {
+		if user.ID != *state.UserID || !bytes.Equal(user.PublicKey, publicKey) {
+			continue
+		}
+
+		m.signer = signer
+		m.privKey = m.pendingKey.PrivateKey
+		state.Signer = signer
+		state.PrivateKey = m.pendingKey.PrivateKey
+		if config.ReadConfig().PrivateKey() != "" {
+			err := config.UseConfig(func(config *config.Config) {
+				config.RememberPrivateKey(m.pendingKey.Path)
+			})
+			if err != nil {
+				log.Println("unable to remember rotated private key:", err)
+			}
+		}
+		log.Println("rotated private key, new key saved to:", m.pendingKey.Path)
+		m.pendingKey = nil
+		return
+	}
 }
 
 func calculateNotifications() {
@@ -985,16 +1333,14 @@ func getFrequencyNotification(networkId, frequencyId snowflake.ID) (_ int, _ boo
 	btree.AscendGreaterOrEqual(data.Message{ID: *lastReadMsg + 1}, func(item data.Message) bool {
 		hasNotif = true
 
-		if item.Ping == nil {
-			return true
-		}
-
-		if *item.Ping == packet.PingEveryone {
-			pings++
-		} else if *item.Ping == packet.PingAdmins && isAdmin {
-			pings++
-		} else if *item.Ping == *state.UserID {
-			pings++
+		for _, mention := range packet.ParseMentions(packet.WithLegacyPing(item.Content, item.Ping)) {
+			isPinged := mention == packet.PingEveryone ||
+				(mention == packet.PingAdmins && isAdmin) ||
+				mention == *state.UserID
+			if isPinged {
+				pings++
+				break
+			}
 		}
 
 		// No need to continue if we have 10 pings
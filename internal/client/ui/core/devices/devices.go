@@ -0,0 +1,422 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package devices
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/sshagent"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/field"
+	"github.com/kyren223/eko/internal/client/ui/layouts/flex"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/sshkey"
+)
+
+var (
+	width = 48
+
+	blurredButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White)
+	}
+	focusedButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White)
+	}
+)
+
+const (
+	ListField = iota
+	PrivateKeyField
+	PassphraseField
+	NameField
+	ButtonField
+	FieldCount
+)
+
+type Model struct {
+	privateKey  field.Model
+	passphrase  field.Model
+	name        field.Model
+	button      string
+	buttonStyle lipgloss.Style
+
+	index      int
+	selected   int
+	fieldWidth int
+}
+
+func New() Model {
+	privateKey := newField("Device Private Key")
+	privateKey.Input.Placeholder = "~/.ssh/id_ed25519 or " + sshagent.Prefix + ":<comment>"
+	privateKey.Input.CharLimit = 4096
+	privateKey.Input.Validate = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("cannot be empty")
+		}
+		return nil
+	}
+	privateKey.Blur()
+
+	passphrase := newField("Passphrase (Optional)")
+	passphrase.Input.Placeholder = "Passphrase"
+	passphrase.SetRevealed(false)
+	passphrase.Input.EchoCharacter = '*'
+	passphrase.Blur()
+
+	name := newField("Device Name")
+	name.Input.CharLimit = packet.MaxDeviceNameBytes
+	name.Input.Validate = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("cannot be empty")
+		}
+		return nil
+	}
+	name.Blur()
+
+	return Model{
+		privateKey:  privateKey,
+		passphrase:  passphrase,
+		name:        name,
+		button:      "Authorize Device",
+		buttonStyle: blurredButtonStyle(),
+		index:       0,
+		selected:    ListField,
+		fieldWidth:  lipgloss.Width(privateKey.View()),
+	}
+}
+
+func newField(header string) field.Model {
+	headerStyle := lipgloss.NewStyle().Foreground(colors.Turquoise)
+
+	blurredTextStyle := lipgloss.NewStyle().
+		Background(colors.Background).Foreground(colors.White)
+	focusedTextStyle := blurredTextStyle.Foreground(colors.Focus)
+
+	fieldBlurredStyle := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.DarkCyan).
+		BorderBackground(colors.Background).
+		Background(colors.Background)
+	fieldFocusedStyle := fieldBlurredStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.Focus)
+
+	f := field.New(width)
+	f.Header = header
+	f.HeaderStyle = headerStyle
+	f.FocusedStyle = fieldFocusedStyle
+	f.BlurredStyle = fieldBlurredStyle
+	f.FocusedTextStyle = focusedTextStyle
+	f.BlurredTextStyle = blurredTextStyle
+	f.ErrorStyle = lipgloss.NewStyle().Background(colors.Background).Foreground(colors.Error)
+	return f
+}
+
+// devices returns the user's own devices, oldest first
+func devices() []data.DeviceKey {
+	devices := []data.DeviceKey{}
+	for _, device := range state.State.Devices {
+		if device.UserID == *state.UserID {
+			devices = append(devices, device)
+		}
+	}
+	slices.SortFunc(devices, func(a, b data.DeviceKey) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return devices
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	header := lipgloss.NewStyle().
+		Width(m.fieldWidth).
+		Background(colors.Background).Foreground(colors.Turquoise).
+		Render(fmt.Sprintf("Devices (%v/%v)", len(devices()), packet.MaxDevices))
+
+	list := m.renderDevices()
+
+	button := lipgloss.NewStyle().
+		Width(m.fieldWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.buttonStyle.Render(m.button))
+
+	content := flex.NewVertical(
+		header, list, m.privateKey.View(), m.passphrase.View(), m.name.View(), button,
+	).WithGap(1).View()
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 4).
+		Align(lipgloss.Center, lipgloss.Center).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(content)
+}
+
+func (m Model) renderDevices() string {
+	style := lipgloss.NewStyle().Width(m.fieldWidth).
+		Background(colors.Background).Foreground(colors.White)
+
+	devices := devices()
+	if len(devices) == 0 {
+		return style.Foreground(colors.LightGray).
+			Render("No devices, enter a device's private key below to authorize it")
+	}
+
+	currentKey := state.PublicKey()
+
+	lines := make([]string, 0, len(devices)+1)
+	for i, device := range devices {
+		line := device.Name + " " + device.PublicKey.Fingerprint()
+		if bytes.Equal(device.PublicKey, currentKey) {
+			line += " (this device)"
+		}
+		lineStyle := style
+		if m.selected == ListField && i == m.index {
+			lineStyle = lineStyle.Background(colors.BackgroundHighlight).Foreground(colors.Focus)
+		}
+		lines = append(lines, lineStyle.Render(line))
+	}
+	if m.selected == ListField {
+		lines = append(lines, style.Foreground(colors.LightGray).
+			Render("enter to revoke, along with the devices it authorized"))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		key := msg.Type
+		switch key {
+		case tea.KeyTab:
+			return m, m.cycle(1)
+		case tea.KeyShiftTab:
+			return m, m.cycle(-1)
+
+		default:
+			var cmd tea.Cmd
+			switch m.selected {
+			case ListField:
+				switch msg.String() {
+				case "j", "down":
+					m.index = min(m.index+1, len(devices())-1)
+				case "k", "up":
+					m.index = max(m.index-1, 0)
+				}
+			case PrivateKeyField:
+				m.privateKey, cmd = m.privateKey.Update(msg)
+			case PassphraseField:
+				m.passphrase, cmd = m.passphrase.Update(msg)
+			case NameField:
+				m.name, cmd = m.name.Update(msg)
+			}
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) cycle(step int) tea.Cmd {
+	m.selected += step
+	if m.selected < 0 {
+		m.selected = FieldCount - 1
+	} else {
+		m.selected %= FieldCount
+	}
+	return m.updateFocus()
+}
+
+func (m *Model) updateFocus() tea.Cmd {
+	m.privateKey.Blur()
+	m.passphrase.Blur()
+	m.name.Blur()
+	m.buttonStyle = blurredButtonStyle()
+	switch m.selected {
+	case ListField:
+		return nil
+	case PrivateKeyField:
+		return m.privateKey.Focus()
+	case PassphraseField:
+		return m.passphrase.Focus()
+	case NameField:
+		return m.name.Focus()
+	case ButtonField:
+		m.buttonStyle = focusedButtonStyle()
+		return nil
+	default:
+		assert.Never("missing switch statement field in update focus", "selected", m.selected)
+		return nil
+	}
+}
+
+// Select revokes the highlighted device or authorizes a new one,
+// returns false if the popup should stay open
+func (m *Model) Select() (tea.Cmd, bool) {
+	if m.selected == ListField {
+		devices := devices()
+		if m.index < 0 || m.index >= len(devices) {
+			return nil, false
+		}
+		device := devices[m.index]
+		m.index = max(m.index-1, 0)
+		return gateway.Send(&packet.RevokeDevice{Device: device.ID}), false
+	}
+
+	if m.selected != ButtonField {
+		return nil, false
+	}
+
+	m.privateKey.Input.Err = m.privateKey.Input.Validate(m.privateKey.Input.Value())
+	m.name.Input.Err = m.name.Input.Validate(m.name.Input.Value())
+	m.passphrase.Input.Err = nil
+	if m.privateKey.Input.Err != nil || m.name.Input.Err != nil {
+		return nil, false
+	}
+
+	if state.Signer == nil || state.UserID == nil {
+		return nil, false
+	}
+
+	path := strings.TrimSpace(m.privateKey.Input.Value())
+	signer, err := deviceSigner(path, m.passphrase.Input.Value())
+	if errors.Is(err, errPassphraseMissing) || errors.Is(err, errPassphraseIncorrect) {
+		m.passphrase.Input.Err = err
+		return nil, false
+	}
+	if err != nil {
+		m.privateKey.Input.Err = err
+		return nil, false
+	}
+
+	pubKey := sshkey.FromSSH(signer.PublicKey())
+	payload := packet.DeviceSignaturePayload(*state.UserID, pubKey)
+	signature := state.Sign(payload)
+	if signature == nil {
+		m.privateKey.Input.Err = errors.New("failed signing with the current key")
+		return nil, false
+	}
+	newSignature, err := sshkey.Sign(signer, payload)
+	if err != nil {
+		m.privateKey.Input.Err = errors.New("failed signing with the device key")
+		log.Println("device key sign error:", err)
+		return nil, false
+	}
+
+	return gateway.Send(&packet.AuthorizeDevice{
+		PubKey:       pubKey,
+		Name:         strings.TrimSpace(m.name.Input.Value()),
+		Signature:    signature,
+		NewSignature: newSignature,
+	}), true
+}
+
+var (
+	errPassphraseMissing   = errors.New("missing passphrase")
+	errPassphraseIncorrect = errors.New("incorrect passphrase")
+)
+
+// deviceSigner returns a signer for the device's private key, which is
+// either a file or an identity held by ssh-agent. The signer is only used
+// to prove possession of the key, the private key is never stored.
+func deviceSigner(path, passphrase string) (ssh.Signer, error) {
+	if sshagent.IsAgentPath(path) {
+		identity, err := sshagent.Find(path)
+		if err != nil {
+			return nil, err
+		}
+		return sshagent.NewSigner(identity)
+	}
+
+	file, err := os.ReadFile(expandHome(path)) // #nosec 304
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("file doesn't exist")
+	}
+	if err != nil {
+		log.Println("device private key read error:", err)
+		return nil, errors.New("failed reading file")
+	}
+
+	var privateKey any
+	if passphrase == "" {
+		privateKey, err = ssh.ParseRawPrivateKey(file)
+	} else {
+		privateKey, err = ssh.ParseRawPrivateKeyWithPassphrase(file, []byte(passphrase))
+		if err != nil && (err.Error() == "ssh: not an encrypted key" || err.Error() == "ssh: key is not password protected") {
+			privateKey, err = ssh.ParseRawPrivateKey(file)
+		}
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, errPassphraseMissing
+	}
+	if err == x509.IncorrectPasswordError {
+		return nil, errPassphraseIncorrect
+	}
+	if err != nil {
+		log.Println("device private key parse error:", err)
+		return nil, errors.New("invalid private key file format")
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err == nil {
+		err = sshkey.FromSSH(signer.PublicKey()).Validate()
+	}
+	if err != nil {
+		return nil, errors.New("must be ed25519, ecdsa or rsa (2048+ bits)")
+	}
+	return signer, nil
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator))
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
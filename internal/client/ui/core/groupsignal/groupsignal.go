@@ -0,0 +1,291 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsignal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/field"
+	"github.com/kyren223/eko/internal/client/ui/layouts/flex"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+var (
+	width = 48
+
+	blurredButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White)
+	}
+	focusedButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White)
+	}
+)
+
+type Model struct {
+	// Name is only used when creating a group
+	name        *field.Model
+	users       field.Model
+	button      string
+	buttonStyle lipgloss.Style
+
+	// Add a member to an existing group, or create a new one
+	group *snowflake.ID
+
+	selected   int
+	fieldWidth int
+}
+
+func NewCreate() Model {
+	name := newField("Group Name")
+	name.Input.CharLimit = packet.MaxGroupSignalNameBytes
+	name.Input.Validate = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("cannot be empty")
+		}
+		return nil
+	}
+	name.Focus()
+
+	users := newField("User IDs (space separated)")
+	users.Input.CharLimit = 20 * packet.MaxGroupSignalMembers
+	users.Input.Validate = func(s string) error {
+		ids, err := parseUserIds(s)
+		if err != nil {
+			return err
+		}
+		if len(ids)+1 < packet.MinGroupSignalMembers {
+			return fmt.Errorf("at least %v other users are required", packet.MinGroupSignalMembers-1)
+		}
+		if len(ids)+1 > packet.MaxGroupSignalMembers {
+			return fmt.Errorf("at most %v other users are allowed", packet.MaxGroupSignalMembers-1)
+		}
+		return nil
+	}
+	users.Blur()
+
+	return Model{
+		name:        &name,
+		users:       users,
+		button:      "Create group signal",
+		buttonStyle: blurredButtonStyle(),
+		selected:    0,
+		fieldWidth:  lipgloss.Width(users.View()),
+	}
+}
+
+func NewAddMember(group snowflake.ID) Model {
+	users := newField("User ID")
+	users.Input.CharLimit = width
+	users.Input.Validate = func(s string) error {
+		ids, err := parseUserIds(s)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return errors.New("exactly one user id is required")
+		}
+		return nil
+	}
+	users.Focus()
+
+	return Model{
+		name:        nil,
+		users:       users,
+		button:      "Add member",
+		buttonStyle: blurredButtonStyle(),
+		group:       &group,
+		selected:    0,
+		fieldWidth:  lipgloss.Width(users.View()),
+	}
+}
+
+func newField(header string) field.Model {
+	headerStyle := lipgloss.NewStyle().Foreground(colors.Turquoise)
+
+	blurredTextStyle := lipgloss.NewStyle().
+		Background(colors.Background).Foreground(colors.White)
+	focusedTextStyle := blurredTextStyle.Foreground(colors.Focus)
+
+	fieldBlurredStyle := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.DarkCyan).
+		BorderBackground(colors.Background).
+		Background(colors.Background)
+	fieldFocusedStyle := fieldBlurredStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.Focus)
+
+	f := field.New(width)
+	f.Header = header
+	f.HeaderStyle = headerStyle
+	f.FocusedStyle = fieldFocusedStyle
+	f.BlurredStyle = fieldBlurredStyle
+	f.FocusedTextStyle = focusedTextStyle
+	f.BlurredTextStyle = blurredTextStyle
+	f.ErrorStyle = lipgloss.NewStyle().Background(colors.Background).Foreground(colors.Error)
+	return f
+}
+
+func parseUserIds(s string) ([]snowflake.ID, error) {
+	ids := []snowflake.ID{}
+	for _, word := range strings.Fields(s) {
+		num, err := strconv.ParseInt(word, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid user id")
+		}
+		ids = append(ids, snowflake.ID(num))
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("cannot be empty")
+	}
+	return ids, nil
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	button := lipgloss.NewStyle().
+		Width(m.fieldWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.buttonStyle.Render(m.button))
+
+	var content string
+	if m.name != nil {
+		content = flex.NewVertical(m.name.View(), m.users.View(), button).WithGap(1).View()
+	} else {
+		content = flex.NewVertical(m.users.View(), button).WithGap(1).View()
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 4).
+		Align(lipgloss.Center, lipgloss.Center).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(content)
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		key := msg.Type
+		switch key {
+		case tea.KeyTab:
+			return m, m.cycle(1)
+		case tea.KeyShiftTab:
+			return m, m.cycle(-1)
+
+		default:
+			var cmd tea.Cmd
+			if m.name != nil && m.selected == 0 {
+				*m.name, cmd = m.name.Update(msg)
+			} else if !m.isButtonSelected() {
+				m.users, cmd = m.users.Update(msg)
+			}
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+func (m Model) fieldCount() int {
+	if m.name != nil {
+		return 3
+	}
+	return 2
+}
+
+func (m Model) isButtonSelected() bool {
+	return m.selected == m.fieldCount()-1
+}
+
+func (m *Model) cycle(step int) tea.Cmd {
+	m.selected += step
+	if m.selected < 0 {
+		m.selected = m.fieldCount() - 1
+	} else {
+		m.selected %= m.fieldCount()
+	}
+	return m.updateFocus()
+}
+
+func (m *Model) updateFocus() tea.Cmd {
+	if m.name != nil {
+		m.name.Blur()
+	}
+	m.users.Blur()
+	m.buttonStyle = blurredButtonStyle()
+
+	switch {
+	case m.isButtonSelected():
+		m.buttonStyle = focusedButtonStyle()
+		return nil
+	case m.name != nil && m.selected == 0:
+		return m.name.Focus()
+	case m.selected < m.fieldCount():
+		return m.users.Focus()
+	default:
+		assert.Never("missing switch statement field in update focus", "selected", m.selected)
+		return nil
+	}
+}
+
+// Select sends the request, returns false if any of the fields is invalid
+func (m *Model) Select() (tea.Cmd, bool) {
+	if !m.isButtonSelected() {
+		return nil, false
+	}
+
+	if m.name != nil {
+		m.name.Input.Err = m.name.Input.Validate(m.name.Input.Value())
+	}
+	m.users.Input.Err = m.users.Input.Validate(m.users.Input.Value())
+	if m.users.Input.Err != nil || (m.name != nil && m.name.Input.Err != nil) {
+		return nil, false
+	}
+	users, err := parseUserIds(m.users.Input.Value())
+	assert.NoError(err, "input is already validated to be valid")
+
+	if m.group != nil {
+		return gateway.Send(&packet.AddGroupSignalMember{
+			Group: *m.group,
+			User:  users[0],
+		}), true
+	}
+	return gateway.Send(&packet.CreateGroupSignal{
+		Name:  strings.TrimSpace(m.name.Input.Value()),
+		Users: users,
+	}), true
+}
@@ -245,8 +245,12 @@ func (m HelpPopup) HelpSignalList() [][]Keymap {
 		{"G", "Move to the bottom"},
 	}, {
 		{"a", "Add new user signal"},
+		{"n", "Create new group signal"},
 		{"c", "Close user signal"},
-		{"T", "Trust/untrust user"},
+		{"m", "Add member to group signal"},
+		{"x", "Leave group signal/ignore request"},
+		{"T", "Trust/untrust user (accepts request)"},
+		{"E", "Toggle end-to-end encryption"},
 		{"B", "Block user"},
 		{"U", "Unblock user"},
 		{"i", "Copy your user ID"},
@@ -266,6 +270,13 @@ func (m HelpPopup) HelpChat() [][]Keymap {
 
 		{"x", "Delete selected message"},
 		{"e", "Edit selected message"},
+		{"m", "Pin/unpin selected message"},
+		{"'", "View pinned messages"},
+		{"y", "Copy link to selected message"},
+		{"o", "Open link in selected message"},
+		{"/", "Search messages"},
+		{"a", "Upload and send a file"},
+		{"S", "Save attachment of selected message"},
 	}, {
 		{"K", "Kick message sender"},
 		{"M", "Mute message sender"},
@@ -309,7 +320,12 @@ func (m HelpPopup) HelpVim() [][]Keymap {
 		{"q", "Exit typing from normal mode"},
 		{"ctrl+q", "Exit typing from insert mode"},
 		{"other", "The rest of the vim keys work as usual"},
-	}, {}}
+	}, {
+		{"@", "Mention a member, everyone or admins"},
+		{"tab", "Cycle to the next mention completion"},
+		{"shift+tab", "Cycle to the previous mention completion"},
+		{"enter", "Accept the selected mention completion"},
+	}}
 }
 
 func (m HelpPopup) HelpBanList() [][]Keymap {
@@ -0,0 +1,328 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package keyrotation
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/field"
+	"github.com/kyren223/eko/internal/client/ui/layouts/flex"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/sshkey"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	width = 48
+
+	blurredButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White)
+	}
+	focusedButtonStyle = func() lipgloss.Style {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White)
+	}
+)
+
+const (
+	PathField = iota
+	PassphraseField
+	ConfirmField
+	ButtonField
+	FieldCount
+)
+
+// PendingMsg is sent before the rotation request, the new key
+// should be used once the server confirms the rotation
+type PendingMsg struct {
+	PrivateKey ed25519.PrivateKey
+	Path       string
+}
+
+type Model struct {
+	path        field.Model
+	passphrase  field.Model
+	confirm     field.Model
+	button      string
+	buttonStyle lipgloss.Style
+
+	selected   int
+	fieldWidth int
+}
+
+func New() Model {
+	path := newField("New Private Key")
+	path.Input.Placeholder = "Path to New Private Key"
+	path.Input.CharLimit = 4096
+	path.Input.Validate = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("cannot be empty")
+		}
+		return nil
+	}
+	path.Focus()
+
+	passphrase := newField("Passphrase (Optional)")
+	passphrase.Input.Placeholder = "Passphrase"
+	passphrase.SetRevealed(false)
+	passphrase.Input.EchoCharacter = '*'
+	passphrase.Blur()
+
+	confirm := newField("Passphrase Confirm")
+	confirm.Input.Placeholder = "Repeated Passphrase"
+	confirm.SetRevealed(false)
+	confirm.Input.EchoCharacter = '*'
+	confirm.Blur()
+
+	return Model{
+		path:        path,
+		passphrase:  passphrase,
+		confirm:     confirm,
+		button:      "Rotate Private Key",
+		buttonStyle: blurredButtonStyle(),
+		selected:    PathField,
+		fieldWidth:  lipgloss.Width(path.View()),
+	}
+}
+
+func newField(header string) field.Model {
+	headerStyle := lipgloss.NewStyle().Foreground(colors.Turquoise)
+
+	blurredTextStyle := lipgloss.NewStyle().
+		Background(colors.Background).Foreground(colors.White)
+	focusedTextStyle := blurredTextStyle.Foreground(colors.Focus)
+
+	fieldBlurredStyle := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.DarkCyan).
+		BorderBackground(colors.Background).
+		Background(colors.Background)
+	fieldFocusedStyle := fieldBlurredStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.Focus)
+
+	f := field.New(width)
+	f.Header = header
+	f.HeaderStyle = headerStyle
+	f.FocusedStyle = fieldFocusedStyle
+	f.BlurredStyle = fieldBlurredStyle
+	f.FocusedTextStyle = focusedTextStyle
+	f.BlurredTextStyle = blurredTextStyle
+	f.ErrorStyle = lipgloss.NewStyle().Background(colors.Background).Foreground(colors.Error)
+	return f
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	warning := lipgloss.NewStyle().
+		Width(m.fieldWidth).
+		Background(colors.Background).Foreground(colors.Gold).
+		Render("The current key signs the new one, users who trust you will be notified. " +
+			"Encrypted messages from before the rotation won't be decryptable.")
+
+	button := lipgloss.NewStyle().
+		Width(m.fieldWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.buttonStyle.Render(m.button))
+
+	content := flex.NewVertical(
+		warning, m.path.View(), m.passphrase.View(), m.confirm.View(), button,
+	).WithGap(1).View()
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 4).
+		Align(lipgloss.Center, lipgloss.Center).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(content)
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		key := msg.Type
+		switch key {
+		case tea.KeyTab:
+			return m, m.cycle(1)
+		case tea.KeyShiftTab:
+			return m, m.cycle(-1)
+
+		default:
+			var cmd tea.Cmd
+			switch m.selected {
+			case PathField:
+				m.path, cmd = m.path.Update(msg)
+			case PassphraseField:
+				m.passphrase, cmd = m.passphrase.Update(msg)
+			case ConfirmField:
+				m.confirm, cmd = m.confirm.Update(msg)
+			}
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) cycle(step int) tea.Cmd {
+	m.selected += step
+	if m.selected < 0 {
+		m.selected = FieldCount - 1
+	} else {
+		m.selected %= FieldCount
+	}
+	return m.updateFocus()
+}
+
+func (m *Model) updateFocus() tea.Cmd {
+	m.path.Blur()
+	m.passphrase.Blur()
+	m.confirm.Blur()
+	m.buttonStyle = blurredButtonStyle()
+	switch m.selected {
+	case PathField:
+		return m.path.Focus()
+	case PassphraseField:
+		return m.passphrase.Focus()
+	case ConfirmField:
+		return m.confirm.Focus()
+	case ButtonField:
+		m.buttonStyle = focusedButtonStyle()
+		return nil
+	default:
+		assert.Never("missing switch statement field in update focus", "selected", m.selected)
+		return nil
+	}
+}
+
+// Select writes the new private key to disk and requests the rotation,
+// returns false if any of the fields is invalid
+func (m *Model) Select() (tea.Cmd, bool) {
+	if m.selected != ButtonField {
+		return nil, false
+	}
+
+	m.path.Input.Err = m.path.Input.Validate(m.path.Input.Value())
+	if m.path.Input.Err != nil {
+		return nil, false
+	}
+
+	passphrase := m.passphrase.Input.Value()
+	if passphrase != m.confirm.Input.Value() {
+		m.confirm.Input.Err = errors.New("passphrase mismatch")
+		return nil, false
+	}
+	m.confirm.Input.Err = nil
+
+	if state.Signer == nil || state.UserID == nil {
+		return nil, false
+	}
+
+	_, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		m.path.Input.Err = errors.New("failed private key generation")
+		log.Println("ed25519 generate key error:", err)
+		return nil, false
+	}
+
+	pubKey := sshkey.FromEd25519(privKey.Public().(ed25519.PublicKey))
+	oldPubKey := state.PublicKey()
+	payload := packet.KeyRotationSignaturePayload(*state.UserID, oldPubKey, pubKey)
+	signature := state.Sign(payload)
+	if signature == nil {
+		m.path.Input.Err = errors.New("failed signing with the current key")
+		return nil, false
+	}
+
+	path := expandHome(strings.TrimSpace(m.path.Input.Value()))
+	err = writePrivateKey(path, privKey, passphrase)
+	if errors.Is(err, os.ErrExist) {
+		m.path.Input.Err = errors.New("file already exists")
+		return nil, false
+	}
+	if err != nil {
+		m.path.Input.Err = errors.New("failed writing to disk")
+		log.Println("private key write error:", err)
+		return nil, false
+	}
+
+	pending := PendingMsg{PrivateKey: privKey, Path: path}
+	return tea.Sequence(func() tea.Msg {
+		return pending
+	}, gateway.Send(&packet.RotateKey{
+		PubKey:       pubKey,
+		Signature:    signature,
+		NewSignature: ed25519.Sign(privKey, payload),
+	})), true
+}
+
+func writePrivateKey(path string, privKey ed25519.PrivateKey, passphrase string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec 304
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	comment := state.State.Users[*state.UserID].Name
+	var pemBlock *pem.Block
+	if passphrase != "" {
+		pemBlock, err = ssh.MarshalPrivateKeyWithPassphrase(privKey, comment, []byte(passphrase))
+	} else {
+		pemBlock, err = ssh.MarshalPrivateKey(privKey, comment)
+	}
+	if err != nil {
+		return err
+	}
+	return pem.Encode(file, pemBlock)
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator))
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
@@ -112,7 +112,8 @@ func (m Model) View() string {
 
 		user := state.State.Users[member.UserID]
 		trustedPublicKey, isTrusted := state.State.TrustedUsers[user.ID]
-		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey)
+		isRotated := state.KeyRotated(user.ID)
+		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey) || isRotated
 
 		var userStyle lipgloss.Style
 		if isTrusted && keysMatch {
@@ -134,9 +135,12 @@ func (m Model) View() string {
 		}
 		memberName := m.Users()[member.UserID].Name
 		memberName = userStyle.Render(memberName)
-		if isTrusted && !keysMatch {
+		if isRotated {
+			memberName = ui.KeyRotatedSymbol() + memberName
+		} else if isTrusted && !keysMatch {
 			memberName = ui.UntrustedSymbol() + memberName
 		}
+		memberName = ui.PresenceSymbol(state.Presence(member.UserID).State) + memberName
 
 		if lipgloss.Width(memberName) <= maxMemberWidth {
 			memberName = lipgloss.NewStyle().
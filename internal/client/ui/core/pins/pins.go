@@ -0,0 +1,182 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pins
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/ui"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+var (
+	width  = 60
+	height = 6
+
+	ellipsis = "…"
+
+	NoPins = "There are no pinned messages here yet"
+)
+
+type Model struct {
+	source snowflake.ID
+	base   int
+	index  int
+}
+
+func New(source snowflake.ID) Model {
+	return Model{
+		source: source,
+		base:   0,
+		index:  0,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	headerStyle := lipgloss.NewStyle().
+		Width(width).
+		Background(colors.Background).
+		Foreground(colors.Focus).
+		Border(lipgloss.ThickBorder(), false, false, true).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Align(lipgloss.Center)
+	pinStyle := lipgloss.NewStyle().
+		Width(width).
+		Padding(0, 1).
+		Background(colors.Background).
+		Foreground(colors.White)
+	grayStyle := lipgloss.NewStyle().
+		Background(colors.Background).
+		Foreground(colors.LightGray)
+
+	var builder strings.Builder
+	builder.WriteString(headerStyle.Render("Pinned Messages"))
+	builder.WriteString("\n")
+
+	pins := m.Pins()
+	if len(pins) == 0 {
+		builder.WriteString(grayStyle.Width(width).Align(lipgloss.Center).Render(NoPins))
+	}
+
+	upper := min(m.base+height, len(pins))
+	for i, pin := range pins[m.base:upper] {
+		pinStyle := pinStyle
+		grayStyle := grayStyle
+		if m.index == m.base+i {
+			pinStyle = pinStyle.Background(colors.BackgroundHighlight)
+			grayStyle = grayStyle.Background(colors.BackgroundHighlight)
+		}
+
+		name := state.State.Users[pin.SenderID].Name
+		sentAt := time.UnixMilli(pin.ID.Time()).Format(" 01/02/2006 15:04")
+		header := ui.UserStyle().Background(pinStyle.GetBackground()).Render(name) +
+			grayStyle.Render(sentAt)
+
+		content, _, _ := strings.Cut(pin.Content, "\n")
+		maxContentWidth := width - pinStyle.GetHorizontalPadding()
+		if lipgloss.Width(content) > maxContentWidth {
+			content = lipgloss.NewStyle().MaxWidth(maxContentWidth-1).Render(content) + ellipsis
+		}
+
+		builder.WriteString("\n")
+		builder.WriteString(pinStyle.Render(header))
+		builder.WriteString("\n")
+		builder.WriteString(pinStyle.Render(content))
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 2).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(builder.String())
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "k":
+			m.SetIndex(m.index - 1)
+		case "j":
+			m.SetIndex(m.index + 1)
+		case "g":
+			m.SetIndex(0)
+		case "G":
+			m.SetIndex(len(m.Pins()) - 1)
+		}
+	}
+
+	return m, nil
+}
+
+// Pins returns the pinned messages of the source, most recently pinned first
+func (m *Model) Pins() []data.Message {
+	btree := state.State.Messages[m.source]
+	if btree == nil {
+		return nil
+	}
+
+	pins := []data.Message{}
+	btree.Ascend(func(message data.Message) bool {
+		if message.PinnedAt != nil {
+			pins = append(pins, message)
+		}
+		return true
+	})
+	slices.SortFunc(pins, func(a, b data.Message) int {
+		return cmp.Compare(*b.PinnedAt, *a.PinnedAt)
+	})
+
+	return pins
+}
+
+func (m *Model) SetIndex(index int) {
+	m.index = max(min(index, len(m.Pins())-1), 0)
+	if m.index < m.base {
+		m.base = m.index
+	} else if m.index >= m.base+height {
+		m.base = 1 + m.index - height
+	}
+}
+
+func (m *Model) Select() tea.Cmd {
+	pins := m.Pins()
+	if len(pins) == 0 {
+		return nil
+	}
+
+	message := pins[m.index].ID
+	return func() tea.Msg {
+		return ui.JumpToMessageMsg{Message: message}
+	}
+}
@@ -17,17 +17,16 @@
 package profile
 
 import (
-	"crypto/ed25519"
 	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/ui"
 	"github.com/kyren223/eko/internal/client/ui/colors"
 	"github.com/kyren223/eko/internal/client/ui/core/state"
-	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/snowflake"
-	"golang.org/x/crypto/ssh"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 var width = 70
@@ -79,6 +78,24 @@ func (m Model) View() string {
 	builder.WriteByte('\n')
 	builder.WriteByte('\n')
 
+	presence := state.Presence(user.ID)
+	presenceText := ui.PresenceName(presence.State)
+	if presence.Status != "" {
+		presenceText += " | " + presence.Status
+	}
+	presenceText = lipgloss.NewStyle().
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(" " + presenceText)
+	presenceLine := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Width(width).
+		Background(colors.Background).
+		Render(ui.PresenceSymbol(presence.State) + presenceText)
+	builder.WriteString(presenceLine)
+	builder.WriteByte('\n')
+	builder.WriteByte('\n')
+
 	publicDMs := "Private DMs"
 	if user.IsPublicDM {
 		publicDMs = "Public DMs"
@@ -95,7 +112,7 @@ func (m Model) View() string {
 	builder.WriteByte('\n')
 	builder.WriteByte('\n')
 
-	publicKeyHeader := headerStyle.Render("Public Key (ssh-ed25519)")
+	publicKeyHeader := headerStyle.Render("Public Key (" + user.PublicKey.Type() + ")")
 	publicKey := lipgloss.NewStyle().
 		PaddingLeft(2).
 		Width(width).
@@ -106,6 +123,17 @@ func (m Model) View() string {
 	builder.WriteString(publicKey)
 	builder.WriteByte('\n')
 
+	fingerprintHeader := headerStyle.Render("Fingerprint")
+	fingerprint := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Width(width).
+		Render(user.PublicKey.Fingerprint())
+	builder.WriteString(fingerprintHeader)
+	builder.WriteByte('\n')
+	builder.WriteByte('\n')
+	builder.WriteString(fingerprint)
+	builder.WriteByte('\n')
+
 	aboutMeHeader := headerStyle.Render("About me")
 	builder.WriteString(aboutMeHeader)
 	builder.WriteByte('\n')
@@ -152,9 +180,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	return m, nil
 }
 
-func publicKeyToSshString(pub ed25519.PublicKey) string {
-	sshPubKey, err := ssh.NewPublicKey(pub)
-	assert.NoError(err, "converting to ssh key should never fail")
-	sshPubKeyBytes := ssh.MarshalAuthorizedKey(sshPubKey)
-	return strings.Split(string(sshPubKeyBytes), " ")[1]
+func publicKeyToSshString(pub sshkey.PublicKey) string {
+	fields := strings.Fields(pub.String())
+	return fields[len(fields)-1]
 }
@@ -0,0 +1,463 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/ui"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/client/ui/field"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+var (
+	width  = 72
+	height = 5
+
+	ellipsis = "…"
+
+	// Characters of context to show before the first match
+	contextBefore = 20
+
+	DateLayout = "2006-01-02"
+
+	NoResults = "No messages found"
+	Searching = "Searching..."
+	Hint      = "Filters: from:<user> in:here|network has:mention before:/after:YYYY-MM-DD"
+)
+
+const (
+	FilterFrom   = "from:"
+	FilterIn     = "in:"
+	FilterHas    = "has:"
+	FilterBefore = "before:"
+	FilterAfter  = "after:"
+	InHere       = "here"
+	InNetwork    = "network"
+	HasMention   = "mention"
+)
+
+const (
+	QueryField = iota
+	ResultsField
+)
+
+type Model struct {
+	query field.Model
+
+	// Where the search was opened from, used by in:here and in:network
+	network   *snowflake.ID
+	frequency *snowflake.ID
+	receiver  *snowflake.ID
+
+	searching bool
+	pending   string // The query that is being searched
+	terms     []string
+	results   []data.Message
+	selected  int
+	base      int
+	index     int
+}
+
+func New(network, frequency, receiver *snowflake.ID) Model {
+	headerStyle := lipgloss.NewStyle().Foreground(colors.Turquoise)
+
+	blurredTextStyle := lipgloss.NewStyle().
+		Background(colors.Background).Foreground(colors.White)
+	focusedTextStyle := blurredTextStyle.Foreground(colors.Focus)
+
+	fieldBlurredStyle := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.DarkCyan).
+		BorderBackground(colors.Background).
+		Background(colors.Background)
+	fieldFocusedStyle := fieldBlurredStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.Focus)
+
+	query := field.New(width - 3)
+	query.Header = "Search Messages"
+	query.HeaderStyle = headerStyle
+	query.FocusedStyle = fieldFocusedStyle
+	query.BlurredStyle = fieldBlurredStyle
+	query.FocusedTextStyle = focusedTextStyle
+	query.BlurredTextStyle = blurredTextStyle
+	query.ErrorStyle = lipgloss.NewStyle().Background(colors.Background).Foreground(colors.Error)
+	query.Input.CharLimit = packet.MaxSearchQueryBytes
+	query.Focus()
+
+	return Model{
+		query:     query,
+		network:   network,
+		frequency: frequency,
+		receiver:  receiver,
+		selected:  QueryField,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) View() string {
+	resultStyle := lipgloss.NewStyle().
+		Width(width).
+		Padding(0, 1).
+		Background(colors.Background).
+		Foreground(colors.White)
+	grayStyle := lipgloss.NewStyle().
+		Background(colors.Background).
+		Foreground(colors.LightGray)
+
+	var builder strings.Builder
+	builder.WriteString(m.query.View())
+	builder.WriteString("\n")
+	builder.WriteString(grayStyle.Width(width).Render(Hint))
+	builder.WriteString("\n")
+
+	if m.searching {
+		builder.WriteString("\n")
+		builder.WriteString(grayStyle.Width(width).Align(lipgloss.Center).Render(Searching))
+	} else if m.terms != nil && len(m.results) == 0 {
+		builder.WriteString("\n")
+		builder.WriteString(grayStyle.Width(width).Align(lipgloss.Center).Render(NoResults))
+	}
+
+	upper := min(m.base+height, len(m.results))
+	for i, result := range m.results[m.base:upper] {
+		resultStyle := resultStyle
+		grayStyle := grayStyle
+		if m.selected == ResultsField && m.index == m.base+i {
+			resultStyle = resultStyle.Background(colors.BackgroundHighlight)
+			grayStyle = grayStyle.Background(colors.BackgroundHighlight)
+		}
+		background := resultStyle.GetBackground()
+
+		name := state.State.Users[result.SenderID].Name
+		sentAt := time.UnixMilli(result.ID.Time()).Format(" 01/02/2006 15:04")
+		header := ui.UserStyle().Background(background).Render(name) +
+			grayStyle.Render(sentAt+" in "+location(result))
+
+		maxContentWidth := width - resultStyle.GetHorizontalPadding()
+		textStyle := lipgloss.NewStyle().Background(background).Foreground(colors.White)
+		content := snippet(result.Content, m.terms, maxContentWidth, textStyle)
+
+		builder.WriteString("\n")
+		builder.WriteString(resultStyle.Render(header))
+		builder.WriteString("\n")
+		builder.WriteString(resultStyle.Render(content))
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 2).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(builder.String())
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyTab || msg.Type == tea.KeyShiftTab {
+			return m, m.cycle()
+		}
+
+		if m.selected == QueryField {
+			var cmd tea.Cmd
+			m.query, cmd = m.query.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "k":
+			m.SetIndex(m.index - 1)
+		case "j":
+			m.SetIndex(m.index + 1)
+		case "g":
+			m.SetIndex(0)
+		case "G":
+			m.SetIndex(len(m.results) - 1)
+		case "i", "/":
+			return m, m.cycle()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) cycle() tea.Cmd {
+	if m.selected == QueryField && len(m.results) != 0 {
+		m.selected = ResultsField
+		m.query.Blur()
+		return nil
+	}
+	m.selected = QueryField
+	return m.query.Focus()
+}
+
+// SetResults shows the results, unless they are for a different query
+func (m *Model) SetResults(results *packet.SearchResults) {
+	if !m.searching || results.Query != m.pending {
+		return
+	}
+
+	m.searching = false
+	m.results = results.Messages
+	m.base = 0
+	m.index = 0
+	if len(m.results) != 0 {
+		m.selected = ResultsField
+		m.query.Blur()
+	}
+}
+
+func (m *Model) SetIndex(index int) {
+	m.index = max(min(index, len(m.results)-1), 0)
+	if m.index < m.base {
+		m.base = m.index
+	} else if m.index >= m.base+height {
+		m.base = 1 + m.index - height
+	}
+}
+
+// Select searches if the query is selected, returning false.
+// Otherwise it opens the selected message, returning true.
+func (m *Model) Select() (tea.Cmd, bool) {
+	if m.selected == QueryField {
+		request, terms, err := m.parseQuery(m.query.Input.Value())
+		m.query.Input.Err = err
+		if err != nil {
+			return nil, false
+		}
+
+		m.searching = true
+		m.pending = request.Query
+		m.terms = terms
+		m.results = nil
+		return gateway.Send(request), false
+	}
+
+	if len(m.results) == 0 {
+		return nil, false
+	}
+
+	message := m.results[m.index]
+	if message.FrequencyID != nil {
+		network := state.FrequencyNetwork(*message.FrequencyID)
+		if network == nil {
+			return nil, true
+		}
+		link := packet.Link{
+			Network:   *network,
+			Frequency: message.FrequencyID,
+			Message:   &message.ID,
+		}
+		return func() tea.Msg {
+			return ui.OpenLinkMsg{Link: link}
+		}, true
+	}
+
+	signal := message.SenderID
+	if message.GroupID != nil {
+		signal = *message.GroupID
+	} else if signal == *state.UserID && message.ReceiverID != nil {
+		signal = *message.ReceiverID
+	}
+	return func() tea.Msg {
+		return ui.OpenSignalMsg{Signal: signal, Message: &message.ID}
+	}, true
+}
+
+// parseQuery extracts the filters from the query, returning
+// the request and the search terms without the filters
+func (m *Model) parseQuery(query string) (*packet.SearchMessages, []string, error) {
+	request := &packet.SearchMessages{}
+	terms := []string{}
+
+	for _, word := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(word, FilterFrom):
+			user, err := findUser(strings.TrimPrefix(word, FilterFrom))
+			if err != nil {
+				return nil, nil, err
+			}
+			request.SenderID = &user
+
+		case strings.HasPrefix(word, FilterIn):
+			switch value := strings.TrimPrefix(word, FilterIn); value {
+			case InHere:
+				request.FrequencyID = m.frequency
+				request.ReceiverID = m.receiver
+				if m.frequency == nil && m.receiver == nil {
+					return nil, nil, errors.New("nothing is selected")
+				}
+			case InNetwork:
+				if m.network == nil {
+					return nil, nil, errors.New("not in a network")
+				}
+				request.NetworkID = m.network
+			default:
+				return nil, nil, fmt.Errorf("unknown %v%v", FilterIn, value)
+			}
+
+		case strings.HasPrefix(word, FilterHas):
+			if value := strings.TrimPrefix(word, FilterHas); value != HasMention {
+				return nil, nil, fmt.Errorf("unknown %v%v", FilterHas, value)
+			}
+			request.HasMention = true
+
+		case strings.HasPrefix(word, FilterBefore):
+			date, err := time.ParseInLocation(DateLayout, strings.TrimPrefix(word, FilterBefore), time.Local)
+			if err != nil {
+				return nil, nil, errors.New("dates must be YYYY-MM-DD")
+			}
+			before := date.UnixMilli()
+			request.Before = &before
+
+		case strings.HasPrefix(word, FilterAfter):
+			date, err := time.ParseInLocation(DateLayout, strings.TrimPrefix(word, FilterAfter), time.Local)
+			if err != nil {
+				return nil, nil, errors.New("dates must be YYYY-MM-DD")
+			}
+			after := date.UnixMilli()
+			request.After = &after
+
+		default:
+			terms = append(terms, word)
+		}
+	}
+
+	if len(terms) == 0 {
+		return nil, nil, errors.New("nothing to search for")
+	}
+
+	request.Query = strings.Join(terms, " ")
+	return request, terms, nil
+}
+
+// findUser finds a user by id, mention or (case insensitive) name
+func findUser(value string) (snowflake.ID, error) {
+	value = strings.TrimPrefix(value, packet.MentionPrefix)
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return snowflake.ID(id), nil
+	}
+	for id, user := range state.State.Users {
+		if strings.EqualFold(user.Name, value) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown user %v", value)
+}
+
+func location(message data.Message) string {
+	if message.FrequencyID == nil {
+		return "signal"
+	}
+
+	network := state.FrequencyNetwork(*message.FrequencyID)
+	if network == nil {
+		return "unknown"
+	}
+	for _, frequency := range state.State.Frequencies[*network] {
+		if frequency.ID == *message.FrequencyID {
+			return state.State.Networks[*network].Name + " › #" + frequency.Name
+		}
+	}
+	return "unknown"
+}
+
+// snippet returns a single line of the content around the first
+// matching term, with the matching terms highlighted
+func snippet(content string, terms []string, width int, style lipgloss.Style) string {
+	content = strings.Join(strings.Fields(content), " ")
+
+	start := 0
+	for _, term := range terms {
+		if i := indexFold(content, term); i != -1 {
+			start = i
+			break
+		}
+	}
+
+	prefix := ""
+	if utf8.RuneCountInString(content[:start]) > contextBefore {
+		runes := []rune(content[:start])
+		start -= len(string(runes[len(runes)-contextBefore:]))
+		prefix = ellipsis
+	} else {
+		start = 0
+	}
+	content = prefix + content[start:]
+
+	highlightStyle := style.Foreground(colors.Gold).Bold(true)
+	var builder strings.Builder
+	last := 0
+	for i := 0; i < len(content); {
+		matched := 0
+		for _, term := range terms {
+			if hasPrefixFold(content[i:], term) {
+				matched = max(matched, len(term))
+			}
+		}
+		if matched == 0 {
+			_, size := utf8.DecodeRuneInString(content[i:])
+			i += size
+			continue
+		}
+		builder.WriteString(style.Render(content[last:i]))
+		builder.WriteString(highlightStyle.Render(content[i : i+matched]))
+		i += matched
+		last = i
+	}
+	builder.WriteString(style.Render(content[last:]))
+
+	line := builder.String()
+	if lipgloss.Width(line) > width {
+		line = lipgloss.NewStyle().MaxWidth(width-1).Render(line) + style.Render(ellipsis)
+	}
+	return line
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return prefix != "" && len(prefix) <= len(s) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func indexFold(s, substr string) int {
+	for i := range s {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
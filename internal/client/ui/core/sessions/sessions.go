@@ -0,0 +1,145 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sessions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/packet"
+)
+
+var width = 64
+
+type Model struct {
+	sessions []packet.Session
+	index    int
+}
+
+func New() Model {
+	return Model{
+		sessions: nil,
+		index:    0,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return gateway.Send(&packet.GetSessions{})
+}
+
+func (m *Model) SetSessions(sessions []packet.Session) {
+	m.sessions = sessions
+	m.index = min(m.index, max(len(m.sessions)-1, 0))
+}
+
+func (m Model) View() string {
+	style := lipgloss.NewStyle().Width(width).
+		Background(colors.Background).Foreground(colors.White)
+
+	lines := []string{
+		style.Foreground(colors.Turquoise).Render("Active Sessions"),
+		"",
+	}
+
+	if m.sessions == nil {
+		lines = append(lines, style.Foreground(colors.LightGray).Render("Loading..."))
+	}
+	for i, session := range m.sessions {
+		lineStyle := style
+		if i == m.index {
+			lineStyle = lineStyle.Background(colors.BackgroundHighlight).Foreground(colors.Focus)
+		}
+		lines = append(lines, lineStyle.Render(renderSession(session)))
+	}
+
+	lines = append(lines, "", style.Foreground(colors.LightGray).
+		Render("enter to sign out the selected session"))
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		Padding(1, 4).
+		BorderBackground(colors.Background).
+		BorderForeground(colors.White).
+		Background(colors.Background).
+		Foreground(colors.White).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func renderSession(session packet.Session) string {
+	device := "Unknown device"
+	if session.OS != "" {
+		device = session.OS + "/" + session.Arch
+		if session.Term != "" {
+			device += " " + session.Term
+		}
+	}
+
+	start := time.UnixMilli(session.Start).Local().Format("Jan 02 15:04")
+	duration := "<1m"
+	if session.Duration >= time.Minute {
+		duration = strings.TrimSuffix(session.Duration.Round(time.Minute).String(), "0s")
+	}
+
+	line := fmt.Sprintf("%v %v\n  %v, since %v (%v)", keyName(session), device, session.Addr, start, duration)
+	if session.Current {
+		line += " (this session)"
+	}
+	return line
+}
+
+// keyName returns the name of the key the session signed in with
+func keyName(session packet.Session) string {
+	for _, device := range state.State.Devices {
+		if device.UserID == *state.UserID && bytes.Equal(device.PublicKey, session.PubKey) {
+			return device.Name + ":"
+		}
+	}
+	return "Account key:"
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "j", "down":
+			m.index = min(m.index+1, max(len(m.sessions)-1, 0))
+		case "k", "up":
+			m.index = max(m.index-1, 0)
+		}
+	}
+
+	return m, nil
+}
+
+// Select signs out the selected session, the popup stays open
+func (m *Model) Select() tea.Cmd {
+	if m.index < 0 || m.index >= len(m.sessions) {
+		return nil
+	}
+	session := m.sessions[m.index]
+	if session.Current {
+		return nil
+	}
+	return gateway.Send(&packet.TerminateSession{Session: session.ID})
+}
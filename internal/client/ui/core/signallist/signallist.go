@@ -94,10 +94,14 @@ func (m Model) View() string {
 
 		user := state.State.Users[signal]
 		trustedPublicKey, isTrusted := state.State.TrustedUsers[user.ID]
-		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey)
+		isRotated := state.KeyRotated(user.ID)
+		keysMatch := bytes.Equal(trustedPublicKey, user.PublicKey) || isRotated
+		group, isGroup := state.State.GroupSignals[signal]
 
 		var userStyle lipgloss.Style
-		if isTrusted && keysMatch {
+		if isGroup {
+			userStyle = ui.GroupSignalStyle().Background(colors.BackgroundDim)
+		} else if isTrusted && keysMatch {
 			userStyle = ui.TrustedUserStyle().Background(colors.BackgroundDim)
 		} else {
 			userStyle = ui.UserStyle().Background(colors.BackgroundDim)
@@ -123,10 +127,21 @@ func (m Model) View() string {
 		}
 
 		username := user.Name
+		if isGroup {
+			username = group.Name
+		}
 		username = userStyle.Render(username)
-		if isTrusted && !keysMatch {
+		if isRotated {
+			username = ui.KeyRotatedSymbol() + username
+		} else if isTrusted && !keysMatch {
 			username = ui.UntrustedSymbol() + username
 		}
+		if state.IsMessageRequest(signal) {
+			username = ui.RequestSymbol() + username
+		}
+		if !isGroup {
+			username = ui.PresenceSymbol(state.Presence(user.ID).State) + username
+		}
 
 		blockSymbol := ""
 		if _, ok := state.State.BlockedUsers[user.ID]; ok {
@@ -227,6 +242,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, nil
 			}
 			userId := state.Data.Signals[m.index]
+			if state.IsGroupSignal(userId) {
+				return m, nil
+			}
 
 			return m, func() tea.Msg {
 				return ui.ProfilePopupMsg{
@@ -243,11 +261,49 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.SetIndex(m.index - 1)
 			}
 
+		case "x":
+			if m.index == -1 {
+				return m, nil
+			}
+			signal := state.Data.Signals[m.index]
+			if state.IsMessageRequest(signal) {
+				return m, gateway.Send(&packet.IgnoreMessageRequest{
+					User: signal,
+				})
+			}
+			if !state.IsGroupSignal(signal) {
+				return m, nil
+			}
+
+			return m, gateway.Send(&packet.LeaveGroupSignal{
+				Group: signal,
+			})
+
+		case "E":
+			if m.index == -1 {
+				return m, nil
+			}
+			userId := state.Data.Signals[m.index]
+			if state.IsGroupSignal(userId) {
+				return m, nil
+			}
+
+			if state.IsEncrypted(userId) {
+				state.Data.Encrypted = slices.DeleteFunc(state.Data.Encrypted, func(id snowflake.ID) bool {
+					return id == userId
+				})
+			} else {
+				state.Data.Encrypted = append(state.Data.Encrypted, userId)
+			}
+
 		case "T":
 			if m.index == -1 {
 				return m, nil
 			}
 			userId := state.Data.Signals[m.index]
+			if state.IsGroupSignal(userId) {
+				return m, nil
+			}
 
 			_, isTrusting := state.State.TrustedUsers[userId]
 
@@ -266,6 +322,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, nil
 			}
 			userId := state.Data.Signals[m.index]
+			if state.IsGroupSignal(userId) {
+				return m, nil
+			}
 
 			if userId == *state.UserID {
 				return m, nil
@@ -284,6 +343,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, nil
 			}
 			userId := state.Data.Signals[m.index]
+			if state.IsGroupSignal(userId) {
+				return m, nil
+			}
 
 			if userId == *state.UserID {
 				return m, nil
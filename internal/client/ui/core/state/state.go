@@ -17,6 +17,7 @@
 package state
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"encoding/json"
@@ -25,13 +26,19 @@ import (
 	"time"
 
 	"github.com/google/btree"
+	"github.com/kyren223/eko/internal/client/e2ee"
 	"github.com/kyren223/eko/internal/client/gateway"
 	"github.com/kyren223/eko/internal/data"
 	"github.com/kyren223/eko/internal/packet"
 	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
+	"golang.org/x/crypto/ssh"
 )
 
+// How long a user is considered typing after their last typing event
+const TypingTimeout = 5 * time.Second
+
 type ChatState struct {
 	IncompleteMessage string
 	Base              int
@@ -47,9 +54,18 @@ type state struct {
 	Frequencies   map[snowflake.ID][]data.Frequency             // key is network id
 	Members       map[snowflake.ID]map[snowflake.ID]data.Member // key is network id then user id
 	Users         map[snowflake.ID]data.User                    // key is user id
-	TrustedUsers  map[snowflake.ID]ed25519.PublicKey            // key is user id
+	TrustedUsers  map[snowflake.ID]sshkey.PublicKey             // key is user id
 	BlockedUsers  map[snowflake.ID]struct{}                     // key is user id
 	BlockingUsers map[snowflake.ID]struct{}                     // key is user id
+	Attachments   map[snowflake.ID]data.Attachment              // key is attachment id
+	Typing        map[snowflake.ID]map[snowflake.ID]time.Time   // key is frequency id or receiver id then user id
+	Presences     map[snowflake.ID]packet.Presence              // key is user id
+	ReadReceipts  map[snowflake.ID]snowflake.ID                 // key is receiver id
+	GroupSignals  map[snowflake.ID]GroupSignal                  // key is group id
+	Requests      map[snowflake.ID]data.Message                 // key is sender id
+	Signatures    map[snowflake.ID]int                          // key is message id
+	KeyRotations  map[snowflake.ID][]data.KeyRotation           // key is user id
+	Devices       map[snowflake.ID]data.DeviceKey               // key is device id
 
 	LastReadMessages    map[snowflake.ID]*snowflake.ID // key is frequency id or receiver id
 	RemoteNotifications map[snowflake.ID]int           // key is frequency id or receiver id
@@ -64,31 +80,67 @@ var State state = state{
 	Frequencies:         map[snowflake.ID][]data.Frequency{},
 	Members:             map[snowflake.ID]map[snowflake.ID]data.Member{},
 	Users:               map[snowflake.ID]data.User{},
-	TrustedUsers:        map[snowflake.ID]ed25519.PublicKey{},
+	TrustedUsers:        map[snowflake.ID]sshkey.PublicKey{},
 	BlockedUsers:        map[snowflake.ID]struct{}{},
 	BlockingUsers:       map[snowflake.ID]struct{}{},
+	Attachments:         map[snowflake.ID]data.Attachment{},
+	Typing:              map[snowflake.ID]map[snowflake.ID]time.Time{},
+	Presences:           map[snowflake.ID]packet.Presence{},
+	ReadReceipts:        map[snowflake.ID]snowflake.ID{},
+	GroupSignals:        map[snowflake.ID]GroupSignal{},
+	Requests:            map[snowflake.ID]data.Message{},
+	Signatures:          map[snowflake.ID]int{},
+	KeyRotations:        map[snowflake.ID][]data.KeyRotation{},
+	Devices:             map[snowflake.ID]data.DeviceKey{},
 	LastReadMessages:    map[snowflake.ID]*snowflake.ID{},
 	RemoteNotifications: map[snowflake.ID]int{},
 	LocalNotifications:  map[snowflake.ID]int{},
 }
 
+type GroupSignal struct {
+	data.GroupSignal
+	Members []snowflake.ID
+}
+
 type UserData struct {
-	Networks []snowflake.ID
-	Signals  []snowflake.ID
+	Networks  []snowflake.ID
+	Signals   []snowflake.ID
+	Encrypted []snowflake.ID // Signals with end-to-end encryption enabled
 }
 
 var Data UserData = UserData{
-	Networks: []snowflake.ID{},
-	Signals:  []snowflake.ID{},
+	Networks:  []snowflake.ID{},
+	Signals:   []snowflake.ID{},
+	Encrypted: []snowflake.ID{},
 }
 
-var UserID *snowflake.ID = nil
+var (
+	UserID *snowflake.ID = nil
+	// Signer signs on behalf of the user, PrivateKey is only set if
+	// the signer is an ed25519 key file, as encryption requires it
+	Signer     ssh.Signer         = nil
+	PrivateKey ed25519.PrivateKey = nil
+)
+
+const (
+	SignatureValid      = iota
+	SignatureUnverified // The sender's public key is unknown
+	SignatureMissing
+	SignatureInvalid
+)
+
+// UndecryptableContent replaces the content of encrypted
+// messages that failed to decrypt
+const UndecryptableContent = "[Unable to decrypt this message]"
 
 func Reset() {
 	UserID = nil
+	Signer = nil
+	PrivateKey = nil
 	Data = UserData{
-		Networks: []snowflake.ID{},
-		Signals:  []snowflake.ID{},
+		Networks:  []snowflake.ID{},
+		Signals:   []snowflake.ID{},
+		Encrypted: []snowflake.ID{},
 	}
 	State = state{
 		ChatState:           map[snowflake.ID]ChatState{},
@@ -98,9 +150,18 @@ func Reset() {
 		Frequencies:         map[snowflake.ID][]data.Frequency{},
 		Members:             map[snowflake.ID]map[snowflake.ID]data.Member{},
 		Users:               map[snowflake.ID]data.User{},
-		TrustedUsers:        map[snowflake.ID]ed25519.PublicKey{},
+		TrustedUsers:        map[snowflake.ID]sshkey.PublicKey{},
 		BlockedUsers:        map[snowflake.ID]struct{}{},
 		BlockingUsers:       map[snowflake.ID]struct{}{},
+		Attachments:         map[snowflake.ID]data.Attachment{},
+		Typing:              map[snowflake.ID]map[snowflake.ID]time.Time{},
+		Presences:           map[snowflake.ID]packet.Presence{},
+		ReadReceipts:        map[snowflake.ID]snowflake.ID{},
+		GroupSignals:        map[snowflake.ID]GroupSignal{},
+		Requests:            map[snowflake.ID]data.Message{},
+		Signatures:          map[snowflake.ID]int{},
+		KeyRotations:        map[snowflake.ID][]data.KeyRotation{},
+		Devices:             map[snowflake.ID]data.DeviceKey{},
 		LastReadMessages:    map[snowflake.ID]*snowflake.ID{},
 		RemoteNotifications: map[snowflake.ID]int{},
 		LocalNotifications:  map[snowflake.ID]int{},
@@ -185,9 +246,22 @@ func UpdateMessages(info *packet.MessagesInfo) {
 		}
 	}
 
+	for _, attachment := range info.Attachments {
+		State.Attachments[attachment.ID] = attachment
+	}
+
 	unknownUsers := []snowflake.ID{}
 	for _, message := range info.Messages {
+		// Signatures are over the content as sent, so verify before decrypting
+		State.Signatures[message.ID] = verifyMessage(message)
+		if message.IsEncrypted {
+			message.Content = decryptMessage(message)
+		}
+
 		msgSource := message.FrequencyID
+		if msgSource == nil {
+			msgSource = message.GroupID
+		}
 		if msgSource == nil {
 			msgSource = message.ReceiverID
 			if *message.ReceiverID == *UserID {
@@ -202,10 +276,22 @@ func UpdateMessages(info *packet.MessagesInfo) {
 			State.Messages[*msgSource] = bt
 		}
 		bt.ReplaceOrInsert(message)
+		delete(State.Typing[*msgSource], message.SenderID)
 
 		if _, ok := State.Users[message.SenderID]; !ok {
 			unknownUsers = append(unknownUsers, message.SenderID)
 		}
+
+		if message.FrequencyID == nil {
+			continue
+		}
+		for _, mention := range packet.ParseMentions(packet.WithLegacyPing(message.Content, message.Ping)) {
+			isUser := mention != packet.PingEveryone && mention != packet.PingAdmins
+			_, ok := State.Users[mention]
+			if isUser && !ok && !slices.Contains(unknownUsers, mention) {
+				unknownUsers = append(unknownUsers, mention)
+			}
+		}
 	}
 
 	gateway.SendAsync(&packet.GetUsers{
@@ -225,6 +311,12 @@ func UpdateMessages(info *packet.MessagesInfo) {
 	}
 }
 
+func UpdateAttachments(info *packet.AttachmentsInfo) {
+	for _, attachment := range info.Attachments {
+		State.Attachments[attachment.ID] = attachment
+	}
+}
+
 func UpdateMembers(info *packet.MembersInfo) {
 	for _, member := range info.Members {
 		if State.Members[info.Network] == nil {
@@ -282,6 +374,9 @@ func FromJsonUserData(s string) {
 	if data.Signals != nil {
 		Data.Signals = data.Signals
 	}
+	if data.Encrypted != nil {
+		Data.Encrypted = data.Encrypted
+	}
 	log.Println("Updated user data:", Data)
 }
 
@@ -292,6 +387,7 @@ func UpdateTrustedUsers(info *packet.TrustInfo) {
 
 	for i, trusted := range info.TrustedUsers {
 		State.TrustedUsers[trusted] = info.TrustedPublicKeys[i]
+		delete(State.Requests, trusted) // Trusting accepts the request
 	}
 }
 
@@ -326,6 +422,19 @@ func IsFrequency(id snowflake.ID) bool {
 	return false
 }
 
+// FrequencyNetwork returns the id of the network the frequency is in
+func FrequencyNetwork(id snowflake.ID) *snowflake.ID {
+	// OPTIMIZE: same as IsFrequency
+	for networkId, frequencies := range State.Frequencies {
+		for _, frequency := range frequencies {
+			if id == frequency.ID {
+				return &networkId
+			}
+		}
+	}
+	return nil
+}
+
 func UpdateNotifications(info *packet.NotificationsInfo) []snowflake.ID {
 	signals := []snowflake.ID{}
 
@@ -412,6 +521,7 @@ func UpdateBlockedUsers(info *packet.BlockInfo) {
 	for _, blocked := range info.BlockedUsers {
 		State.BlockedUsers[blocked] = struct{}{}
 		delete(State.TrustedUsers, blocked)
+		delete(State.Requests, blocked)
 	}
 
 	for _, removed := range info.RemovedBlockingUsers {
@@ -434,3 +544,426 @@ func MergedNotification(chatId snowflake.ID) (pings int, ok bool) {
 	localPings, localOk := State.LocalNotifications[chatId]
 	return remotePings + localPings, remoteOk || localOk
 }
+
+func UpdateTyping(typing *packet.Typing) {
+	chatId := typing.User
+	if typing.FrequencyID != nil {
+		chatId = *typing.FrequencyID
+	} else if *typing.ReceiverID != *UserID {
+		chatId = *typing.ReceiverID // Group signal
+	}
+
+	users := State.Typing[chatId]
+	if users == nil {
+		users = map[snowflake.ID]time.Time{}
+		State.Typing[chatId] = users
+	}
+	users[typing.User] = time.Now()
+}
+
+// TypingUsers returns the users currently typing in the given chat,
+// ordered from the first to start typing to the last.
+func TypingUsers(chatId snowflake.ID) []snowflake.ID {
+	users := State.Typing[chatId]
+	typing := make([]snowflake.ID, 0, len(users))
+	for user, lastTyped := range users {
+		if time.Since(lastTyped) >= TypingTimeout {
+			delete(users, user)
+			continue
+		}
+		typing = append(typing, user)
+	}
+
+	slices.SortFunc(typing, func(a, b snowflake.ID) int {
+		return users[a].Compare(users[b])
+	})
+	return typing
+}
+
+func UpdatePresences(info *packet.PresenceInfo) {
+	for _, presence := range info.Presences {
+		if presence.State == packet.PresenceOffline && presence.Status == "" {
+			delete(State.Presences, presence.User)
+		} else {
+			State.Presences[presence.User] = presence
+		}
+	}
+}
+
+// Presence returns the presence of the given user,
+// users the server never sent a presence for are offline.
+func Presence(userId snowflake.ID) packet.Presence {
+	if UserID != nil && *UserID == userId {
+		// We are connected, the server doesn't send us our own presence
+		user := State.Users[userId]
+		presence := packet.Presence{
+			Status: user.Status,
+			User:   userId,
+			State:  packet.PresenceOnline,
+		}
+		if user.IsDND {
+			presence.State = packet.PresenceDoNotDisturb
+		}
+		return presence
+	}
+
+	if presence, ok := State.Presences[userId]; ok {
+		return presence
+	}
+	return packet.Presence{User: userId, State: packet.PresenceOffline}
+}
+
+func UpdateReadReceipts(info *packet.ReadReceiptsInfo) {
+	for _, removedUser := range info.RemovedUsers {
+		delete(State.ReadReceipts, removedUser)
+	}
+	for i, user := range info.Users {
+		State.ReadReceipts[user] = snowflake.ID(info.LastRead[i])
+	}
+}
+
+// ReadReceipt returns the last message the given receiver read in their
+// DM with us, only if both of us have read receipts enabled
+func ReadReceipt(receiverId snowflake.ID) *snowflake.ID {
+	if UserID == nil || !State.Users[*UserID].IsReadReceipts {
+		return nil
+	}
+	lastRead, ok := State.ReadReceipts[receiverId]
+	if !ok {
+		return nil
+	}
+	return &lastRead
+}
+
+func UpdateGroupSignals(info *packet.GroupSignalsInfo) {
+	for _, removed := range info.RemovedGroups {
+		delete(State.GroupSignals, removed)
+		Data.Signals = slices.DeleteFunc(Data.Signals, func(signal snowflake.ID) bool {
+			return signal == removed
+		})
+	}
+
+	for _, group := range info.Groups {
+		State.GroupSignals[group.ID] = GroupSignal{
+			GroupSignal: group.GroupSignal,
+			Members:     group.Members,
+		}
+		for _, user := range group.Users {
+			State.Users[user.ID] = user
+		}
+		if !slices.Contains(Data.Signals, group.ID) {
+			Data.Signals = append(Data.Signals, group.ID)
+		}
+	}
+}
+
+func IsGroupSignal(id snowflake.ID) bool {
+	_, ok := State.GroupSignals[id]
+	return ok
+}
+
+func UpdateMessageRequests(info *packet.MessageRequestsInfo) {
+	for _, removed := range info.RemovedRequests {
+		delete(State.Requests, removed)
+		Data.Signals = slices.DeleteFunc(Data.Signals, func(signal snowflake.ID) bool {
+			return signal == removed
+		})
+	}
+
+	for _, user := range info.Users {
+		State.Users[user.ID] = user
+	}
+
+	for _, request := range info.Requests {
+		State.Requests[request.SenderID] = request
+		if !slices.Contains(Data.Signals, request.SenderID) {
+			Data.Signals = slices.Insert(Data.Signals, 0, request.SenderID)
+		}
+	}
+
+	UpdateMessages(&packet.MessagesInfo{
+		Messages:        info.Requests,
+		RemovedMessages: nil,
+		Attachments:     nil,
+	})
+}
+
+func IsMessageRequest(id snowflake.ID) bool {
+	_, ok := State.Requests[id]
+	return ok
+}
+
+// IsEncrypted returns whether end-to-end encryption is enabled for the signal
+func IsEncrypted(signal snowflake.ID) bool {
+	return slices.Contains(Data.Encrypted, signal)
+}
+
+// EncryptionKey returns the key shared with the given user, or nil if the user
+// isn't trusted, their key no longer matches the pinned public key,
+// the user is signed in with a device key, or either key isn't an ed25519 key
+func EncryptionKey(userId snowflake.ID) []byte {
+	user, ok := State.Users[userId]
+	if _, isTrusted := State.TrustedUsers[userId]; !ok || !isTrusted || KeyMismatch(userId) {
+		return nil
+	}
+	if IsDeviceKey() {
+		return nil
+	}
+	return sharedKey(user.PublicKey)
+}
+
+// KeyMismatch returns whether the user is trusted, but their public key
+// no longer matches the pinned public key, nor was it rotated from it
+func KeyMismatch(userId snowflake.ID) bool {
+	trustedPublicKey, isTrusted := State.TrustedUsers[userId]
+	user, ok := State.Users[userId]
+	return isTrusted && ok && !bytes.Equal(trustedPublicKey, user.PublicKey) && !KeyRotated(userId)
+}
+
+// KeyRotated returns whether the user is trusted and their public key was
+// rotated from the pinned public key, each rotation signed by the previous key
+func KeyRotated(userId snowflake.ID) bool {
+	trustedPublicKey, isTrusted := State.TrustedUsers[userId]
+	user, ok := State.Users[userId]
+	if !isTrusted || !ok || bytes.Equal(trustedPublicKey, user.PublicKey) {
+		return false
+	}
+	keys := trustedKeys(userId)
+	return bytes.Equal(keys[len(keys)-1], user.PublicKey)
+}
+
+// trustedKeys returns the user's pinned public key followed by every key it
+// was rotated to, stopping at the first rotation that fails to verify
+func trustedKeys(userId snowflake.ID) []sshkey.PublicKey {
+	if UserID != nil && userId == *UserID {
+		return ownKeys()
+	}
+
+	trustedPublicKey, isTrusted := State.TrustedUsers[userId]
+	if !isTrusted {
+		return nil
+	}
+	return rotateKeys(userId, trustedPublicKey)
+}
+
+// ownKeys returns every key the user rotated through, or just the current
+// one if the rotations don't end with it
+func ownKeys() []sshkey.PublicKey {
+	publicKey := PublicKey()
+	if publicKey == nil {
+		return nil
+	}
+	if user, ok := State.Users[*UserID]; ok && IsDeviceKey() {
+		publicKey = user.PublicKey
+	}
+
+	rotations := State.KeyRotations[*UserID]
+	if len(rotations) == 0 {
+		return []sshkey.PublicKey{publicKey}
+	}
+	keys := rotateKeys(*UserID, rotations[0].OldPublicKey)
+	if !bytes.Equal(keys[len(keys)-1], publicKey) {
+		return []sshkey.PublicKey{publicKey}
+	}
+	return keys
+}
+
+func rotateKeys(userId snowflake.ID, publicKey sshkey.PublicKey) []sshkey.PublicKey {
+	keys := []sshkey.PublicKey{publicKey}
+	for _, rotation := range State.KeyRotations[userId] {
+		current := keys[len(keys)-1]
+		if !bytes.Equal(rotation.OldPublicKey, current) {
+			continue
+		}
+		payload := packet.KeyRotationSignaturePayload(userId, rotation.OldPublicKey, rotation.NewPublicKey)
+		if !sshkey.Verify(current, payload, rotation.Signature) {
+			log.Println("invalid key rotation signature:", rotation.ID, "user:", userId)
+			break
+		}
+		keys = append(keys, rotation.NewPublicKey)
+	}
+	return keys
+}
+
+func UpdateKeyRotations(info *packet.KeyRotationsInfo) {
+	for _, rotation := range info.Rotations {
+		rotations := State.KeyRotations[rotation.UserID]
+		if slices.ContainsFunc(rotations, func(r data.KeyRotation) bool { return r.ID == rotation.ID }) {
+			continue
+		}
+		State.KeyRotations[rotation.UserID] = append(rotations, rotation)
+
+		user, ok := State.Users[rotation.UserID]
+		if ok && bytes.Equal(user.PublicKey, rotation.OldPublicKey) {
+			user.PublicKey = rotation.NewPublicKey
+			State.Users[rotation.UserID] = user
+		}
+	}
+}
+
+// LacksEncryptionKey returns whether the user signed in through ssh-agent
+// or with an ECDSA or RSA key, encryption keys are derived from
+// an ed25519 private key so they can't be derived
+func LacksEncryptionKey() bool {
+	return Signer != nil && PrivateKey == nil
+}
+
+// SupportsEncryption returns whether the user's key can be used to derive
+// encryption keys, which only ed25519 keys can
+func SupportsEncryption(userId snowflake.ID) bool {
+	user, ok := State.Users[userId]
+	return ok && user.PublicKey.Ed25519() != nil
+}
+
+// IsDeviceKey returns whether the user is signed in with
+// one of their device keys rather than their account key
+func IsDeviceKey() bool {
+	publicKey := PublicKey()
+	if publicKey == nil || UserID == nil {
+		return false
+	}
+	for _, device := range State.Devices {
+		if device.UserID == *UserID && bytes.Equal(device.PublicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceKeys returns the keys of the user's devices that were authorized,
+// directly or through other devices, by one of the given keys
+func deviceKeys(userId snowflake.ID, publicKeys []sshkey.PublicKey) []sshkey.PublicKey {
+	keys := []sshkey.PublicKey{}
+	authorized := slices.Clone(publicKeys)
+	for i := 0; i < len(authorized); i++ {
+		for _, device := range State.Devices {
+			if device.UserID != userId || !bytes.Equal(device.AuthorizedBy, authorized[i]) {
+				continue
+			}
+			if slices.ContainsFunc(authorized, func(key sshkey.PublicKey) bool {
+				return bytes.Equal(key, device.PublicKey)
+			}) {
+				continue
+			}
+			payload := packet.DeviceSignaturePayload(userId, device.PublicKey)
+			if !sshkey.Verify(authorized[i], payload, device.Signature) {
+				log.Println("invalid device signature:", device.ID, "user:", userId)
+				continue
+			}
+			authorized = append(authorized, device.PublicKey)
+			keys = append(keys, device.PublicKey)
+		}
+	}
+	return keys
+}
+
+func UpdateDevices(info *packet.DevicesInfo) {
+	for _, device := range info.Devices {
+		State.Devices[device.ID] = device
+	}
+	for _, id := range info.RemovedDevices {
+		delete(State.Devices, id)
+	}
+}
+
+// sharedKey returns the key shared with the given public key,
+// or nil if it can't be derived
+func sharedKey(publicKey sshkey.PublicKey) []byte {
+	peer := publicKey.Ed25519()
+	if PrivateKey == nil || peer == nil {
+		return nil
+	}
+
+	key, err := e2ee.SharedKey(PrivateKey, peer)
+	if err != nil {
+		log.Println("failed to derive shared key:", err)
+		return nil
+	}
+	return key
+}
+
+func decryptMessage(message data.Message) string {
+	peer := message.SenderID
+	if peer == *UserID && message.ReceiverID != nil {
+		peer = *message.ReceiverID
+	}
+
+	// Messages sent before a key change or rotation are still decryptable
+	keys := trustedKeys(peer)
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := sharedKey(keys[i])
+		if key == nil {
+			continue
+		}
+		content, err := e2ee.Decrypt(key, message.Content)
+		if err == nil {
+			return content
+		}
+	}
+
+	log.Println("failed to decrypt message:", message.ID)
+	return UndecryptableContent
+}
+
+// SignMessage returns the signature of the message content
+// sent to the given frequency or receiver
+func SignMessage(chat snowflake.ID, attachment *snowflake.ID, content string) []byte {
+	return Sign(packet.MessageSignaturePayload(chat, attachment, content))
+}
+
+// PublicKey returns the public key the user signed in with
+func PublicKey() sshkey.PublicKey {
+	if Signer == nil {
+		return nil
+	}
+	return sshkey.FromSSH(Signer.PublicKey())
+}
+
+// Sign returns the signature of the message using the user's signer,
+// or nil if signing failed
+func Sign(message []byte) []byte {
+	if Signer == nil {
+		return nil
+	}
+	signature, err := sshkey.Sign(Signer, message)
+	if err != nil {
+		log.Println("failed to sign:", err)
+		return nil
+	}
+	return signature
+}
+
+// verifyMessage verifies the message's signature against the sender's
+// pinned public key and the keys it was rotated to if they are trusted
+// or the sender is the user, or their current one otherwise,
+// as well as the device keys authorized by any of those
+func verifyMessage(message data.Message) int {
+	if message.Signature == nil {
+		return SignatureMissing
+	}
+
+	publicKeys := trustedKeys(message.SenderID)
+	if publicKeys == nil {
+		user, ok := State.Users[message.SenderID]
+		if !ok {
+			return SignatureUnverified
+		}
+		publicKeys = []sshkey.PublicKey{user.PublicKey}
+	}
+	publicKeys = append(publicKeys, deviceKeys(message.SenderID, publicKeys)...)
+
+	chat := message.FrequencyID
+	if chat == nil {
+		chat = message.GroupID
+	}
+	if chat == nil {
+		chat = message.ReceiverID
+	}
+	payload := packet.MessageSignaturePayload(*chat, message.AttachmentID, message.Content)
+	for _, publicKey := range publicKeys {
+		if sshkey.Verify(publicKey, payload, message.Signature) {
+			return SignatureValid
+		}
+	}
+	return SignatureInvalid
+}
@@ -0,0 +1,403 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package transfer uploads and downloads attachments in chunks.
+// Both directions are stop-and-wait, the next chunk is only sent
+// (or requested) after the previous one was acknowledged.
+package transfer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyren223/eko/internal/client/gateway"
+	"github.com/kyren223/eko/internal/client/ui/core/state"
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const (
+	RetryDelay    = time.Second // Used when the server doesn't say when to retry
+	PartialSuffix = ".part"
+
+	errRateLimited = "rate limited" // See api.ErrRateLimited
+)
+
+type Upload struct {
+	Name string
+	Size int64
+	Sent int64
+	Done bool
+	Err  error
+
+	hash       string
+	file       *os.File
+	attachment *snowflake.ID // Assigned by the server when it asks for the first chunk
+	awaiting   bool
+	request    packet.Payload
+
+	frequency *snowflake.ID
+	receiver  *snowflake.ID
+}
+
+type Download struct {
+	Attachment data.Attachment
+	Path       string
+	Received   int64
+	Done       bool
+	Err        error
+
+	file     *os.File
+	hasher   hash.Hash
+	awaiting bool
+}
+
+type uploadReadyMsg struct {
+	upload *Upload
+	file   *os.File
+	hash   string
+	size   int64
+	err    error
+}
+
+type retryMsg struct{}
+
+var (
+	upload    *Upload
+	downloads = map[snowflake.ID]*Download{}
+)
+
+// CurrentUpload returns the latest upload, nil if there was none
+func CurrentUpload() *Upload {
+	return upload
+}
+
+// GetDownload returns the latest download of the attachment, if any
+func GetDownload(attachment snowflake.ID) *Download {
+	return downloads[attachment]
+}
+
+// Reset cancels all transfers, the server forgets about them on disconnect
+func Reset() {
+	err := errors.New("disconnected")
+	if upload != nil && !upload.Done {
+		upload.fail(err)
+	}
+	for _, download := range downloads {
+		download.fail(err)
+	}
+	upload = nil
+	downloads = map[snowflake.ID]*Download{}
+}
+
+// StartUpload uploads the file at the given path, once it's uploaded
+// it's sent as a message to the given frequency or receiver.
+func StartUpload(path string, frequency, receiver *snowflake.ID) tea.Cmd {
+	if upload != nil && !upload.Done && upload.Err == nil {
+		return nil // Server only allows one upload at a time
+	}
+
+	pending := &Upload{
+		Name:      filepath.Base(path),
+		frequency: frequency,
+		receiver:  receiver,
+	}
+	upload = pending
+
+	// Hashing may take a while for big files
+	return func() tea.Msg {
+		file, err := os.Open(expandHome(path))
+		if err != nil {
+			return uploadReadyMsg{upload: pending, err: err}
+		}
+
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, file)
+		if err != nil {
+			_ = file.Close()
+			return uploadReadyMsg{upload: pending, err: err}
+		}
+
+		return uploadReadyMsg{
+			upload: pending,
+			file:   file,
+			hash:   fmt.Sprintf("%x", hasher.Sum(nil)),
+			size:   size,
+		}
+	}
+}
+
+// StartDownload saves the attachment to the given path,
+// if the path is a directory, the attachment's name is used.
+func StartDownload(attachment data.Attachment, path string) tea.Cmd {
+	if download := downloads[attachment.ID]; download != nil && !download.Done && download.Err == nil {
+		return nil
+	}
+
+	path = expandHome(path)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, filepath.Base(attachment.Name))
+	}
+
+	download := &Download{
+		Attachment: attachment,
+		Path:       path,
+		hasher:     sha256.New(),
+	}
+	downloads[attachment.ID] = download
+
+	file, err := os.Create(path + PartialSuffix)
+	if err != nil {
+		download.Err = err
+		return nil
+	}
+	download.file = file
+
+	if attachment.Size == 0 {
+		download.finish()
+		return nil
+	}
+
+	return download.request()
+}
+
+func Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case uploadReadyMsg:
+		if msg.upload != upload {
+			if msg.file != nil {
+				_ = msg.file.Close()
+			}
+			return nil
+		}
+		if msg.err != nil {
+			upload.fail(msg.err)
+			return nil
+		}
+		upload.file = msg.file
+		upload.hash = msg.hash
+		upload.Size = msg.size
+		return upload.send(&packet.UploadAttachment{
+			Name: upload.Name,
+			Hash: upload.hash,
+			Size: upload.Size,
+		})
+
+	case *packet.AttachmentChunk:
+		if len(msg.Data) == 0 {
+			return onChunkRequest(msg)
+		}
+		return onChunk(msg)
+
+	case *packet.AttachmentsInfo:
+		if upload == nil || !upload.awaiting {
+			return nil
+		}
+		for _, attachment := range msg.Attachments {
+			if attachment.Hash != upload.hash || attachment.Name != upload.Name {
+				continue
+			}
+			upload.awaiting = false
+			upload.Done = true
+			upload.Sent = upload.Size
+			_ = upload.file.Close()
+
+			chat := upload.frequency
+			if chat == nil {
+				chat = upload.receiver
+			}
+
+			return gateway.Send(&packet.SendMessage{
+				ReceiverID:  upload.receiver,
+				FrequencyID: upload.frequency,
+				Attachment:  &attachment.ID,
+				Content:     "",
+				Signature:   state.SignMessage(*chat, &attachment.ID, ""),
+			})
+		}
+
+	case *packet.Error:
+		// Errors aren't tied to a request, so assume it's for whatever
+		// transfer is waiting for a response
+		if msg.Error == errRateLimited {
+			delay := RetryDelay
+			if msg.RetryAfter > 0 {
+				delay = msg.RetryAfter
+			}
+			return tea.Tick(delay, func(time.Time) tea.Msg {
+				return retryMsg{}
+			})
+		}
+		err := errors.New(msg.Error)
+		if upload != nil && upload.awaiting {
+			upload.fail(err)
+		}
+		for _, download := range downloads {
+			if download.awaiting {
+				download.fail(err)
+			}
+		}
+
+	case retryMsg:
+		var cmds []tea.Cmd
+		if upload != nil && upload.awaiting {
+			cmds = append(cmds, gateway.Send(upload.request))
+		}
+		for _, download := range downloads {
+			if download.awaiting {
+				cmds = append(cmds, download.request())
+			}
+		}
+		return tea.Batch(cmds...)
+	}
+
+	return nil
+}
+
+func onChunkRequest(msg *packet.AttachmentChunk) tea.Cmd {
+	if upload == nil || !upload.awaiting {
+		return nil
+	}
+	if upload.attachment == nil {
+		upload.attachment = &msg.Attachment
+	} else if *upload.attachment != msg.Attachment {
+		return nil
+	}
+
+	offset := msg.Offset
+	if offset < 0 || offset >= upload.Size {
+		upload.fail(fmt.Errorf("server requested invalid offset %v", offset))
+		return nil
+	}
+	upload.Sent = offset
+
+	chunk := make([]byte, min(packet.AttachmentChunkBytes, upload.Size-offset))
+	if _, err := upload.file.ReadAt(chunk, offset); err != nil {
+		upload.fail(err)
+		return nil
+	}
+
+	return upload.send(&packet.AttachmentChunk{
+		Data:       chunk,
+		Offset:     offset,
+		Attachment: msg.Attachment,
+	})
+}
+
+func onChunk(msg *packet.AttachmentChunk) tea.Cmd {
+	download := downloads[msg.Attachment]
+	if download == nil || !download.awaiting || msg.Offset != download.Received {
+		return nil
+	}
+	download.awaiting = false
+
+	if download.Received+int64(len(msg.Data)) > download.Attachment.Size {
+		download.fail(errors.New("attachment is larger than expected"))
+		return nil
+	}
+	if _, err := download.file.Write(msg.Data); err != nil {
+		download.fail(err)
+		return nil
+	}
+	_, _ = download.hasher.Write(msg.Data) // Never returns an error
+	download.Received += int64(len(msg.Data))
+
+	if download.Received == download.Attachment.Size {
+		download.finish()
+		return nil
+	}
+	return download.request()
+}
+
+func (u *Upload) send(request packet.Payload) tea.Cmd {
+	u.awaiting = true
+	u.request = request
+	return gateway.Send(request)
+}
+
+func (u *Upload) fail(err error) {
+	if u.Done || u.Err != nil {
+		return
+	}
+	log.Println("upload failed:", u.Name, err)
+	u.awaiting = false
+	u.Err = err
+	if u.file != nil {
+		_ = u.file.Close()
+	}
+}
+
+func (d *Download) request() tea.Cmd {
+	d.awaiting = true
+	return gateway.Send(&packet.AttachmentChunk{
+		Data:       nil,
+		Offset:     d.Received,
+		Attachment: d.Attachment.ID,
+	})
+}
+
+func (d *Download) finish() {
+	if fmt.Sprintf("%x", d.hasher.Sum(nil)) != d.Attachment.Hash {
+		d.fail(errors.New("attachment content doesn't match its hash"))
+		return
+	}
+	if err := d.file.Close(); err != nil {
+		d.fail(err)
+		return
+	}
+	if err := os.Rename(d.file.Name(), d.Path); err != nil {
+		d.fail(err)
+		return
+	}
+	d.Done = true
+	log.Println("saved attachment:", d.Attachment.Name, "to", d.Path)
+}
+
+func (d *Download) fail(err error) {
+	if d.Done || d.Err != nil {
+		return
+	}
+	log.Println("download failed:", d.Attachment.Name, err)
+	d.awaiting = false
+	d.Err = err
+	if d.file != nil {
+		_ = d.file.Close()
+		_ = os.Remove(d.file.Name())
+	}
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator))
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
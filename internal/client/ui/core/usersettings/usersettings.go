@@ -48,6 +48,39 @@ var (
 			Render("Update User Settings")
 	}
 
+	blurredRotate = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White).
+			Render("Rotate Private Key")
+	}
+	focusedRotate = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White).
+			Render("Rotate Private Key")
+	}
+
+	blurredDevices = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White).
+			Render("Manage Devices")
+	}
+	focusedDevices = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White).
+			Render("Manage Devices")
+	}
+
+	blurredSessions = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Gray).Foreground(colors.White).
+			Render("Active Sessions")
+	}
+	focusedSessions = func() string {
+		return lipgloss.NewStyle().Padding(0, 1).
+			Background(colors.Blue).Foreground(colors.White).
+			Render("Active Sessions")
+	}
+
 	blurredDelete = func() string {
 		return lipgloss.NewStyle().Padding(0, 1).
 			Background(colors.DarkGray).Foreground(colors.Red).
@@ -68,18 +101,32 @@ var (
 const (
 	NameField = iota
 	Description
+	StatusField
 	PrivateField
+	DNDField
+	HidePresenceField
+	ReadReceiptsField
 	UpdateField
+	RotateField
+	DevicesField
+	SessionsField
 	DeleteField
 	FieldCount
 )
 
 type Model struct {
-	name        field.Model
-	description field.Model
-	privateDM   bool
-	update      string
-	delete      string
+	name         field.Model
+	description  field.Model
+	status       field.Model
+	privateDM    bool
+	dnd          bool
+	hidePresence bool
+	readReceipts bool
+	update       string
+	rotate       string
+	devices      string
+	sessions     string
+	delete       string
 
 	selected  int
 	nameWidth int
@@ -136,14 +183,33 @@ func New() Model {
 	description.Input.SetValue(user.Description)
 	description.Blur()
 
+	status := field.New(width)
+	status.Header = "Status"
+	status.HeaderStyle = headerStyle
+	status.FocusedStyle = fieldFocusedStyle
+	status.BlurredStyle = fieldBlurredStyle
+	status.FocusedTextStyle = focusedTextStyle
+	status.BlurredTextStyle = blurredTextStyle
+	status.ErrorStyle = lipgloss.NewStyle().Foreground(colors.Error)
+	status.Input.CharLimit = packet.MaxUserStatusBytes
+	status.Input.SetValue(user.Status)
+	status.Blur()
+
 	return Model{
-		name:        name,
-		description: description,
-		privateDM:   !user.IsPublicDM,
-		update:      blurredUpdate(),
-		delete:      blurredDelete(),
-		selected:    0,
-		nameWidth:   nameWidth,
+		name:         name,
+		description:  description,
+		status:       status,
+		privateDM:    !user.IsPublicDM,
+		dnd:          user.IsDND,
+		hidePresence: !user.IsPresencePublic,
+		readReceipts: user.IsReadReceipts,
+		update:       blurredUpdate(),
+		rotate:       blurredRotate(),
+		devices:      blurredDevices(),
+		sessions:     blurredSessions(),
+		delete:       blurredDelete(),
+		selected:     0,
+		nameWidth:    nameWidth,
 	}
 }
 
@@ -154,19 +220,14 @@ func (m Model) Init() tea.Cmd {
 func (m Model) View() string {
 	name := m.name.View()
 	description := m.description.View()
+	status := m.status.View()
 
 	width := lipgloss.Width(name)
 
-	privateStyle := lipgloss.NewStyle().Width(width).PaddingLeft(1).
-		Background(colors.Background).Foreground(colors.White)
-	if m.selected == PrivateField {
-		privateStyle = privateStyle.Foreground(colors.Focus)
-	}
-	private := "[ ] Private DMs"
-	if m.privateDM {
-		private = "[x] Private DMs"
-	}
-	private = privateStyle.Render(private)
+	private := m.renderCheckbox("Private DMs", m.privateDM, PrivateField, width)
+	dnd := m.renderCheckbox("Do Not Disturb", m.dnd, DNDField, width)
+	hidePresence := m.renderCheckbox("Appear Offline", m.hidePresence, HidePresenceField, width)
+	readReceipts := m.renderCheckbox("Read Receipts", m.readReceipts, ReadReceiptsField, width)
 
 	update := lipgloss.NewStyle().
 		Width(m.nameWidth).
@@ -174,6 +235,24 @@ func (m Model) View() string {
 		Align(lipgloss.Center).
 		Render(m.update)
 
+	rotate := lipgloss.NewStyle().
+		Width(m.nameWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.rotate)
+
+	devices := lipgloss.NewStyle().
+		Width(m.nameWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.devices)
+
+	sessions := lipgloss.NewStyle().
+		Width(m.nameWidth).
+		Background(colors.Background).
+		Align(lipgloss.Center).
+		Render(m.sessions)
+
 	del := lipgloss.NewStyle().
 		Width(m.nameWidth).
 		Background(colors.Background).
@@ -200,7 +279,8 @@ func (m Model) View() string {
 		Render(analyticsOptOut)
 
 	content := flex.NewVertical(
-		analyticsOptOut, configFile, cacheFile, name, description, private, update, del,
+		analyticsOptOut, configFile, cacheFile, name, description, status,
+		private, dnd, hidePresence, readReceipts, update, rotate, devices, sessions, del,
 	).WithGap(1).View()
 
 	return lipgloss.NewStyle().
@@ -214,6 +294,18 @@ func (m Model) View() string {
 		Render(content)
 }
 
+func (m Model) renderCheckbox(label string, checked bool, index, width int) string {
+	style := lipgloss.NewStyle().Width(width).PaddingLeft(1).
+		Background(colors.Background).Foreground(colors.White)
+	if m.selected == index {
+		style = style.Foreground(colors.Focus)
+	}
+	if checked {
+		return style.Render("[x] " + label)
+	}
+	return style.Render("[ ] " + label)
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -231,6 +323,8 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.name, cmd = m.name.Update(msg)
 			case Description:
 				m.description, cmd = m.description.Update(msg)
+			case StatusField:
+				m.status, cmd = m.status.Update(msg)
 			}
 			return m, cmd
 		}
@@ -252,18 +346,33 @@ func (m *Model) cycle(step int) tea.Cmd {
 func (m *Model) updateFocus() tea.Cmd {
 	m.name.Blur()
 	m.description.Blur()
+	m.status.Blur()
 	m.update = blurredUpdate()
+	m.rotate = blurredRotate()
+	m.devices = blurredDevices()
+	m.sessions = blurredSessions()
 	m.delete = blurredDelete()
 	switch m.selected {
 	case NameField:
 		return m.name.Focus()
 	case Description:
 		return m.description.Focus()
-	case PrivateField:
+	case StatusField:
+		return m.status.Focus()
+	case PrivateField, DNDField, HidePresenceField, ReadReceiptsField:
 		return nil
 	case UpdateField:
 		m.update = focusedUpdate()
 		return nil
+	case RotateField:
+		m.rotate = focusedRotate()
+		return nil
+	case DevicesField:
+		m.devices = focusedDevices()
+		return nil
+	case SessionsField:
+		m.sessions = focusedSessions()
+		return nil
 	case DeleteField:
 		m.delete = focusedDelete()
 		return nil
@@ -274,9 +383,37 @@ func (m *Model) updateFocus() tea.Cmd {
 }
 
 func (m *Model) Select() tea.Cmd {
-	if m.selected == PrivateField {
+	switch m.selected {
+	case PrivateField:
 		m.privateDM = !m.privateDM
 		return nil
+	case DNDField:
+		m.dnd = !m.dnd
+		return nil
+	case HidePresenceField:
+		m.hidePresence = !m.hidePresence
+		return nil
+	case ReadReceiptsField:
+		m.readReceipts = !m.readReceipts
+		return nil
+	}
+
+	if m.selected == RotateField {
+		return func() tea.Msg {
+			return ui.KeyRotationPopupMsg{}
+		}
+	}
+
+	if m.selected == DevicesField {
+		return func() tea.Msg {
+			return ui.DevicesPopupMsg{}
+		}
+	}
+
+	if m.selected == SessionsField {
+		return func() tea.Msg {
+			return ui.SessionsPopupMsg{}
+		}
 	}
 
 	if m.selected == DeleteField {
@@ -305,12 +442,16 @@ func (m *Model) Select() tea.Cmd {
 	return gateway.Send(&packet.SetUserData{
 		Data: nil,
 		User: &data.User{
-			ID:          *state.UserID,
-			Name:        m.name.Input.Value(),
-			Description: m.description.Input.Value(),
-			IsPublicDM:  !m.privateDM,
-			IsDeleted:   false,
-			PublicKey:   nil,
+			ID:               *state.UserID,
+			Name:             m.name.Input.Value(),
+			Description:      m.description.Input.Value(),
+			IsPublicDM:       !m.privateDM,
+			IsDeleted:        false,
+			PublicKey:        nil,
+			Status:           strings.TrimSpace(m.status.Input.Value()),
+			IsDND:            m.dnd,
+			IsPresencePublic: !m.hidePresence,
+			IsReadReceipts:   m.readReceipts,
 		},
 	})
 }
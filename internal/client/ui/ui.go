@@ -29,6 +29,7 @@ import (
 	"github.com/muesli/reflow/truncate"
 
 	"github.com/kyren223/eko/internal/client/ui/colors"
+	"github.com/kyren223/eko/internal/packet"
 	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/snowflake"
 )
@@ -51,10 +52,43 @@ var (
 	TrustedMemberStyle = func() lipgloss.Style { return UserStyle().SetString("󰢏") }
 	TrustedAdminStyle  = func() lipgloss.Style { return AdminStyle().SetString("󱄻") }
 	TrustedOwnerStyle  = func() lipgloss.Style { return OwnerStyle().SetString("󱢼") }
+	GroupSignalStyle   = func() lipgloss.Style { return UserStyle().Foreground(colors.Orange).SetString("󰡉") }
 	UntrustedSymbol    = func() string { return lipgloss.NewStyle().Foreground(colors.Red).Render("󱈸") }
+	KeyRotatedSymbol   = func() string { return lipgloss.NewStyle().Foreground(colors.Gold).Render("󰌆") }
+	RequestSymbol      = func() string { return lipgloss.NewStyle().Foreground(colors.Gold).Render("󰇮 ") }
 	BlockedSymbol      = func() string { return lipgloss.NewStyle().Foreground(colors.Red).Render(" 󰅜") }
 )
 
+// PresenceSymbol renders a dot colored by the given presence state
+func PresenceSymbol(presence int) string {
+	style := lipgloss.NewStyle()
+	switch presence {
+	case packet.PresenceOnline:
+		style = style.Foreground(colors.Green)
+	case packet.PresenceIdle:
+		style = style.Foreground(colors.Gold)
+	case packet.PresenceDoNotDisturb:
+		style = style.Foreground(colors.Red)
+	default:
+		style = style.Foreground(colors.Gray)
+	}
+	return style.Render("●")
+}
+
+// PresenceName returns a human readable name of the given presence state
+func PresenceName(presence int) string {
+	switch presence {
+	case packet.PresenceOnline:
+		return "Online"
+	case packet.PresenceIdle:
+		return "Idle"
+	case packet.PresenceDoNotDisturb:
+		return "Do Not Disturb"
+	default:
+		return "Offline"
+	}
+}
+
 var NewAuth func() tea.Model
 
 // Used to update a model with a "fake" message
@@ -86,6 +120,44 @@ type BanReasonPopupMsg struct {
 	User    snowflake.ID
 }
 
+type PinsPopupMsg struct {
+	Source snowflake.ID
+}
+
+type JumpToMessageMsg struct {
+	Message snowflake.ID
+}
+
+type OpenLinkMsg struct {
+	Link packet.Link
+}
+
+type OpenSignalMsg struct {
+	Signal  snowflake.ID
+	Message *snowflake.ID
+}
+
+type SearchPopupMsg struct {
+	Network   *snowflake.ID
+	Frequency *snowflake.ID
+	Receiver  *snowflake.ID
+}
+
+type UploadAttachmentPopupMsg struct {
+	Frequency *snowflake.ID
+	Receiver  *snowflake.ID
+}
+
+type SaveAttachmentPopupMsg struct {
+	Attachment snowflake.ID
+}
+
+type KeyRotationPopupMsg struct{}
+
+type DevicesPopupMsg struct{}
+
+type SessionsPopupMsg struct{}
+
 func AddBorderHeader(header string, headerOffset int, style lipgloss.Style, render string) string {
 	b := style.GetBorderStyle()
 	body := style.UnsetBorderTop().Render(render)
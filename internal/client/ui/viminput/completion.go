@@ -0,0 +1,134 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package viminput
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kyren223/eko/internal/client/ui/colors"
+)
+
+const MaxVisibleCompletions = 5
+
+type Completion struct {
+	Display string // What is shown in the completion menu
+	Insert  string // What replaces the trigger and word when accepted
+}
+
+// Completer returns the completions for the word after the trigger
+type Completer func(word string) []Completion
+
+// updateCompletions refreshes the completion menu based on the
+// word before the cursor, closing it if there is nothing to complete
+func (m *Model) updateCompletions() {
+	m.completions = nil
+	m.completionIndex = 0
+
+	if m.Completer == nil || m.CompletionTrigger == NullChar {
+		return
+	}
+
+	line := m.lines[m.cursorLine]
+	start := m.cursorColumn
+	for start > 0 && IsKeyword(line[start-1]) {
+		start--
+	}
+	start-- // The trigger
+
+	if start < 0 || line[start] != m.CompletionTrigger {
+		return
+	}
+	if start != 0 && IsKeyword(line[start-1]) {
+		return
+	}
+
+	m.completionStart = start
+	m.completions = m.Completer(string(line[start+1 : m.cursorColumn]))
+}
+
+// handleCompletionKeys handles the keys of an open completion menu,
+// returns true if the key was consumed
+func (m *Model) handleCompletionKeys(key tea.KeyMsg) bool {
+	if len(m.completions) == 0 {
+		return false
+	}
+
+	switch key.Type {
+	case tea.KeyTab:
+		m.completionIndex = (m.completionIndex + 1) % len(m.completions)
+	case tea.KeyShiftTab:
+		m.completionIndex = (m.completionIndex - 1 + len(m.completions)) % len(m.completions)
+	case tea.KeyEnter:
+		m.AcceptCompletion()
+	case tea.KeyEscape:
+		m.completions = nil
+	default:
+		return false
+	}
+
+	return true
+}
+
+// AcceptCompletion replaces the trigger and the word with the
+// selected completion, followed by a space
+func (m *Model) AcceptCompletion() {
+	if len(m.completions) == 0 {
+		return
+	}
+
+	insert := []rune(m.completions[m.completionIndex].Insert + " ")
+	line := m.lines[m.cursorLine]
+	line = slices.Delete(line, m.completionStart, m.cursorColumn)
+	m.lines[m.cursorLine] = slices.Insert(line, m.completionStart, insert...)
+	m.SetCursorColumn(m.completionStart + len(insert))
+
+	m.completions = nil
+	m.completionIndex = 0
+}
+
+// Completing returns true if the completion menu is open
+func (m *Model) Completing() bool {
+	return len(m.completions) != 0 && m.mode == InsertMode
+}
+
+func (m *Model) CompletionView() string {
+	if len(m.completions) == 0 || m.mode != InsertMode || !m.focus {
+		return ""
+	}
+
+	base := max(0, m.completionIndex-MaxVisibleCompletions+1)
+	upper := min(base+MaxVisibleCompletions, len(m.completions))
+
+	completionStyle := lipgloss.NewStyle().Width(m.width).
+		Background(colors.BackgroundDim).Foreground(colors.White).
+		Padding(0, 1)
+
+	lines := make([]string, 0, upper-base)
+	for i := base; i < upper; i++ {
+		style := completionStyle
+		if i == m.completionIndex {
+			style = style.Background(colors.BackgroundHighlight).Foreground(colors.Focus)
+		}
+		display := lipgloss.NewStyle().MaxWidth(m.width - 2).Render(m.completions[i].Display)
+		lines = append(lines, style.Render(display))
+	}
+
+	return strings.Join(lines, "\n")
+}
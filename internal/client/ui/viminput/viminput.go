@@ -79,6 +79,12 @@ type Model struct {
 	height    int
 	maxHeight int
 	offset    int
+
+	Completer         Completer
+	CompletionTrigger rune
+	completions       []Completion
+	completionIndex   int
+	completionStart   int
 }
 
 func New() Model {
@@ -109,6 +115,12 @@ func New() Model {
 		height:       1,
 		maxHeight:    -1,
 		offset:       0,
+
+		Completer:         nil,
+		CompletionTrigger: NullChar,
+		completions:       nil,
+		completionIndex:   0,
+		completionStart:   0,
 	}
 }
 
@@ -641,7 +653,12 @@ func (m *Model) handleNormalModeKeys(key tea.KeyMsg) {
 }
 
 func (m *Model) handleInsertModeKeys(key tea.KeyMsg) {
+	if m.handleCompletionKeys(key) {
+		return
+	}
+
 	if key.Type == tea.KeyEscape {
+		m.completions = nil
 		m.SetCursorColumn(m.cursorColumn - 1)
 		m.mode = NormalMode
 		return
@@ -661,6 +678,7 @@ func (m *Model) handleInsertModeKeys(key tea.KeyMsg) {
 			Paste: false,
 		})
 		m.Yank(paste)
+		m.updateCompletions()
 		return
 	}
 
@@ -675,6 +693,7 @@ func (m *Model) handleInsertModeKeys(key tea.KeyMsg) {
 		line := m.lines[m.cursorLine]
 		m.lines[m.cursorLine] = slices.Insert(line, m.cursorColumn, runes...)
 		m.SetCursorColumn(m.cursorColumn + len(runes))
+		m.updateCompletions()
 		return
 	}
 
@@ -693,6 +712,7 @@ func (m *Model) handleInsertModeKeys(key tea.KeyMsg) {
 			m.lines[m.cursorLine] = slices.Delete(line, m.cursorColumn-1, m.cursorColumn)
 			m.SetCursorColumn(m.cursorColumn - 1)
 		}
+		m.updateCompletions()
 		return
 	}
 
@@ -724,6 +744,9 @@ func (m *Model) handleInsertModeKeys(key tea.KeyMsg) {
 		line := m.lines[m.cursorLine]
 		m.lines[m.cursorLine] = slices.Insert(line, m.cursorColumn, rune(keyStr[0]))
 		m.SetCursorColumn(m.cursorColumn + 1)
+		m.updateCompletions()
+	} else {
+		m.completions = nil
 	}
 }
 
@@ -2219,6 +2242,8 @@ func (m *Model) Reset() {
 	m.imod = false
 	m.amod = false
 	m.offset = 0
+	m.completions = nil
+	m.completionIndex = 0
 }
 
 func (m *Model) SetInactive(inactive bool) {
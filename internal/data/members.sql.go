@@ -58,7 +58,7 @@ func (q *Queries) FilterUsersInNetwork(ctx context.Context, arg FilterUsersInNet
 
 const getBannedMembers = `-- name: GetBannedMembers :many
 SELECT
-  users.id, users.name, users.public_key, users.description, users.is_public_dm, users.is_deleted, users.last_activity,
+  users.id, users.name, users.public_key, users.description, users.is_public_dm, users.is_deleted, users.last_activity, users.status, users.is_dnd, users.is_presence_public, users.is_read_receipts,
   members.user_id, members.network_id, members.joined_at, members.is_member, members.is_admin, members.is_muted, members.is_banned, members.ban_reason
 FROM members
 JOIN users ON users.id = members.user_id
@@ -87,6 +87,10 @@ func (q *Queries) GetBannedMembers(ctx context.Context, networkID snowflake.ID)
 			&i.User.IsPublicDM,
 			&i.User.IsDeleted,
 			&i.User.LastActivity,
+			&i.User.Status,
+			&i.User.IsDND,
+			&i.User.IsPresencePublic,
+			&i.User.IsReadReceipts,
 			&i.Member.UserID,
 			&i.Member.NetworkID,
 			&i.Member.JoinedAt,
@@ -137,7 +141,7 @@ func (q *Queries) GetMemberById(ctx context.Context, arg GetMemberByIdParams) (M
 
 const getNetworkMembers = `-- name: GetNetworkMembers :many
 SELECT
-  users.id, users.name, users.public_key, users.description, users.is_public_dm, users.is_deleted, users.last_activity,
+  users.id, users.name, users.public_key, users.description, users.is_public_dm, users.is_deleted, users.last_activity, users.status, users.is_dnd, users.is_presence_public, users.is_read_receipts,
   members.user_id, members.network_id, members.joined_at, members.is_member, members.is_admin, members.is_muted, members.is_banned, members.ban_reason
 FROM members
 JOIN users ON users.id = members.user_id
@@ -166,6 +170,10 @@ func (q *Queries) GetNetworkMembers(ctx context.Context, networkID snowflake.ID)
 			&i.User.IsPublicDM,
 			&i.User.IsDeleted,
 			&i.User.LastActivity,
+			&i.User.Status,
+			&i.User.IsDND,
+			&i.User.IsPresencePublic,
+			&i.User.IsReadReceipts,
 			&i.Member.UserID,
 			&i.Member.NetworkID,
 			&i.Member.JoinedAt,
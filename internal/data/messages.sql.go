@@ -13,20 +13,24 @@ import (
 
 const createMessage = `-- name: CreateMessage :one
 INSERT INTO messages (
-  id, content, sender_id, frequency_id, receiver_id, ping
+  id, content, sender_id, frequency_id, receiver_id, group_id, ping, attachment_id, is_encrypted, signature
 ) VALUES (
-  ?, ?, ?, ?, ?, ?
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 )
-RETURNING id, sender_id, content, edited, frequency_id, receiver_id, ping
+RETURNING id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature
 `
 
 type CreateMessageParams struct {
-	ID          snowflake.ID
-	Content     string
-	SenderID    snowflake.ID
-	FrequencyID *snowflake.ID
-	ReceiverID  *snowflake.ID
-	Ping        *snowflake.ID
+	ID           snowflake.ID
+	Content      string
+	SenderID     snowflake.ID
+	FrequencyID  *snowflake.ID
+	ReceiverID   *snowflake.ID
+	GroupID      *snowflake.ID
+	Ping         *snowflake.ID
+	AttachmentID *snowflake.ID
+	IsEncrypted  bool
+	Signature    []byte
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
@@ -36,7 +40,11 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.SenderID,
 		arg.FrequencyID,
 		arg.ReceiverID,
+		arg.GroupID,
 		arg.Ping,
+		arg.AttachmentID,
+		arg.IsEncrypted,
+		arg.Signature,
 	)
 	var i Message
 	err := row.Scan(
@@ -46,7 +54,12 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.Edited,
 		&i.FrequencyID,
 		&i.ReceiverID,
+		&i.GroupID,
 		&i.Ping,
+		&i.PinnedAt,
+		&i.AttachmentID,
+		&i.IsEncrypted,
+		&i.Signature,
 	)
 	return i, err
 }
@@ -61,21 +74,40 @@ func (q *Queries) DeleteMessage(ctx context.Context, id snowflake.ID) error {
 	return err
 }
 
+const deleteMessageMentions = `-- name: DeleteMessageMentions :exec
+DELETE FROM message_mentions
+WHERE message_id = ?
+`
+
+func (q *Queries) DeleteMessageMentions(ctx context.Context, messageID snowflake.ID) error {
+	_, err := q.db.ExecContext(ctx, deleteMessageMentions, messageID)
+	return err
+}
+
 const editMessage = `-- name: EditMessage :one
 UPDATE messages SET
   edited = true,
-  content = ?
+  content = ?,
+  ping = ?,
+  signature = ?
 WHERE id = ?
-RETURNING id, sender_id, content, edited, frequency_id, receiver_id, ping
+RETURNING id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature
 `
 
 type EditMessageParams struct {
-	Content string
-	ID      snowflake.ID
+	Content   string
+	Ping      *snowflake.ID
+	Signature []byte
+	ID        snowflake.ID
 }
 
 func (q *Queries) EditMessage(ctx context.Context, arg EditMessageParams) (Message, error) {
-	row := q.db.QueryRowContext(ctx, editMessage, arg.Content, arg.ID)
+	row := q.db.QueryRowContext(ctx, editMessage,
+		arg.Content,
+		arg.Ping,
+		arg.Signature,
+		arg.ID,
+	)
 	var i Message
 	err := row.Scan(
 		&i.ID,
@@ -84,13 +116,18 @@ func (q *Queries) EditMessage(ctx context.Context, arg EditMessageParams) (Messa
 		&i.Edited,
 		&i.FrequencyID,
 		&i.ReceiverID,
+		&i.GroupID,
 		&i.Ping,
+		&i.PinnedAt,
+		&i.AttachmentID,
+		&i.IsEncrypted,
+		&i.Signature,
 	)
 	return i, err
 }
 
 const getDirectMessages = `-- name: GetDirectMessages :many
-SELECT id, sender_id, content, edited, frequency_id, receiver_id, ping FROM messages
+SELECT id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature FROM messages
 WHERE
   (sender_id = ?1 AND receiver_id = ?2) OR
   (sender_id = ?2 AND receiver_id = ?1)
@@ -118,7 +155,12 @@ func (q *Queries) GetDirectMessages(ctx context.Context, arg GetDirectMessagesPa
 			&i.Edited,
 			&i.FrequencyID,
 			&i.ReceiverID,
+			&i.GroupID,
 			&i.Ping,
+			&i.PinnedAt,
+			&i.AttachmentID,
+			&i.IsEncrypted,
+			&i.Signature,
 		); err != nil {
 			return nil, err
 		}
@@ -134,7 +176,7 @@ func (q *Queries) GetDirectMessages(ctx context.Context, arg GetDirectMessagesPa
 }
 
 const getFrequencyMessages = `-- name: GetFrequencyMessages :many
-SELECT id, sender_id, content, edited, frequency_id, receiver_id, ping FROM messages
+SELECT id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature FROM messages
 WHERE frequency_id = ?
 ORDER BY id
 `
@@ -155,7 +197,12 @@ func (q *Queries) GetFrequencyMessages(ctx context.Context, frequencyID *snowfla
 			&i.Edited,
 			&i.FrequencyID,
 			&i.ReceiverID,
+			&i.GroupID,
 			&i.Ping,
+			&i.PinnedAt,
+			&i.AttachmentID,
+			&i.IsEncrypted,
+			&i.Signature,
 		); err != nil {
 			return nil, err
 		}
@@ -171,7 +218,7 @@ func (q *Queries) GetFrequencyMessages(ctx context.Context, frequencyID *snowfla
 }
 
 const getMessageById = `-- name: GetMessageById :one
-SELECT id, sender_id, content, edited, frequency_id, receiver_id, ping FROM messages
+SELECT id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature FROM messages
 WHERE id = ?
 `
 
@@ -185,7 +232,161 @@ func (q *Queries) GetMessageById(ctx context.Context, id snowflake.ID) (Message,
 		&i.Edited,
 		&i.FrequencyID,
 		&i.ReceiverID,
+		&i.GroupID,
+		&i.Ping,
+		&i.PinnedAt,
+		&i.AttachmentID,
+		&i.IsEncrypted,
+		&i.Signature,
+	)
+	return i, err
+}
+
+const insertMessageMention = `-- name: InsertMessageMention :exec
+INSERT INTO message_mentions (
+  message_id, mention
+) VALUES (
+  ?, ?
+)
+`
+
+type InsertMessageMentionParams struct {
+	MessageID snowflake.ID
+	Mention   snowflake.ID
+}
+
+func (q *Queries) InsertMessageMention(ctx context.Context, arg InsertMessageMentionParams) error {
+	_, err := q.db.ExecContext(ctx, insertMessageMention, arg.MessageID, arg.Mention)
+	return err
+}
+
+const searchMessages = `-- name: SearchMessages :many
+SELECT messages.id, messages.sender_id, messages.content, messages.edited, messages.frequency_id, messages.receiver_id, messages.group_id, messages.ping, messages.pinned_at, messages.attachment_id, messages.is_encrypted, messages.signature FROM messages_fts
+JOIN messages ON messages.id = messages_fts.rowid
+WHERE messages_fts MATCH ?1
+AND messages.is_encrypted = false -- The server can't search ciphertext
+AND (
+  (messages.frequency_id IS NOT NULL AND EXISTS (
+    SELECT 1 FROM frequencies f
+    JOIN members m ON m.network_id = f.network_id
+    WHERE f.id = messages.frequency_id AND m.user_id = ?2
+    AND m.is_member = true AND (f.perms != 0 OR m.is_admin = true)
+  )) OR
+  (messages.receiver_id IS NOT NULL AND
+    (messages.sender_id = ?2 OR messages.receiver_id = ?2)) OR
+  (messages.group_id IN (
+    SELECT group_id FROM group_signal_members WHERE user_id = ?2
+  ))
+)
+AND (?3 IS NULL OR messages.frequency_id = ?3)
+AND (?4 IS NULL OR messages.frequency_id IN (
+  SELECT id FROM frequencies WHERE network_id = ?4
+))
+AND (?5 IS NULL OR
+  (messages.sender_id = ?2 AND messages.receiver_id = ?5) OR
+  (messages.sender_id = ?5 AND messages.receiver_id = ?2) OR
+  (messages.group_id = ?5 AND messages.group_id IN (
+    SELECT group_id FROM group_signal_members WHERE user_id = ?2
+  ))
+)
+AND (?6 IS NULL OR messages.sender_id = ?6)
+AND (?7 IS NULL OR messages.id >= ?7)
+AND (?8 IS NULL OR messages.id < ?8)
+AND (CAST(?9 AS BOOLEAN) = false OR EXISTS (
+  SELECT 1 FROM message_mentions mm WHERE mm.message_id = messages.id
+))
+ORDER BY messages.id DESC
+LIMIT ?10
+`
+
+type SearchMessagesParams struct {
+	Query       string
+	UserID      snowflake.ID
+	FrequencyID *snowflake.ID
+	NetworkID   *snowflake.ID
+	PeerID      *snowflake.ID
+	SenderID    *snowflake.ID
+	After       *snowflake.ID
+	Before      *snowflake.ID
+	HasMention  bool
+	MaxResults  int64
+}
+
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, searchMessages,
+		arg.Query,
+		arg.UserID,
+		arg.FrequencyID,
+		arg.NetworkID,
+		arg.PeerID,
+		arg.SenderID,
+		arg.After,
+		arg.Before,
+		arg.HasMention,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SenderID,
+			&i.Content,
+			&i.Edited,
+			&i.FrequencyID,
+			&i.ReceiverID,
+			&i.GroupID,
+			&i.Ping,
+			&i.PinnedAt,
+			&i.AttachmentID,
+			&i.IsEncrypted,
+			&i.Signature,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setMessagePinned = `-- name: SetMessagePinned :one
+UPDATE messages SET
+  pinned_at = ?
+WHERE id = ?
+RETURNING id, sender_id, content, edited, frequency_id, receiver_id, group_id, ping, pinned_at, attachment_id, is_encrypted, signature
+`
+
+type SetMessagePinnedParams struct {
+	PinnedAt *int64
+	ID       snowflake.ID
+}
+
+func (q *Queries) SetMessagePinned(ctx context.Context, arg SetMessagePinnedParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, setMessagePinned, arg.PinnedAt, arg.ID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.SenderID,
+		&i.Content,
+		&i.Edited,
+		&i.FrequencyID,
+		&i.ReceiverID,
+		&i.GroupID,
 		&i.Ping,
+		&i.PinnedAt,
+		&i.AttachmentID,
+		&i.IsEncrypted,
+		&i.Signature,
 	)
 	return i, err
 }
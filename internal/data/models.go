@@ -5,10 +5,18 @@
 package data
 
 import (
-	"crypto/ed25519"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
+type Attachment struct {
+	ID         snowflake.ID
+	UploaderID snowflake.ID
+	Name       string
+	Size       int64
+	Hash       string
+}
+
 type BlockedUser struct {
 	BlockingUserID snowflake.ID
 	BlockedUserID  snowflake.ID
@@ -22,6 +30,15 @@ type DeviceAnalytic struct {
 	Colorterm *string
 }
 
+type DeviceKey struct {
+	ID           snowflake.ID
+	UserID       snowflake.ID
+	PublicKey    sshkey.PublicKey
+	Name         string
+	AuthorizedBy sshkey.PublicKey
+	Signature    []byte
+}
+
 type Frequency struct {
 	ID        snowflake.ID
 	NetworkID snowflake.ID
@@ -31,6 +48,24 @@ type Frequency struct {
 	Position  int64
 }
 
+type GroupSignal struct {
+	ID   snowflake.ID
+	Name string
+}
+
+type GroupSignalMember struct {
+	GroupID snowflake.ID
+	UserID  snowflake.ID
+}
+
+type KeyRotation struct {
+	ID           snowflake.ID
+	UserID       snowflake.ID
+	OldPublicKey sshkey.PublicKey
+	NewPublicKey sshkey.PublicKey
+	Signature    []byte
+}
+
 type LastReadMessage struct {
 	UserID   snowflake.ID
 	SourceID snowflake.ID
@@ -49,13 +84,30 @@ type Member struct {
 }
 
 type Message struct {
-	ID          snowflake.ID
-	SenderID    snowflake.ID
-	Content     string
-	Edited      bool
-	FrequencyID *snowflake.ID
-	ReceiverID  *snowflake.ID
-	Ping        *snowflake.ID
+	ID           snowflake.ID
+	SenderID     snowflake.ID
+	Content      string
+	Edited       bool
+	FrequencyID  *snowflake.ID
+	ReceiverID   *snowflake.ID
+	GroupID      *snowflake.ID
+	Ping         *snowflake.ID
+	PinnedAt     *int64
+	AttachmentID *snowflake.ID
+	IsEncrypted  bool
+	Signature    []byte
+}
+
+type MessageMention struct {
+	MessageID snowflake.ID
+	Mention   snowflake.ID
+}
+
+type MessageRequest struct {
+	SenderID   snowflake.ID
+	ReceiverID snowflake.ID
+	MessageID  snowflake.ID
+	IsIgnored  bool
 }
 
 type Network struct {
@@ -71,17 +123,21 @@ type Network struct {
 type TrustedUser struct {
 	TrustingUserID   snowflake.ID
 	TrustedUserID    snowflake.ID
-	TrustedPublicKey ed25519.PublicKey
+	TrustedPublicKey sshkey.PublicKey
 }
 
 type User struct {
-	ID           snowflake.ID
-	Name         string
-	PublicKey    ed25519.PublicKey
-	Description  string
-	IsPublicDM   bool
-	IsDeleted    bool
-	LastActivity *int64
+	ID               snowflake.ID
+	Name             string
+	PublicKey        sshkey.PublicKey
+	Description      string
+	IsPublicDM       bool
+	IsDeleted        bool
+	LastActivity     *int64
+	Status           string
+	IsDND            bool
+	IsPresencePublic bool
+	IsReadReceipts   bool
 }
 
 type UserData struct {
@@ -11,6 +11,82 @@ import (
 	"github.com/kyren223/eko/pkg/snowflake"
 )
 
+const getReadPositions = `-- name: GetReadPositions :many
+SELECT last_read_messages.source_id, last_read_messages.last_read
+FROM last_read_messages
+JOIN users ON users.id = last_read_messages.source_id
+WHERE last_read_messages.user_id = ? AND users.is_read_receipts = true
+`
+
+type GetReadPositionsRow struct {
+	SourceID snowflake.ID
+	LastRead int64
+}
+
+// Read positions of the given user in their DMs
+// with users that have read receipts enabled
+func (q *Queries) GetReadPositions(ctx context.Context, userID snowflake.ID) ([]GetReadPositionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReadPositions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReadPositionsRow
+	for rows.Next() {
+		var i GetReadPositionsRow
+		if err := rows.Scan(&i.SourceID, &i.LastRead); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReadReceipts = `-- name: GetReadReceipts :many
+SELECT last_read_messages.user_id, last_read_messages.last_read
+FROM last_read_messages
+JOIN users AS readers ON readers.id = last_read_messages.user_id
+JOIN users AS self ON self.id = last_read_messages.source_id
+WHERE last_read_messages.source_id = ?
+  AND readers.is_read_receipts = true AND self.is_read_receipts = true
+`
+
+type GetReadReceiptsRow struct {
+	UserID   snowflake.ID
+	LastRead int64
+}
+
+// Read positions of other users in their DM with the given user,
+// only if both users have read receipts enabled
+func (q *Queries) GetReadReceipts(ctx context.Context, sourceID snowflake.ID) ([]GetReadReceiptsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReadReceipts, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReadReceiptsRow
+	for rows.Next() {
+		var i GetReadReceiptsRow
+		if err := rows.Scan(&i.UserID, &i.LastRead); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const insertLastReadMessage = `-- name: InsertLastReadMessage :exec
 INSERT OR IGNORE INTO last_read_messages (
   user_id, source_id, last_read
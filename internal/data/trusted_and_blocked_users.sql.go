@@ -8,8 +8,8 @@ package data
 import (
 	"context"
 
-	"crypto/ed25519"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 const blockUser = `-- name: BlockUser :exec
@@ -94,9 +94,9 @@ type GetTrustedPublicKeyParams struct {
 	TrustedUserID  snowflake.ID
 }
 
-func (q *Queries) GetTrustedPublicKey(ctx context.Context, arg GetTrustedPublicKeyParams) (ed25519.PublicKey, error) {
+func (q *Queries) GetTrustedPublicKey(ctx context.Context, arg GetTrustedPublicKeyParams) (sshkey.PublicKey, error) {
 	row := q.db.QueryRowContext(ctx, getTrustedPublicKey, arg.TrustingUserID, arg.TrustedUserID)
-	var trusted_public_key ed25519.PublicKey
+	var trusted_public_key sshkey.PublicKey
 	err := row.Scan(&trusted_public_key)
 	return trusted_public_key, err
 }
@@ -108,7 +108,7 @@ WHERE trusting_user_id = ?
 
 type GetTrustedUsersRow struct {
 	TrustedUserID    snowflake.ID
-	TrustedPublicKey ed25519.PublicKey
+	TrustedPublicKey sshkey.PublicKey
 }
 
 func (q *Queries) GetTrustedUsers(ctx context.Context, trustingUserID snowflake.ID) ([]GetTrustedUsersRow, error) {
@@ -134,6 +134,34 @@ func (q *Queries) GetTrustedUsers(ctx context.Context, trustingUserID snowflake.
 	return items, nil
 }
 
+const getTrustingUsers = `-- name: GetTrustingUsers :many
+SELECT trusting_user_id FROM trusted_users
+WHERE trusted_user_id = ?
+`
+
+func (q *Queries) GetTrustingUsers(ctx context.Context, trustedUserID snowflake.ID) ([]snowflake.ID, error) {
+	rows, err := q.db.QueryContext(ctx, getTrustingUsers, trustedUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []snowflake.ID
+	for rows.Next() {
+		var trusting_user_id snowflake.ID
+		if err := rows.Scan(&trusting_user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, trusting_user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const isUserBlocked = `-- name: IsUserBlocked :one
 SELECT blocked_user_id FROM blocked_users
 WHERE blocking_user_id = ? AND blocked_user_id = ?
@@ -160,7 +188,7 @@ INSERT OR IGNORE INTO trusted_users (
 type TrustUserParams struct {
 	TrustingUserID   snowflake.ID
 	TrustedUserID    snowflake.ID
-	TrustedPublicKey ed25519.PublicKey
+	TrustedPublicKey sshkey.PublicKey
 }
 
 func (q *Queries) TrustUser(ctx context.Context, arg TrustUserParams) error {
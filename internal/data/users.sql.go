@@ -9,8 +9,8 @@ import (
 	"context"
 	"strings"
 
-	"crypto/ed25519"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -19,13 +19,13 @@ INSERT INTO users (
 ) VALUES (
   ?, ?, ?
 )
-RETURNING id, name, public_key, description, is_public_dm, is_deleted, last_activity
+RETURNING id, name, public_key, description, is_public_dm, is_deleted, last_activity, status, is_dnd, is_presence_public, is_read_receipts
 `
 
 type CreateUserParams struct {
 	ID        snowflake.ID
 	Name      string
-	PublicKey ed25519.PublicKey
+	PublicKey sshkey.PublicKey
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -39,6 +39,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.IsPublicDM,
 		&i.IsDeleted,
 		&i.LastActivity,
+		&i.Status,
+		&i.IsDND,
+		&i.IsPresencePublic,
+		&i.IsReadReceipts,
 	)
 	return i, err
 }
@@ -50,7 +54,7 @@ WHERE id = ? AND is_deleted = false
 `
 
 type DeleteUserParams struct {
-	PublicKey ed25519.PublicKey
+	PublicKey sshkey.PublicKey
 	ID        snowflake.ID
 }
 
@@ -59,8 +63,48 @@ func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) error {
 	return err
 }
 
+const getPresenceAudience = `-- name: GetPresenceAudience :many
+SELECT others.user_id FROM members AS self
+JOIN members AS others ON others.network_id = self.network_id
+WHERE self.user_id = ?1 AND self.is_member = true
+  AND others.is_member = true AND others.user_id != ?1
+UNION
+SELECT trusted_user_id FROM trusted_users
+WHERE trusting_user_id = ?1
+EXCEPT
+SELECT blocked_user_id FROM blocked_users
+WHERE blocking_user_id = ?1
+EXCEPT
+SELECT blocking_user_id FROM blocked_users
+WHERE blocked_user_id = ?1
+`
+
+// Users that may see the given user's presence
+func (q *Queries) GetPresenceAudience(ctx context.Context, userID snowflake.ID) ([]snowflake.ID, error) {
+	rows, err := q.db.QueryContext(ctx, getPresenceAudience, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []snowflake.ID
+	for rows.Next() {
+		var user_id snowflake.ID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserById = `-- name: GetUserById :one
-SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity FROM users
+SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity, status, is_dnd, is_presence_public, is_read_receipts FROM users
 WHERE id = ? AND is_deleted = false
 `
 
@@ -75,16 +119,20 @@ func (q *Queries) GetUserById(ctx context.Context, id snowflake.ID) (User, error
 		&i.IsPublicDM,
 		&i.IsDeleted,
 		&i.LastActivity,
+		&i.Status,
+		&i.IsDND,
+		&i.IsPresencePublic,
+		&i.IsReadReceipts,
 	)
 	return i, err
 }
 
 const getUserByPublicKey = `-- name: GetUserByPublicKey :one
-SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity FROM users
+SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity, status, is_dnd, is_presence_public, is_read_receipts FROM users
 WHERE public_key = ?
 `
 
-func (q *Queries) GetUserByPublicKey(ctx context.Context, publicKey ed25519.PublicKey) (User, error) {
+func (q *Queries) GetUserByPublicKey(ctx context.Context, publicKey sshkey.PublicKey) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUserByPublicKey, publicKey)
 	var i User
 	err := row.Scan(
@@ -95,6 +143,10 @@ func (q *Queries) GetUserByPublicKey(ctx context.Context, publicKey ed25519.Publ
 		&i.IsPublicDM,
 		&i.IsDeleted,
 		&i.LastActivity,
+		&i.Status,
+		&i.IsDND,
+		&i.IsPresencePublic,
+		&i.IsReadReceipts,
 	)
 	return i, err
 }
@@ -112,7 +164,7 @@ func (q *Queries) GetUserData(ctx context.Context, userID snowflake.ID) (string,
 }
 
 const getUsersByIds = `-- name: GetUsersByIds :many
-SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity FROM users
+SELECT id, name, public_key, description, is_public_dm, is_deleted, last_activity, status, is_dnd, is_presence_public, is_read_receipts FROM users
 WHERE id IN (/*SLICE:ids*/?)
 `
 
@@ -143,6 +195,10 @@ func (q *Queries) GetUsersByIds(ctx context.Context, ids []snowflake.ID) ([]User
 			&i.IsPublicDM,
 			&i.IsDeleted,
 			&i.LastActivity,
+			&i.Status,
+			&i.IsDND,
+			&i.IsPresencePublic,
+			&i.IsReadReceipts,
 		); err != nil {
 			return nil, err
 		}
@@ -157,6 +213,46 @@ func (q *Queries) GetUsersByIds(ctx context.Context, ids []snowflake.ID) ([]User
 	return items, nil
 }
 
+const getVisiblePresenceUsers = `-- name: GetVisiblePresenceUsers :many
+SELECT others.user_id FROM members AS self
+JOIN members AS others ON others.network_id = self.network_id
+WHERE self.user_id = ?1 AND self.is_member = true
+  AND others.is_member = true AND others.user_id != ?1
+UNION
+SELECT trusting_user_id FROM trusted_users
+WHERE trusted_user_id = ?1
+EXCEPT
+SELECT blocked_user_id FROM blocked_users
+WHERE blocking_user_id = ?1
+EXCEPT
+SELECT blocking_user_id FROM blocked_users
+WHERE blocked_user_id = ?1
+`
+
+// Users whose presence the given user may see
+func (q *Queries) GetVisiblePresenceUsers(ctx context.Context, userID snowflake.ID) ([]snowflake.ID, error) {
+	rows, err := q.db.QueryContext(ctx, getVisiblePresenceUsers, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []snowflake.ID
+	for rows.Next() {
+		var user_id snowflake.ID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const setUserData = `-- name: SetUserData :one
 INSERT INTO user_data (
   user_id, data
@@ -184,16 +280,22 @@ func (q *Queries) SetUserData(ctx context.Context, arg SetUserDataParams) (UserD
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users SET
-  name = ?, description = ?, is_public_dm = ?
+  name = ?, description = ?, is_public_dm = ?,
+  status = ?, is_dnd = ?, is_presence_public = ?,
+  is_read_receipts = ?
 WHERE id = ?
-RETURNING id, name, public_key, description, is_public_dm, is_deleted, last_activity
+RETURNING id, name, public_key, description, is_public_dm, is_deleted, last_activity, status, is_dnd, is_presence_public, is_read_receipts
 `
 
 type UpdateUserParams struct {
-	Name        string
-	Description string
-	IsPublicDM  bool
-	ID          snowflake.ID
+	Name             string
+	Description      string
+	IsPublicDM       bool
+	Status           string
+	IsDND            bool
+	IsPresencePublic bool
+	IsReadReceipts   bool
+	ID               snowflake.ID
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -201,6 +303,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.Name,
 		arg.Description,
 		arg.IsPublicDM,
+		arg.Status,
+		arg.IsDND,
+		arg.IsPresencePublic,
+		arg.IsReadReceipts,
 		arg.ID,
 	)
 	var i User
@@ -212,6 +318,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.IsPublicDM,
 		&i.IsDeleted,
 		&i.LastActivity,
+		&i.Status,
+		&i.IsDND,
+		&i.IsPresencePublic,
+		&i.IsReadReceipts,
 	)
 	return i, err
 }
@@ -0,0 +1,116 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package packet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const (
+	LinkScheme    = "eko://"
+	LinkSeparator = "/"
+)
+
+// Link references a network, a frequency inside of it, or a message
+// inside of that frequency, it's formatted as
+// eko://<network id>[/<frequency id>[/<message id>]].
+// Start and End are byte offsets so content[Start:End] is the link.
+type Link struct {
+	Start     int
+	End       int
+	Network   snowflake.ID
+	Frequency *snowflake.ID
+	Message   *snowflake.ID
+}
+
+func (l Link) String() string {
+	var builder strings.Builder
+	builder.WriteString(LinkScheme)
+	builder.WriteString(l.Network.String())
+	if l.Frequency != nil {
+		builder.WriteString(LinkSeparator)
+		builder.WriteString(l.Frequency.String())
+		if l.Message != nil {
+			builder.WriteString(LinkSeparator)
+			builder.WriteString(l.Message.String())
+		}
+	}
+	return builder.String()
+}
+
+// FindLinks returns all the links in the content in order.
+// Links that are malformed are ignored.
+func FindLinks(content string) []Link {
+	var links []Link
+
+	offset := 0
+	for {
+		index := strings.Index(content[offset:], LinkScheme)
+		if index == -1 {
+			break
+		}
+		start := offset + index
+		end := start + len(LinkScheme)
+		for end < len(content) && isLinkChar(content[end]) {
+			end++
+		}
+		offset = end
+
+		link, ok := parseLink(content[start+len(LinkScheme) : end])
+		if !ok {
+			continue
+		}
+		link.Start = start
+		link.End = end
+		links = append(links, link)
+	}
+
+	return links
+}
+
+func parseLink(s string) (Link, bool) {
+	s = strings.TrimSuffix(s, LinkSeparator)
+	parts := strings.Split(s, LinkSeparator)
+	if len(parts) > 3 {
+		return Link{}, false
+	}
+
+	ids := make([]snowflake.ID, len(parts))
+	for i, part := range parts {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return Link{}, false
+		}
+		ids[i] = snowflake.ID(id)
+	}
+
+	link := Link{Network: ids[0]}
+	if len(ids) >= 2 {
+		link.Frequency = &ids[1]
+	}
+	if len(ids) == 3 {
+		link.Message = &ids[2]
+	}
+	return link, true
+}
+
+func isLinkChar(c byte) bool {
+	return ('0' <= c && c <= '9') || c == LinkSeparator[0]
+}
@@ -0,0 +1,116 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package packet
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const (
+	MentionPrefix   = "@"
+	MentionEveryone = "everyone"
+	MentionAdmins   = "admins"
+)
+
+// Mention is a single mention token inside of a message's content,
+// Start and End are byte offsets so content[Start:End] is the token
+type Mention struct {
+	Start int
+	End   int
+	ID    snowflake.ID
+}
+
+// FindMentions returns all mention tokens in the content in order.
+// A mention is either @everyone, @admins or @<user id>, and it must
+// not be preceded by a keyword character (so emails aren't mentions).
+func FindMentions(content string) []Mention {
+	var mentions []Mention
+
+	for i := 0; i < len(content); i++ {
+		if content[i] != MentionPrefix[0] {
+			continue
+		}
+		if i != 0 && isMentionChar(content[i-1]) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(content) && isMentionChar(content[end]) {
+			end++
+		}
+
+		value := content[i+1 : end]
+		switch value {
+		case MentionEveryone:
+			mentions = append(mentions, Mention{Start: i, End: end, ID: PingEveryone})
+		case MentionAdmins:
+			mentions = append(mentions, Mention{Start: i, End: end, ID: PingAdmins})
+		default:
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err == nil && snowflake.ID(id) > PingAdmins {
+				mentions = append(mentions, Mention{Start: i, End: end, ID: snowflake.ID(id)})
+			}
+		}
+
+		i = end - 1
+	}
+
+	return mentions
+}
+
+// ParseMentions returns the unique mentioned ids in the content
+func ParseMentions(content string) []snowflake.ID {
+	var ids []snowflake.ID
+	for _, mention := range FindMentions(content) {
+		if !slices.Contains(ids, mention.ID) {
+			ids = append(ids, mention.ID)
+		}
+	}
+	return ids
+}
+
+// WithLegacyPing returns the content with its message's ping mentioned
+// at the start, messages sent before mentions were part of the content
+// only have a ping. Content that already mentions the ping is unchanged.
+func WithLegacyPing(content string, ping *snowflake.ID) string {
+	if ping == nil || slices.Contains(ParseMentions(content), *ping) {
+		return content
+	}
+	return MentionToken(*ping) + " " + content
+}
+
+// MentionToken returns the token that mentions the given id
+func MentionToken(id snowflake.ID) string {
+	switch id {
+	case PingEveryone:
+		return MentionPrefix + MentionEveryone
+	case PingAdmins:
+		return MentionPrefix + MentionAdmins
+	default:
+		return MentionPrefix + id.String()
+	}
+}
+
+func isMentionChar(c byte) bool {
+	alphaLower := 'a' <= c && c <= 'z'
+	alphaUpper := 'A' <= c && c <= 'Z'
+	numeric := '0' <= c && c <= '9'
+	return alphaLower || alphaUpper || numeric || c == '_'
+}
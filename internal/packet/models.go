@@ -30,6 +30,24 @@ const (
 	MaxUserDescriptionBytes = 200
 	MaxBanReasonBytes       = 64
 	MaxUsersInGetUsers      = 64
+	MaxMentions             = 20
+	MaxSearchQueryBytes     = 256
+	MaxSearchResults        = 50
+	MaxAttachmentNameBytes  = 128
+	AttachmentChunkBytes    = 32 * 1024
+	MaxUserStatusBytes      = 64
+	MaxGroupSignalNameBytes = 32
+	MinGroupSignalMembers   = 3
+	MaxGroupSignalMembers   = 10
+	MaxDeviceNameBytes      = 32
+	MaxDevices              = 10
+)
+
+const (
+	// XChaCha20-Poly1305 nonce and tag
+	EncryptionOverheadBytes = 24 + 16
+	// Base64 of the nonce, ciphertext and tag of a max length message
+	MaxEncryptedMessageBytes = (EncryptionOverheadBytes + MaxMessageBytes + 2) / 3 * 4
 )
 
 const (
@@ -39,6 +57,14 @@ const (
 	PermMax
 )
 
+const (
+	PresenceOffline = 0 + iota
+	PresenceOnline
+	PresenceIdle
+	PresenceDoNotDisturb
+	PresenceMax
+)
+
 const (
 	PingEveryone = snowflake.ID(0)
 	PingAdmins   = snowflake.ID(1)
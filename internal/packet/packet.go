@@ -116,6 +116,40 @@ const (
 
 	PacketDeviceAnalytics
 
+	PacketPinMessage
+
+	PacketSearchMessages
+	PacketSearchResults
+
+	PacketUploadAttachment
+	PacketAttachmentChunk
+	PacketAttachmentsInfo
+
+	PacketViewChat
+	PacketTyping
+
+	PacketPresenceInfo
+	PacketReadReceiptsInfo
+
+	PacketCreateGroupSignal
+	PacketAddGroupSignalMember
+	PacketLeaveGroupSignal
+	PacketGroupSignalsInfo
+
+	PacketIgnoreMessageRequest
+	PacketMessageRequestsInfo
+
+	PacketRotateKey
+	PacketKeyRotationsInfo
+
+	PacketAuthorizeDevice
+	PacketRevokeDevice
+	PacketDevicesInfo
+
+	PacketGetSessions
+	PacketTerminateSession
+	PacketSessionsInfo
+
 	PacketMax
 )
 
@@ -168,6 +202,39 @@ var packetNames = map[PacketType]string{
 	PacketUsersInfo: "PacketUsersInfo",
 
 	PacketDeviceAnalytics: "PacketDeviceAnalytics",
+
+	PacketPinMessage: "PacketPinMessage",
+
+	PacketSearchMessages: "PacketSearchMessages",
+	PacketSearchResults:  "PacketSearchResults",
+
+	PacketUploadAttachment: "PacketUploadAttachment",
+	PacketAttachmentChunk:  "PacketAttachmentChunk",
+	PacketAttachmentsInfo:  "PacketAttachmentsInfo",
+
+	PacketViewChat: "PacketViewChat",
+	PacketTyping:   "PacketTyping",
+
+	PacketPresenceInfo:     "PacketPresenceInfo",
+	PacketReadReceiptsInfo: "PacketReadReceiptsInfo",
+
+	PacketCreateGroupSignal:    "PacketCreateGroupSignal",
+	PacketAddGroupSignalMember: "PacketAddGroupSignalMember",
+	PacketLeaveGroupSignal:     "PacketLeaveGroupSignal",
+	PacketGroupSignalsInfo:     "PacketGroupSignalsInfo",
+
+	PacketIgnoreMessageRequest: "PacketIgnoreMessageRequest",
+	PacketMessageRequestsInfo:  "PacketMessageRequestsInfo",
+
+	PacketRotateKey:        "PacketRotateKey",
+	PacketKeyRotationsInfo: "PacketKeyRotationsInfo",
+
+	PacketAuthorizeDevice:  "PacketAuthorizeDevice",
+	PacketRevokeDevice:     "PacketRevokeDevice",
+	PacketDevicesInfo:      "PacketDevicesInfo",
+	PacketGetSessions:      "PacketGetSessions",
+	PacketTerminateSession: "PacketTerminateSession",
+	PacketSessionsInfo:     "PacketSessionsInfo",
 }
 
 func init() {
@@ -382,6 +449,61 @@ func (p Packet) DecodedPayload() (Payload, error) {
 	case PacketDeviceAnalytics:
 		payload = &DeviceAnalytics{}
 
+	case PacketPinMessage:
+		payload = &PinMessage{}
+
+	case PacketSearchMessages:
+		payload = &SearchMessages{}
+	case PacketSearchResults:
+		payload = &SearchResults{}
+
+	case PacketUploadAttachment:
+		payload = &UploadAttachment{}
+	case PacketAttachmentChunk:
+		payload = &AttachmentChunk{}
+	case PacketAttachmentsInfo:
+		payload = &AttachmentsInfo{}
+
+	case PacketViewChat:
+		payload = &ViewChat{}
+	case PacketTyping:
+		payload = &Typing{}
+
+	case PacketPresenceInfo:
+		payload = &PresenceInfo{}
+	case PacketReadReceiptsInfo:
+		payload = &ReadReceiptsInfo{}
+
+	case PacketCreateGroupSignal:
+		payload = &CreateGroupSignal{}
+	case PacketAddGroupSignalMember:
+		payload = &AddGroupSignalMember{}
+	case PacketLeaveGroupSignal:
+		payload = &LeaveGroupSignal{}
+	case PacketGroupSignalsInfo:
+		payload = &GroupSignalsInfo{}
+
+	case PacketIgnoreMessageRequest:
+		payload = &IgnoreMessageRequest{}
+	case PacketMessageRequestsInfo:
+		payload = &MessageRequestsInfo{}
+	case PacketRotateKey:
+		payload = &RotateKey{}
+	case PacketKeyRotationsInfo:
+		payload = &KeyRotationsInfo{}
+	case PacketAuthorizeDevice:
+		payload = &AuthorizeDevice{}
+	case PacketRevokeDevice:
+		payload = &RevokeDevice{}
+	case PacketDevicesInfo:
+		payload = &DevicesInfo{}
+	case PacketGetSessions:
+		payload = &GetSessions{}
+	case PacketTerminateSession:
+		payload = &TerminateSession{}
+	case PacketSessionsInfo:
+		payload = &SessionsInfo{}
+
 	default:
 		assert.Assert(!p.Type().IsSupported(), "supported PackeType wasn't handled", "type", p.Type())
 		return nil, fmt.Errorf("unsupported PackeType: %v", p.Type().String())
@@ -129,3 +129,50 @@ func doesChannelHaveValue[T any](c <-chan T) bool {
 		return false
 	}
 }
+
+func TestFindMentions(t *testing.T) {
+	content := "@everyone hi @123 and @admins, mail a@456 @1 @ @12x\n@789"
+	mentions := FindMentions(content)
+
+	tokens := []string{}
+	ids := []snowflake.ID{}
+	for _, mention := range mentions {
+		tokens = append(tokens, content[mention.Start:mention.End])
+		ids = append(ids, mention.ID)
+	}
+
+	require.Equal(t, []string{"@everyone", "@123", "@admins", "@789"}, tokens)
+	require.Equal(t, []snowflake.ID{PingEveryone, 123, PingAdmins, 789}, ids)
+
+	require.Equal(t, []snowflake.ID{123}, ParseMentions("@123 @123"))
+	require.Equal(t, "@everyone", MentionToken(PingEveryone))
+	require.Equal(t, "@123", MentionToken(123))
+}
+
+func TestWithLegacyPing(t *testing.T) {
+	ping := snowflake.ID(123)
+	everyone := PingEveryone
+
+	require.Equal(t, "hi", WithLegacyPing("hi", nil))
+	require.Equal(t, "@123 hi", WithLegacyPing("hi", &ping))
+	require.Equal(t, "@everyone hi", WithLegacyPing("hi", &everyone))
+	require.Equal(t, "hi @123", WithLegacyPing("hi @123", &ping))
+	require.Equal(t, "@123 hi @456", WithLegacyPing("hi @456", &ping))
+}
+
+func TestFindLinks(t *testing.T) {
+	content := "see eko://1/2/3, eko://4/ and eko://5/6 but not eko://x or eko://1/2/3/4"
+	links := FindLinks(content)
+
+	tokens := []string{}
+	for _, link := range links {
+		tokens = append(tokens, content[link.Start:link.End])
+	}
+	require.Equal(t, []string{"eko://1/2/3", "eko://4/", "eko://5/6"}, tokens)
+
+	require.Equal(t, "eko://1/2/3", links[0].String())
+	require.Equal(t, "eko://4", links[1].String())
+	require.Nil(t, links[1].Frequency)
+	require.Equal(t, snowflake.ID(6), *links[2].Frequency)
+	require.Nil(t, links[2].Message)
+}
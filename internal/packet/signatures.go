@@ -0,0 +1,73 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package packet
+
+import (
+	"encoding/binary"
+
+	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
+)
+
+// MessageSignatureContext separates message signatures
+// from any other data signed by the same key
+const MessageSignatureContext = "eko message signature v1\x00"
+
+// KeyRotationSignatureContext separates key rotation signatures
+// from any other data signed by the same key
+const KeyRotationSignatureContext = "eko key rotation v1\x00"
+
+// DeviceSignatureContext separates device authorization signatures
+// from any other data signed by the same key
+const DeviceSignatureContext = "eko device authorization v1\x00"
+
+// MessageSignaturePayload returns the bytes a message's sender signs.
+// The chat is the frequency or receiver the message was sent to, so a signed
+// message can't be moved to another chat, attachment is 0 if there is none.
+func MessageSignaturePayload(chat snowflake.ID, attachment *snowflake.ID, content string) []byte {
+	payload := make([]byte, 0, len(MessageSignatureContext)+16+len(content))
+	payload = append(payload, MessageSignatureContext...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(chat))
+	if attachment != nil {
+		payload = binary.BigEndian.AppendUint64(payload, uint64(*attachment))
+	} else {
+		payload = binary.BigEndian.AppendUint64(payload, 0)
+	}
+	payload = append(payload, content...)
+	return payload
+}
+
+// KeyRotationSignaturePayload returns the bytes both keys sign
+// when the user rotates from the old key to the new one
+func KeyRotationSignaturePayload(user snowflake.ID, oldKey, newKey sshkey.PublicKey) []byte {
+	payload := make([]byte, 0, len(KeyRotationSignatureContext)+8+len(oldKey)+len(newKey))
+	payload = append(payload, KeyRotationSignatureContext...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(user))
+	payload = append(payload, oldKey.Compact()...)
+	payload = append(payload, newKey.Compact()...)
+	return payload
+}
+
+// DeviceSignaturePayload returns the bytes an authorized key signs
+// when it authorizes a new device key for the user
+func DeviceSignaturePayload(user snowflake.ID, device sshkey.PublicKey) []byte {
+	payload := make([]byte, 0, len(DeviceSignatureContext)+8+len(device))
+	payload = append(payload, DeviceSignatureContext...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(user))
+	payload = append(payload, device.Compact()...)
+	return payload
+}
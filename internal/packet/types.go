@@ -17,16 +17,27 @@
 package packet
 
 import (
-	"crypto/ed25519"
+	"log/slog"
+	"time"
 
 	"github.com/kyren223/eko/internal/data"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 type Error struct {
 	Error string
+	// How long to wait before retrying, only set when rate limited
+	RetryAfter time.Duration
 }
 
+// Errors sent right before the server closes an authenticated session,
+// the client shouldn't reconnect after receiving one of them
+const (
+	ErrDeviceRevoked     = "this device was revoked, closing this connection"
+	ErrSessionTerminated = "this session was signed out from another session, closing this connection"
+)
+
 func (m *Error) Type() PacketType {
 	return PacketError
 }
@@ -153,8 +164,19 @@ func (m *FrequenciesInfo) Type() PacketType {
 type SendMessage struct {
 	ReceiverID  *snowflake.ID
 	FrequencyID *snowflake.ID
+	Attachment  *snowflake.ID
 	Content     string
-	Ping        *snowflake.ID
+
+	// Deprecated: mentions are part of the content, see [ParseMentions].
+	// Set to the first mention for older servers, treated as a mention if
+	// it's not in the content
+	Ping *snowflake.ID
+
+	// Encrypted DMs contain the base64 encoded ciphertext as the content
+	Encrypted bool
+
+	// Signature over MessageSignaturePayload, nil if unsigned
+	Signature []byte
 }
 
 func (m *SendMessage) Type() PacketType {
@@ -162,8 +184,9 @@ func (m *SendMessage) Type() PacketType {
 }
 
 type EditMessage struct {
-	Content string
-	Message snowflake.ID
+	Content   string
+	Message   snowflake.ID
+	Signature []byte
 }
 
 func (m *EditMessage) Type() PacketType {
@@ -178,6 +201,15 @@ func (m *DeleteMessage) Type() PacketType {
 	return PacketDeleteMessage
 }
 
+type PinMessage struct {
+	Message snowflake.ID
+	Pin     bool
+}
+
+func (m *PinMessage) Type() PacketType {
+	return PacketPinMessage
+}
+
 type RequestMessages struct {
 	ReceiverID  *snowflake.ID
 	FrequencyID *snowflake.ID
@@ -190,12 +222,294 @@ func (m *RequestMessages) Type() PacketType {
 type MessagesInfo struct {
 	Messages        []data.Message
 	RemovedMessages []snowflake.ID
+	Attachments     []data.Attachment // Of the messages that have one
 }
 
 func (m *MessagesInfo) Type() PacketType {
 	return PacketMessagesInfo
 }
 
+type SearchMessages struct {
+	Query       string
+	FrequencyID *snowflake.ID
+	NetworkID   *snowflake.ID
+	ReceiverID  *snowflake.ID
+	SenderID    *snowflake.ID
+	After       *int64 // Unix millis, inclusive
+	Before      *int64 // Unix millis, exclusive
+	HasMention  bool
+}
+
+func (m *SearchMessages) Type() PacketType {
+	return PacketSearchMessages
+}
+
+type SearchResults struct {
+	Query    string
+	Messages []data.Message
+}
+
+func (m *SearchResults) Type() PacketType {
+	return PacketSearchResults
+}
+
+type UploadAttachment struct {
+	Name string
+	Hash string // Hex encoded sha256 of the content
+	Size int64
+}
+
+func (m *UploadAttachment) Type() PacketType {
+	return PacketUploadAttachment
+}
+
+// AttachmentChunk carries the content of an attachment in both directions.
+// A chunk with no data is a request for the chunk starting at Offset,
+// the server sends those to ask for the next chunk of an upload,
+// the client sends those to download an attachment.
+type AttachmentChunk struct {
+	Data       []byte
+	Offset     int64
+	Attachment snowflake.ID
+}
+
+func (m *AttachmentChunk) Type() PacketType {
+	return PacketAttachmentChunk
+}
+
+// Chunks are too big to be logged
+func (m *AttachmentChunk) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("attachment", m.Attachment),
+		slog.Int64("offset", m.Offset),
+		slog.Int("data_bytes", len(m.Data)),
+	)
+}
+
+// The hash of the attachments is always empty
+type AttachmentsInfo struct {
+	Attachments []data.Attachment
+}
+
+func (m *AttachmentsInfo) Type() PacketType {
+	return PacketAttachmentsInfo
+}
+
+// ViewChat tells the server which frequency or DM the user is viewing,
+// neither means the user isn't viewing any chat
+type ViewChat struct {
+	ReceiverID  *snowflake.ID
+	FrequencyID *snowflake.ID
+}
+
+func (m *ViewChat) Type() PacketType {
+	return PacketViewChat
+}
+
+// Typing is sent by the client while the user is typing, the server
+// sets the user and forwards it to whoever is viewing that chat.
+// It's never persisted.
+type Typing struct {
+	ReceiverID  *snowflake.ID
+	FrequencyID *snowflake.ID
+	User        snowflake.ID
+}
+
+func (m *Typing) Type() PacketType {
+	return PacketTyping
+}
+
+type Presence struct {
+	Status string
+	User   snowflake.ID
+	State  int
+}
+
+// PresenceInfo is sent by the server whenever the presence or status
+// of users changes, users that were never sent are offline
+type PresenceInfo struct {
+	Presences []Presence
+}
+
+func (m *PresenceInfo) Type() PacketType {
+	return PacketPresenceInfo
+}
+
+// ReadReceiptsInfo is sent by the server when users read their DM
+// with the receiving user, only if both users enabled read receipts
+type ReadReceiptsInfo struct {
+	Users        []snowflake.ID
+	LastRead     []int64
+	RemovedUsers []snowflake.ID
+}
+
+func (m *ReadReceiptsInfo) Type() PacketType {
+	return PacketReadReceiptsInfo
+}
+
+// CreateGroupSignal creates a group signal with the session's user
+// and the given users as its members
+type CreateGroupSignal struct {
+	Name  string
+	Users []snowflake.ID
+}
+
+func (m *CreateGroupSignal) Type() PacketType {
+	return PacketCreateGroupSignal
+}
+
+type AddGroupSignalMember struct {
+	Group snowflake.ID
+	User  snowflake.ID
+}
+
+func (m *AddGroupSignalMember) Type() PacketType {
+	return PacketAddGroupSignalMember
+}
+
+type LeaveGroupSignal struct {
+	Group snowflake.ID
+}
+
+func (m *LeaveGroupSignal) Type() PacketType {
+	return PacketLeaveGroupSignal
+}
+
+type FullGroupSignal struct {
+	data.GroupSignal
+	Members []snowflake.ID
+	Users   []data.User
+}
+
+type GroupSignalsInfo struct {
+	Groups        []FullGroupSignal
+	RemovedGroups []snowflake.ID
+}
+
+func (m *GroupSignalsInfo) Type() PacketType {
+	return PacketGroupSignalsInfo
+}
+
+// IgnoreMessageRequest hides the pending message request from the given user,
+// without letting them know or send another one
+type IgnoreMessageRequest struct {
+	User snowflake.ID
+}
+
+func (m *IgnoreMessageRequest) Type() PacketType {
+	return PacketIgnoreMessageRequest
+}
+
+// MessageRequestsInfo contains the first message of each pending
+// message request, RemovedRequests contains the ids of the senders
+type MessageRequestsInfo struct {
+	Requests        []data.Message
+	Users           []data.User
+	RemovedRequests []snowflake.ID
+}
+
+func (m *MessageRequestsInfo) Type() PacketType {
+	return PacketMessageRequestsInfo
+}
+
+// RotateKey replaces the public key of the user with PubKey.
+// Both the current and the new key sign KeyRotationSignaturePayload,
+// proving the user owns both of them.
+type RotateKey struct {
+	PubKey       sshkey.PublicKey
+	Signature    []byte
+	NewSignature []byte
+}
+
+func (m *RotateKey) Type() PacketType {
+	return PacketRotateKey
+}
+
+// KeyRotationsInfo contains the key rotations of the user and
+// of trusted users, in the order they were made
+type KeyRotationsInfo struct {
+	Rotations []data.KeyRotation
+}
+
+func (m *KeyRotationsInfo) Type() PacketType {
+	return PacketKeyRotationsInfo
+}
+
+// AuthorizeDevice allows PubKey to sign in to the user's account.
+// Signature is of the session's key over DeviceSignaturePayload,
+// so anyone trusting the user can verify the device was authorized.
+// NewSignature is of PubKey over the same payload, proving possession
+// of the device key so someone else's public key can't be claimed.
+type AuthorizeDevice struct {
+	PubKey       sshkey.PublicKey
+	Name         string
+	Signature    []byte
+	NewSignature []byte
+}
+
+func (m *AuthorizeDevice) Type() PacketType {
+	return PacketAuthorizeDevice
+}
+
+// RevokeDevice revokes the device key and every device it authorized,
+// closing all of their sessions
+type RevokeDevice struct {
+	Device snowflake.ID
+}
+
+func (m *RevokeDevice) Type() PacketType {
+	return PacketRevokeDevice
+}
+
+// DevicesInfo contains the device keys of the user and of trusted users,
+// RemovedDevices contains the ids of revoked device keys
+type DevicesInfo struct {
+	Devices        []data.DeviceKey
+	RemovedDevices []snowflake.ID
+}
+
+func (m *DevicesInfo) Type() PacketType {
+	return PacketDevicesInfo
+}
+
+type GetSessions struct{}
+
+func (m *GetSessions) Type() PacketType {
+	return PacketGetSessions
+}
+
+// TerminateSession signs out one of the user's other sessions
+type TerminateSession struct {
+	Session snowflake.ID
+}
+
+func (m *TerminateSession) Type() PacketType {
+	return PacketTerminateSession
+}
+
+// Session describes one of the user's active sessions, analytics are
+// empty if the session didn't send any or opted out of them
+type Session struct {
+	PubKey   sshkey.PublicKey
+	Addr     string
+	OS       string
+	Arch     string
+	Term     string
+	Start    int64 // Unix millis
+	Duration time.Duration
+	ID       snowflake.ID
+	Current  bool
+}
+
+// SessionsInfo contains all of the user's active sessions
+type SessionsInfo struct {
+	Sessions []Session
+}
+
+func (m *SessionsInfo) Type() PacketType {
+	return PacketSessionsInfo
+}
+
 type MembersInfo struct {
 	RemovedMembers []snowflake.ID
 	Members        []data.Member
@@ -234,7 +548,7 @@ func (m *TrustUser) Type() PacketType {
 
 type TrustInfo struct {
 	TrustedUsers        []snowflake.ID
-	TrustedPublicKeys   []ed25519.PublicKey
+	TrustedPublicKeys   []sshkey.PublicKey
 	RemovedTrustedUsers []snowflake.ID
 }
 
@@ -337,8 +651,11 @@ func (m *NonceInfo) Type() PacketType {
 	return PacketNonceInfo
 }
 
+// Authenticate proves ownership of PubKey by signing the nonce,
+// the key's type is part of its wire format and determines the
+// signature's algorithm, see sshkey.Sign
 type Authenticate struct {
-	PubKey    ed25519.PublicKey
+	PubKey    sshkey.PublicKey
 	Signature []byte
 }
 
@@ -17,13 +17,15 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"crypto/ed25519"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/binary"
 	"fmt"
 	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -34,6 +36,7 @@ import (
 	"github.com/kyren223/eko/internal/server/session"
 	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 var (
@@ -51,20 +54,53 @@ func SendMessage(ctx context.Context, sess *session.Session, request *packet.Sen
 		return &packet.Error{Error: "either receiver id or frequency id must exist"}
 	}
 
-	if len(request.Content) > packet.MaxMessageBytes {
+	maxBytes := packet.MaxMessageBytes
+	if request.Encrypted {
+		maxBytes = packet.MaxEncryptedMessageBytes
+	}
+	if len(request.Content) > maxBytes {
 		return &packet.Error{Error: fmt.Sprintf(
-			"message content must not exceed %v bytes",
-			packet.MaxMessageBytes,
+			"message content must not exceed %v bytes", maxBytes,
 		)}
 	}
 
 	content := strings.TrimSpace(request.Content)
-	if content == "" {
+	if content == "" && request.Attachment == nil {
 		return &packet.Error{Error: "message content must not be blank"}
 	}
 
+	chat := request.FrequencyID
+	if chat == nil {
+		chat = request.ReceiverID
+	}
+	errPayload := validateSignature(sess, *chat, request.Attachment, content, request.Signature)
+	if errPayload != nil {
+		return errPayload
+	}
+
+	if request.Encrypted {
+		if request.ReceiverID == nil {
+			return &packet.Error{Error: "only direct messages may be encrypted"}
+		}
+		if request.Attachment != nil {
+			return &packet.Error{Error: "encrypted messages may not have attachments"}
+		}
+		if errPayload := validateCiphertext(content); errPayload != nil {
+			return errPayload
+		}
+	}
+
 	queries := data.New(db)
 
+	var attachments []data.Attachment
+	if request.Attachment != nil {
+		attachment, errPayload := validateAttachment(ctx, queries, sess, *request.Attachment)
+		if errPayload != nil {
+			return errPayload
+		}
+		attachments = append(attachments, attachment)
+	}
+
 	if request.FrequencyID != nil {
 		frequency, err := queries.GetFrequencyById(ctx, *request.FrequencyID)
 		if err == sql.ErrNoRows {
@@ -94,39 +130,53 @@ func SendMessage(ctx context.Context, sess *session.Session, request *packet.Sen
 			return &ErrPermissionDenied
 		}
 
-		if request.Ping != nil {
-			if *request.Ping == packet.PingEveryone {
-				if !member.IsAdmin {
-					return &ErrPermissionDenied
-				}
-			} else if *request.Ping != packet.PingAdmins {
-				_, err := queries.GetUserById(ctx, *request.Ping)
-				if err == sql.ErrNoRows {
-					return &packet.Error{Error: "pinged user doesn't exist"}
-				}
-				if err != nil {
-					slog.ErrorContext(ctx, "database error", "error", err)
-					return &ErrInternalError
-				}
-			}
+		mentions := packet.ParseMentions(content)
+		if request.Ping != nil && !slices.Contains(mentions, *request.Ping) {
+			mentions = append(mentions, *request.Ping)
+		}
+		if errPayload := validateMentions(ctx, queries, frequency.NetworkID, mentions, member.IsAdmin); errPayload != nil {
+			return errPayload
 		}
 
-		message, err := queries.CreateMessage(ctx, data.CreateMessageParams{
-			ID:          sess.Manager().Node().Generate(),
-			SenderID:    sess.ID(),
-			Content:     content,
-			FrequencyID: request.FrequencyID,
-			ReceiverID:  nil,
-			Ping:        request.Ping,
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		defer func() { _ = tx.Rollback() }()
+		qtx := queries.WithTx(tx)
+
+		message, err := qtx.CreateMessage(ctx, data.CreateMessageParams{
+			ID:           sess.Manager().Node().Generate(),
+			SenderID:     sess.ID(),
+			Content:      content,
+			FrequencyID:  request.FrequencyID,
+			ReceiverID:   nil,
+			Ping:         firstMention(mentions),
+			AttachmentID: request.Attachment,
+			Signature:    request.Signature,
 		})
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
 		}
 
+		err = insertMentions(ctx, qtx, message.ID, mentions)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
 		return NetworkPropagateWithFilter(ctx, sess, frequency.NetworkID, &packet.MessagesInfo{
 			Messages:        []data.Message{message},
 			RemovedMessages: nil,
+			Attachments:     attachments,
 		}, func(userId snowflake.ID) (pass bool) {
 			if frequency.Perms != packet.PermNoAccess {
 				return true
@@ -139,65 +189,41 @@ func SendMessage(ctx context.Context, sess *session.Session, request *packet.Sen
 	if request.ReceiverID != nil {
 		user, err := queries.GetUserById(ctx, *request.ReceiverID)
 		if err == sql.ErrNoRows {
-			return &packet.Error{Error: "user doesn't exist"}
+			return sendGroupSignalMessage(ctx, sess, queries, request, content, attachments)
 		}
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
 		}
 
-		// Session user blocked the user he tried to message
-		_, err = queries.IsUserBlocked(ctx, data.IsUserBlockedParams{
-			BlockingUserID: sess.ID(),
-			BlockedUserID:  user.ID,
-		})
-		if err != nil && err != sql.ErrNoRows {
-			slog.ErrorContext(ctx, "database error", "error", err)
-			return &ErrInternalError
+		if errPayload := validateNotBlocked(ctx, queries, sess, user); errPayload != nil {
+			return errPayload
 		}
-		if err != sql.ErrNoRows {
-			// Can't message a user if you blocked them
-			return &ErrPermissionDenied
+		allowed, errPayload := isDirectMessageAllowed(ctx, queries, sess, user)
+		if errPayload != nil {
+			return errPayload
 		}
-
-		// Session user was blocked by the user they tried to message
-		_, err = queries.IsUserBlocked(ctx, data.IsUserBlockedParams{
-			BlockingUserID: user.ID,
-			BlockedUserID:  sess.ID(),
-		})
-		if err != nil && err != sql.ErrNoRows {
-			slog.ErrorContext(ctx, "database error", "error", err)
-			return &ErrInternalError
+		if !allowed && request.Encrypted {
+			return &packet.Error{Error: fmt.Sprintf(
+				"%v must trust you to receive encrypted messages", user.Name,
+			)}
 		}
-		if err != sql.ErrNoRows {
-			// Can't message a user if they blocked you
-			return &ErrPermissionDenied
+		if !allowed && user.ID != sess.ID() {
+			return sendMessageRequest(ctx, sess, queries, user, request, content, attachments)
 		}
-
-		if !user.IsPublicDM {
-			pubKey, err := queries.GetTrustedPublicKey(ctx, data.GetTrustedPublicKeyParams{
-				TrustingUserID: user.ID,
-				TrustedUserID:  sess.ID(),
-			})
-			if err == sql.ErrNoRows {
-				return &ErrPermissionDenied
-			}
-			if err != nil {
-				slog.ErrorContext(ctx, "database error", "error", err)
-				return &ErrInternalError
-			}
-			if !bytes.Equal(sess.PubKey(), pubKey) {
-				return &ErrPermissionDenied
-			}
+		if !allowed {
+			return &ErrPermissionDenied
 		}
 
 		message, err := queries.CreateMessage(ctx, data.CreateMessageParams{
-			ID:          sess.Manager().Node().Generate(),
-			Content:     content,
-			SenderID:    sess.ID(),
-			FrequencyID: nil,
-			ReceiverID:  request.ReceiverID,
-			Ping:        nil,
+			ID:           sess.Manager().Node().Generate(),
+			Content:      content,
+			SenderID:     sess.ID(),
+			FrequencyID:  nil,
+			ReceiverID:   request.ReceiverID,
+			AttachmentID: request.Attachment,
+			IsEncrypted:  request.Encrypted,
+			Signature:    request.Signature,
 		})
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
@@ -217,6 +243,7 @@ func SendMessage(ctx context.Context, sess *session.Session, request *packet.Sen
 		return UserPropagate(ctx, sess, user.ID, &packet.MessagesInfo{
 			Messages:        []data.Message{message},
 			RemovedMessages: nil,
+			Attachments:     attachments,
 		}, false)
 	}
 
@@ -262,17 +289,44 @@ func RequestMessages(ctx context.Context, sess *session.Session, request *packet
 			return &ErrInternalError
 		}
 
+		attachments, err := messageAttachments(ctx, queries, messages)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
 		return &packet.MessagesInfo{
 			Messages:        messages,
 			RemovedMessages: nil,
+			Attachments:     attachments,
 		}
 	}
 
 	if request.ReceiverID != nil && request.FrequencyID == nil {
-		messages, err := queries.GetDirectMessages(ctx, data.GetDirectMessagesParams{
-			User1: sess.ID(),
-			User2: request.ReceiverID,
-		})
+		members, err := queries.GetGroupSignalMembers(ctx, *request.ReceiverID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		var messages []data.Message
+		if len(members) != 0 {
+			if !slices.Contains(members, sess.ID()) {
+				return &ErrPermissionDenied
+			}
+			messages, err = queries.GetGroupSignalMessages(ctx, request.ReceiverID)
+		} else {
+			messages, err = queries.GetDirectMessages(ctx, data.GetDirectMessagesParams{
+				User1: sess.ID(),
+				User2: request.ReceiverID,
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		attachments, err := messageAttachments(ctx, queries, messages)
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
@@ -281,6 +335,7 @@ func RequestMessages(ctx context.Context, sess *session.Session, request *packet
 		return &packet.MessagesInfo{
 			Messages:        messages,
 			RemovedMessages: nil,
+			Attachments:     attachments,
 		}
 	}
 
@@ -669,6 +724,8 @@ func SetMember(ctx context.Context, sess *session.Session, request *packet.SetMe
 		}
 		members, users := SplitMembersAndUsers(membersAndUsers)
 
+		SyncPresence(ctx, sess)
+
 		return &packet.NetworksInfo{
 			Networks: []packet.FullNetwork{{
 				Network:     network,
@@ -802,6 +859,10 @@ func SetMember(ctx context.Context, sess *session.Session, request *packet.SetMe
 		}
 		members, users := SplitMembersAndUsers(membersAndUsers)
 
+		if newMember.UserID == sess.ID() {
+			SyncPresence(ctx, sess)
+		}
+
 		return &packet.NetworksInfo{
 			Networks: []packet.FullNetwork{{
 				Network:     network,
@@ -848,7 +909,7 @@ func SetUserData(ctx context.Context, sess *session.Session, request *packet.Set
 			pubKey, _, err := ed25519.GenerateKey(nil)
 			assert.NoError(err, "random should never fail")
 			err = queries.DeleteUser(ctx, data.DeleteUserParams{
-				PublicKey: pubKey,
+				PublicKey: sshkey.FromEd25519(pubKey),
 				ID:        sess.ID(),
 			})
 			if err != nil {
@@ -876,17 +937,40 @@ func SetUserData(ctx context.Context, sess *session.Session, request *packet.Set
 			)}
 		}
 
+		status := request.User.Status
+		if len(status) > packet.MaxUserStatusBytes {
+			return &packet.Error{Error: fmt.Sprintf(
+				"user status bytes may not exceed %v bytes",
+				packet.MaxUserStatusBytes,
+			)}
+		}
+
+		oldUser, err := queries.GetUserById(ctx, sess.ID())
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
 		user, err := queries.UpdateUser(ctx, data.UpdateUserParams{
-			Name:        name,
-			Description: description,
-			IsPublicDM:  request.User.IsPublicDM,
-			ID:          sess.ID(),
+			Name:             name,
+			Description:      description,
+			IsPublicDM:       request.User.IsPublicDM,
+			Status:           status,
+			IsDND:            request.User.IsDND,
+			IsPresencePublic: request.User.IsPresencePublic,
+			IsReadReceipts:   request.User.IsReadReceipts,
+			ID:               sess.ID(),
 		})
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
 		}
 
+		updatePresence(ctx, sess, user, true, false)
+		if user.IsReadReceipts != oldUser.IsReadReceipts {
+			syncReadReceipts(ctx, sess, user.IsReadReceipts)
+		}
+
 		userPtr = &user
 	}
 
@@ -1103,7 +1187,7 @@ func DeleteMessage(ctx context.Context, sess *session.Session, request *packet.D
 		})
 	}
 
-	if message.ReceiverID != nil {
+	if message.ReceiverID != nil || message.GroupID != nil {
 		if message.SenderID != sess.ID() {
 			return &ErrPermissionDenied
 		}
@@ -1114,10 +1198,10 @@ func DeleteMessage(ctx context.Context, sess *session.Session, request *packet.D
 			return &ErrInternalError
 		}
 
-		return UserPropagate(ctx, sess, *message.ReceiverID, &packet.MessagesInfo{
+		return ChatPropagate(ctx, sess, message, &packet.MessagesInfo{
 			Messages:        nil,
 			RemovedMessages: []snowflake.ID{message.ID},
-		}, false)
+		})
 	}
 
 	assert.Never("unreachable")
@@ -1125,10 +1209,10 @@ func DeleteMessage(ctx context.Context, sess *session.Session, request *packet.D
 }
 
 func EditMessage(ctx context.Context, sess *session.Session, request *packet.EditMessage) packet.Payload {
-	if len(request.Content) > packet.MaxMessageBytes {
+	if len(request.Content) > packet.MaxEncryptedMessageBytes {
 		return &packet.Error{Error: fmt.Sprintf(
 			"message conent must not exceed %v bytes",
-			packet.MaxMessageBytes,
+			packet.MaxEncryptedMessageBytes,
 		)}
 	}
 
@@ -1156,6 +1240,29 @@ func EditMessage(ctx context.Context, sess *session.Session, request *packet.Edi
 		return &ErrPermissionDenied
 	}
 
+	chat := message.FrequencyID
+	if chat == nil {
+		chat = message.GroupID
+	}
+	if chat == nil {
+		chat = message.ReceiverID
+	}
+	errPayload := validateSignature(sess, *chat, message.AttachmentID, content, request.Signature)
+	if errPayload != nil {
+		return errPayload
+	}
+
+	if message.IsEncrypted {
+		if errPayload := validateCiphertext(content); errPayload != nil {
+			return errPayload
+		}
+	} else if len(content) > packet.MaxMessageBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"message conent must not exceed %v bytes",
+			packet.MaxMessageBytes,
+		)}
+	}
+
 	if message.FrequencyID != nil {
 		frequency, err := queries.GetFrequencyById(ctx, *message.FrequencyID)
 		if err != nil {
@@ -1163,15 +1270,54 @@ func EditMessage(ctx context.Context, sess *session.Session, request *packet.Edi
 			return &ErrInternalError
 		}
 
-		editedMessage, err := queries.EditMessage(ctx, data.EditMessageParams{
-			Content: content,
-			ID:      message.ID,
+		isAdmin, err := IsNetworkAdmin(ctx, queries, sess.ID(), frequency.NetworkID)
+		if err != nil && err != sql.ErrNoRows {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		mentions := packet.ParseMentions(content)
+		if errPayload := validateMentions(ctx, queries, frequency.NetworkID, mentions, isAdmin); errPayload != nil {
+			return errPayload
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		defer func() { _ = tx.Rollback() }()
+		qtx := queries.WithTx(tx)
+
+		editedMessage, err := qtx.EditMessage(ctx, data.EditMessageParams{
+			Content:   content,
+			Ping:      firstMention(mentions),
+			Signature: request.Signature,
+			ID:        message.ID,
 		})
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
 		}
 
+		err = qtx.DeleteMessageMentions(ctx, message.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		err = insertMentions(ctx, qtx, message.ID, mentions)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
 		return NetworkPropagateWithFilter(ctx, sess, frequency.NetworkID, &packet.MessagesInfo{
 			Messages:        []data.Message{editedMessage},
 			RemovedMessages: nil,
@@ -1184,26 +1330,450 @@ func EditMessage(ctx context.Context, sess *session.Session, request *packet.Edi
 		})
 	}
 
-	if message.ReceiverID != nil {
+	if message.ReceiverID != nil || message.GroupID != nil {
 		editedMessage, err := queries.EditMessage(ctx, data.EditMessageParams{
-			Content: content,
-			ID:      message.ID,
+			Content:   content,
+			Signature: request.Signature,
+			ID:        message.ID,
 		})
 		if err != nil {
 			slog.ErrorContext(ctx, "database error", "error", err)
 			return &ErrInternalError
 		}
 
-		return UserPropagate(ctx, sess, *message.ReceiverID, &packet.MessagesInfo{
+		return ChatPropagate(ctx, sess, editedMessage, &packet.MessagesInfo{
 			Messages:        []data.Message{editedMessage},
 			RemovedMessages: nil,
-		}, false)
+		})
 	}
 
 	assert.Never("unreachable")
 	return nil
 }
 
+func PinMessage(ctx context.Context, sess *session.Session, request *packet.PinMessage) packet.Payload {
+	queries := data.New(db)
+
+	message, err := queries.GetMessageById(ctx, request.Message)
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "message doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	var pinnedAt *int64
+	if request.Pin {
+		if message.PinnedAt != nil {
+			return &packet.Error{Error: "message is already pinned"}
+		}
+		now := time.Now().UnixMilli()
+		pinnedAt = &now
+	} else if message.PinnedAt == nil {
+		return &packet.Error{Error: "message is not pinned"}
+	}
+
+	if message.FrequencyID != nil {
+		frequency, err := queries.GetFrequencyById(ctx, *message.FrequencyID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		isAdmin, err := IsNetworkAdmin(ctx, queries, sess.ID(), frequency.NetworkID)
+		if err == sql.ErrNoRows {
+			return &ErrPermissionDenied
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		if !isAdmin {
+			return &ErrPermissionDenied
+		}
+
+		pinnedMessage, err := queries.SetMessagePinned(ctx, data.SetMessagePinnedParams{
+			PinnedAt: pinnedAt,
+			ID:       message.ID,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		return NetworkPropagateWithFilter(ctx, sess, frequency.NetworkID, &packet.MessagesInfo{
+			Messages:        []data.Message{pinnedMessage},
+			RemovedMessages: nil,
+		}, func(userId snowflake.ID) (pass bool) {
+			if frequency.Perms != packet.PermNoAccess {
+				return true
+			}
+			isAdmin, _ := IsNetworkAdmin(ctx, queries, userId, frequency.NetworkID)
+			return isAdmin
+		})
+	}
+
+	if message.ReceiverID != nil || message.GroupID != nil {
+		// Either party of the DM is allowed to pin, or any group member
+		if message.GroupID != nil {
+			_, errPayload := groupSignalMembers(ctx, queries, sess, *message.GroupID)
+			if errPayload != nil {
+				return errPayload
+			}
+		} else if message.SenderID != sess.ID() && *message.ReceiverID != sess.ID() {
+			return &ErrPermissionDenied
+		}
+
+		pinnedMessage, err := queries.SetMessagePinned(ctx, data.SetMessagePinnedParams{
+			PinnedAt: pinnedAt,
+			ID:       message.ID,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		return ChatPropagate(ctx, sess, pinnedMessage, &packet.MessagesInfo{
+			Messages:        []data.Message{pinnedMessage},
+			RemovedMessages: nil,
+		})
+	}
+
+	assert.Never("unreachable")
+	return nil
+}
+
+func SearchMessages(ctx context.Context, sess *session.Session, request *packet.SearchMessages) packet.Payload {
+	if len(request.Query) > packet.MaxSearchQueryBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"search query must not exceed %v bytes",
+			packet.MaxSearchQueryBytes,
+		)}
+	}
+
+	query := ftsQuery(request.Query)
+	if query == "" {
+		return &packet.Error{Error: "search query must not be blank"}
+	}
+
+	var after, before *snowflake.ID
+	if request.After != nil {
+		id := snowflake.FromTime(*request.After)
+		after = &id
+	}
+	if request.Before != nil {
+		id := snowflake.FromTime(*request.Before)
+		before = &id
+	}
+
+	queries := data.New(db)
+	messages, err := queries.SearchMessages(ctx, data.SearchMessagesParams{
+		Query:       query,
+		UserID:      sess.ID(),
+		FrequencyID: request.FrequencyID,
+		NetworkID:   request.NetworkID,
+		PeerID:      request.ReceiverID,
+		SenderID:    request.SenderID,
+		After:       after,
+		Before:      before,
+		HasMention:  request.HasMention,
+		MaxResults:  packet.MaxSearchResults,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.SearchResults{
+		Query:    request.Query,
+		Messages: messages,
+	}
+}
+
+func UploadAttachment(ctx context.Context, sess *session.Session, request *packet.UploadAttachment) packet.Payload {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return &packet.Error{Error: "attachment name must not be blank"}
+	}
+	if len(name) > packet.MaxAttachmentNameBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"attachment name must not exceed %v bytes",
+			packet.MaxAttachmentNameBytes,
+		)}
+	}
+
+	if request.Size < 0 {
+		return &packet.Error{Error: "attachment size must not be negative"}
+	}
+	if request.Size > MaxAttachmentBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"attachment must not exceed %v bytes",
+			MaxAttachmentBytes,
+		)}
+	}
+
+	if !isValidHash(request.Hash) {
+		return &packet.Error{Error: "attachment hash must be a lowercase hex encoded sha256"}
+	}
+
+	// Only one upload at a time, starting a new one cancels the previous
+	AbortUpload(ctx, sess)
+
+	// Always uploaded, even if the blob is already stored, otherwise
+	// knowing a hash would be enough to gain access to its content
+	id := sess.Manager().Node().Generate()
+	file, err := os.Create(uploadPath(id))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed creating upload", "error", err)
+		return &ErrInternalError
+	}
+	upload := &session.Upload{
+		File:    file,
+		Hasher:  sha256.New(),
+		Name:    name,
+		Hash:    request.Hash,
+		Size:    request.Size,
+		Written: 0,
+		ID:      id,
+	}
+	sess.SetUpload(upload)
+
+	if upload.Size == 0 {
+		return finishUpload(ctx, sess, upload)
+	}
+
+	return &packet.AttachmentChunk{
+		Data:       nil,
+		Offset:     0,
+		Attachment: id,
+	}
+}
+
+func AttachmentChunk(ctx context.Context, sess *session.Session, request *packet.AttachmentChunk) packet.Payload {
+	if len(request.Data) == 0 {
+		return downloadAttachmentChunk(ctx, sess, request)
+	}
+
+	upload := sess.Upload()
+	if upload == nil || upload.ID != request.Attachment {
+		return &packet.Error{Error: "attachment is not being uploaded"}
+	}
+
+	if request.Offset != upload.Written {
+		AbortUpload(ctx, sess)
+		return &packet.Error{Error: fmt.Sprintf(
+			"expected chunk at offset %v but got %v", upload.Written, request.Offset,
+		)}
+	}
+	if len(request.Data) > packet.AttachmentChunkBytes {
+		AbortUpload(ctx, sess)
+		return &packet.Error{Error: fmt.Sprintf(
+			"attachment chunk must not exceed %v bytes",
+			packet.AttachmentChunkBytes,
+		)}
+	}
+	if upload.Written+int64(len(request.Data)) > upload.Size {
+		AbortUpload(ctx, sess)
+		return &packet.Error{Error: "attachment is larger than its declared size"}
+	}
+
+	if _, err := upload.File.Write(request.Data); err != nil {
+		AbortUpload(ctx, sess)
+		slog.ErrorContext(ctx, "failed writing upload", "error", err)
+		return &ErrInternalError
+	}
+	_, _ = upload.Hasher.Write(request.Data) // Never returns an error
+	upload.Written += int64(len(request.Data))
+
+	if upload.Written == upload.Size {
+		return finishUpload(ctx, sess, upload)
+	}
+
+	return &packet.AttachmentChunk{
+		Data:       nil,
+		Offset:     upload.Written,
+		Attachment: upload.ID,
+	}
+}
+
+func downloadAttachmentChunk(ctx context.Context, sess *session.Session, request *packet.AttachmentChunk) packet.Payload {
+	queries := data.New(db)
+
+	canAccess, err := queries.CanAccessAttachment(ctx, data.CanAccessAttachmentParams{
+		AttachmentID: request.Attachment,
+		UserID:       sess.ID(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if !canAccess {
+		return &ErrPermissionDenied
+	}
+
+	attachment, err := queries.GetAttachmentById(ctx, request.Attachment)
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "attachment doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	if request.Offset < 0 || request.Offset >= attachment.Size {
+		return &packet.Error{Error: "attachment chunk offset out of bounds"}
+	}
+
+	chunk, err := readBlobChunk(attachment, request.Offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed reading blob", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.AttachmentChunk{
+		Data:       chunk,
+		Offset:     request.Offset,
+		Attachment: attachment.ID,
+	}
+}
+
+func ViewChat(ctx context.Context, sess *session.Session, request *packet.ViewChat) packet.Payload {
+	if request.ReceiverID != nil && request.FrequencyID != nil {
+		return &packet.Error{Error: "only one of receiver id or frequency id may be specified"}
+	}
+
+	viewing := request.FrequencyID
+	if viewing == nil {
+		viewing = request.ReceiverID
+	}
+	sess.SetViewing(viewing)
+
+	return nil
+}
+
+func Typing(ctx context.Context, sess *session.Session, request *packet.Typing) packet.Payload {
+	if (request.ReceiverID != nil) == (request.FrequencyID != nil) {
+		return &packet.Error{Error: "either receiver id or frequency id must exist"}
+	}
+
+	queries := data.New(db)
+
+	payload := &packet.Typing{
+		ReceiverID:  request.ReceiverID,
+		FrequencyID: request.FrequencyID,
+		User:        sess.ID(),
+	}
+
+	if request.FrequencyID != nil {
+		frequency, err := queries.GetFrequencyById(ctx, *request.FrequencyID)
+		if err == sql.ErrNoRows {
+			return &packet.Error{Error: "frequency doesn't exist"}
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		member, err := queries.GetMemberById(ctx, data.GetMemberByIdParams{
+			NetworkID: frequency.NetworkID,
+			UserID:    sess.ID(),
+		})
+		if err == sql.ErrNoRows {
+			return &ErrPermissionDenied // Not a member
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		if !member.IsMember || member.IsMuted {
+			return &ErrPermissionDenied
+		}
+		if frequency.Perms != packet.PermReadWrite && !member.IsAdmin {
+			return &ErrPermissionDenied
+		}
+
+		viewers := viewers(sess, frequency.ID)
+		if len(viewers) == 0 {
+			return nil
+		}
+
+		blocked, err := queries.GetBlockedUsers(ctx, sess.ID())
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		blocking, err := queries.GetBlockingUsers(ctx, sess.ID())
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		response := NetworkPropagateWithFilter(ctx, sess, frequency.NetworkID, payload, func(userId snowflake.ID) (pass bool) {
+			if !slices.Contains(viewers, userId) {
+				return false
+			}
+			if slices.Contains(blocked, userId) || slices.Contains(blocking, userId) {
+				return false
+			}
+			if frequency.Perms != packet.PermNoAccess {
+				return true
+			}
+			isAdmin, _ := IsNetworkAdmin(ctx, queries, userId, frequency.NetworkID)
+			return isAdmin
+		})
+		if response != payload {
+			return response // Error
+		}
+		return nil // Typing is never echoed back
+	}
+
+	user, err := queries.GetUserById(ctx, *request.ReceiverID)
+	if err == sql.ErrNoRows {
+		members, errPayload := groupSignalMembers(ctx, queries, sess, *request.ReceiverID)
+		if errPayload != nil {
+			return errPayload
+		}
+
+		blocked, err := queries.GetBlockedUsers(ctx, sess.ID())
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		blocking, err := queries.GetBlockingUsers(ctx, sess.ID())
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		for _, viewer := range viewers(sess, *request.ReceiverID) {
+			if !slices.Contains(members, viewer) {
+				continue
+			}
+			if slices.Contains(blocked, viewer) || slices.Contains(blocking, viewer) {
+				continue
+			}
+			_ = UserPropagate(ctx, sess, viewer, payload, false)
+		}
+		return nil // Typing is never echoed back
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	if errPayload := validateDirectMessage(ctx, queries, sess, user); errPayload != nil {
+		return errPayload
+	}
+
+	if !slices.Contains(viewers(sess, sess.ID()), user.ID) {
+		return nil
+	}
+	_ = UserPropagate(ctx, sess, user.ID, payload, false)
+
+	return nil // Typing is never echoed back
+}
+
 func TrustUser(ctx context.Context, sess *session.Session, request *packet.TrustUser) packet.Payload {
 	if sess.ID() == request.User {
 		return &packet.Error{Error: "you cannot trust yourself"}
@@ -1228,7 +1798,7 @@ func TrustUser(ctx context.Context, sess *session.Session, request *packet.Trust
 		if err == nil {
 			return &packet.TrustInfo{
 				TrustedUsers:        []snowflake.ID{user.ID},
-				TrustedPublicKeys:   []ed25519.PublicKey{publicKey},
+				TrustedPublicKeys:   []sshkey.PublicKey{publicKey},
 				RemovedTrustedUsers: nil,
 			}
 		}
@@ -1259,9 +1829,30 @@ func TrustUser(ctx context.Context, sess *session.Session, request *packet.Trust
 			return &ErrInternalError
 		}
 
+		// Trusting the sender of a pending message request accepts it
+		err = queries.DeleteMessageRequest(ctx, data.DeleteMessageRequestParams{
+			SenderID:   user.ID,
+			ReceiverID: sess.ID(),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		err = queries.InsertLastReadMessage(ctx, data.InsertLastReadMessageParams{
+			UserID:   sess.ID(),
+			SourceID: user.ID,
+			LastRead: 0,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		SyncPresence(ctx, sess) // The trusted user may now see our presence
+
 		return &packet.TrustInfo{
 			TrustedUsers:        []snowflake.ID{user.ID},
-			TrustedPublicKeys:   []ed25519.PublicKey{user.PublicKey},
+			TrustedPublicKeys:   []sshkey.PublicKey{user.PublicKey},
 			RemovedTrustedUsers: nil,
 		}
 	} else {
@@ -1292,7 +1883,7 @@ func GetTrustedUsers(ctx context.Context, sess *session.Session) packet.Payload
 	}
 
 	trusteds := make([]snowflake.ID, 0, len(trustedRows))
-	trustedPublicKeys := make([]ed25519.PublicKey, 0, len(trustedRows))
+	trustedPublicKeys := make([]sshkey.PublicKey, 0, len(trustedRows))
 
 	for _, row := range trustedRows {
 		trusteds = append(trusteds, row.TrustedUserID)
@@ -1373,9 +1964,16 @@ func SetLastReadMessages(ctx context.Context, sess *session.Session, request *pa
 	queries := data.New(db)
 	qtx := queries.WithTx(tx)
 
+	self, err := qtx.GetUserById(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	receipts := map[snowflake.ID]int64{} // key is receiver id
+
 	// OPTIMIZE: Convert this loop into a SQL query
 	for i := 0; i < len(request.Source); i++ {
-		_, err := qtx.GetUserById(ctx, request.Source[i])
+		user, err := qtx.GetUserById(ctx, request.Source[i])
 		if err == nil {
 			// ID is signal
 			err = qtx.SetLastReadMessage(ctx, data.SetLastReadMessageParams{
@@ -1387,6 +1985,9 @@ func SetLastReadMessages(ctx context.Context, sess *session.Session, request *pa
 				slog.ErrorContext(ctx, "database error", "error", err)
 				return &ErrInternalError
 			}
+			if self.IsReadReceipts && user.IsReadReceipts {
+				receipts[user.ID] = request.LastRead[i]
+			}
 			continue
 		}
 		if err != sql.ErrNoRows {
@@ -1394,11 +1995,33 @@ func SetLastReadMessages(ctx context.Context, sess *session.Session, request *pa
 			return &ErrInternalError
 		}
 
+		members, err := qtx.GetGroupSignalMembers(ctx, request.Source[i])
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		if len(members) != 0 {
+			// ID is group signal
+			if !slices.Contains(members, sess.ID()) {
+				return &ErrPermissionDenied
+			}
+			err = qtx.SetLastReadMessage(ctx, data.SetLastReadMessageParams{
+				UserID:   sess.ID(),
+				SourceID: request.Source[i],
+				LastRead: request.LastRead[i],
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "database error", "error", err)
+				return &ErrInternalError
+			}
+			continue
+		}
+
 		// ID is frequency
 		frequency, err := qtx.GetFrequencyById(ctx, request.Source[i])
 		if err == sql.ErrNoRows {
 			return &packet.Error{Error: fmt.Sprintf(
-				"source at %v is not a valid frequency or user id", i,
+				"source at %v is not a valid frequency, user or group id", i,
 			)}
 		}
 		if err != nil {
@@ -1434,6 +2057,14 @@ func SetLastReadMessages(ctx context.Context, sess *session.Session, request *pa
 		return &ErrInternalError
 	}
 
+	for receiverId, lastRead := range receipts {
+		UserPropagate(ctx, sess, receiverId, &packet.ReadReceiptsInfo{
+			Users:        []snowflake.ID{sess.ID()},
+			LastRead:     []int64{lastRead},
+			RemovedUsers: nil,
+		}, false)
+	}
+
 	return &ErrSuccess
 }
 
@@ -1489,6 +2120,15 @@ func BlockUser(ctx context.Context, sess *session.Session, request *packet.Block
 			return &ErrInternalError
 		}
 
+		err = queries.DeleteMessageRequest(ctx, data.DeleteMessageRequestParams{
+			SenderID:   user.ID,
+			ReceiverID: sess.ID(),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
 		UserPropagate(ctx, sess, user.ID, &packet.BlockInfo{
 			BlockedUsers:         nil,
 			RemovedBlockedUsers:  nil,
@@ -1598,20 +2238,12 @@ func Authenticate(ctx context.Context, sess *session.Session, request *packet.Au
 		return &packet.Error{Error: "already authenticated"}
 	}
 
-	if len(request.PubKey) != ed25519.PublicKeySize {
-		return &packet.Error{Error: fmt.Sprintf(
-			"public key must be exactly %v bytes", ed25519.PublicKeySize,
-		)}
-	}
-
-	if len(request.Signature) != ed25519.SignatureSize {
-		return &packet.Error{Error: fmt.Sprintf(
-			"signature must be exactly %v bytes", ed25519.SignatureSize,
-		)}
+	if err := request.PubKey.Validate(); err != nil {
+		return &packet.Error{Error: err.Error()}
 	}
 
 	// IMPORTANT
-	if ok := ed25519.Verify(request.PubKey, sess.Challenge(), request.Signature); !ok {
+	if ok := sshkey.Verify(request.PubKey, sess.Challenge(), request.Signature); !ok {
 		return &packet.Error{Error: "signature verification failed"}
 	}
 
@@ -1620,6 +2252,13 @@ func Authenticate(ctx context.Context, sess *session.Session, request *packet.Au
 	queries := data.New(db)
 
 	user, err := queries.GetUserByPublicKey(ctx, request.PubKey)
+	if err == sql.ErrNoRows {
+		var device data.DeviceKey
+		device, err = queries.GetDeviceKeyByPublicKey(ctx, request.PubKey)
+		if err == nil {
+			user, err = queries.GetUserById(ctx, device.UserID)
+		}
+	}
 	if err == sql.ErrNoRows {
 		id := sess.Manager().Node().Generate()
 		user, err = queries.CreateUser(ctx, data.CreateUserParams{
@@ -1639,6 +2278,7 @@ func Authenticate(ctx context.Context, sess *session.Session, request *packet.Au
 	}
 
 	sess.Manager().AddSession(sess, user.ID, request.PubKey)
+	sess.SetLastActivity(time.Now())
 
 	// NOTE(kyren): as per the protocol, this must be the first message after auth
 	payload := &packet.UsersInfo{Users: []data.User{user}}
@@ -1654,6 +2294,8 @@ func Authenticate(ctx context.Context, sess *session.Session, request *packet.Au
 		return &packet.Error{Error: "timeout: not all initial auth packets were sent"}
 	}
 
+	updatePresence(ctx, sess, user, true, false)
+
 	return nil // manually writing requests to control order
 }
 
@@ -1662,10 +2304,16 @@ func sendInitialAuthPackets(ctx context.Context, sess *session.Session) bool {
 
 	payloads = append(payloads, GetUserData(ctx, sess, &packet.GetUserData{}))
 	payloads = append(payloads, GetTrustedUsers(ctx, sess))
+	payloads = append(payloads, GetKeyRotations(ctx, sess))
+	payloads = append(payloads, GetDevices(ctx, sess))
 	payloads = append(payloads, GetBlockedUsers(ctx, sess))
 	payloads = append(payloads, GetBlockedUsers(ctx, sess))
 	payloads = append(payloads, GetNetworksInfo(ctx, sess))
+	payloads = append(payloads, GetGroupSignalsInfo(ctx, sess))
+	payloads = append(payloads, GetMessageRequests(ctx, sess))
 	payloads = append(payloads, GetNotifications(ctx, sess))
+	payloads = append(payloads, GetPresences(ctx, sess))
+	payloads = append(payloads, GetReadReceipts(ctx, sess))
 
 	success := true
 	for _, payload := range payloads {
@@ -1685,7 +2333,8 @@ func sendInitialAuthPackets(ctx context.Context, sess *session.Session) bool {
 }
 
 var (
-	ipDeviceID map[uint32]string = map[uint32]string{}
+	// Key is the address block of the client, see [session.AddrPrefix]
+	ipDeviceID map[netip.Prefix]string = map[netip.Prefix]string{}
 	deviceIdMu sync.Mutex
 )
 
@@ -1706,7 +2355,7 @@ func DeviceAnalytics(ctx context.Context, sess *session.Session, request *packet
 		}
 	}
 
-	ip := binary.BigEndian.Uint32(sess.Addr().IP.To4())
+	ip := sess.AddrPrefix()
 	deviceIdMu.Lock()
 	if deviceId, ok := ipDeviceID[ip]; ok {
 		if request.DeviceID != deviceId {
@@ -1744,6 +2393,11 @@ func DeviceAnalytics(ctx context.Context, sess *session.Session, request *packet
 }
 
 func SetLastUserActivity(ctx context.Context, sess *session.Session) {
+	sess.SetLastActivity(time.Now())
+	if sess.Presence().State == packet.PresenceIdle {
+		UpdatePresence(ctx, sess, true) // No longer idle
+	}
+
 	queries := data.New(db)
 	now := time.Now().UnixMilli()
 	err := queries.UpdateUserLastActivity(ctx, data.UpdateUserLastActivityParams{
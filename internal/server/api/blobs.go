@@ -0,0 +1,264 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const uploadsDir = "uploads"
+
+var (
+	// Attachments are stored by their sha256 hash, so identical files
+	// are only stored once, and are never modified after being stored
+	BlobDir            = "blobs"
+	MaxAttachmentBytes = int64(8 * 1024 * 1024)
+
+	// Attachments that aren't sent in a message within the grace period
+	// are removed, along with attachments of deleted messages
+	AttachmentGracePeriod = 24 * time.Hour
+
+	// Held while storing a blob and creating its attachment, so garbage
+	// collection never removes a blob that is about to be referenced
+	blobsMu sync.Mutex
+)
+
+// SetupBlobStore creates the blob store and removes any leftover
+// uploads that didn't finish before the server shutdown.
+func SetupBlobStore() error {
+	uploads := filepath.Join(BlobDir, uploadsDir)
+	if err := os.RemoveAll(uploads); err != nil {
+		return err
+	}
+	return os.MkdirAll(uploads, 0750)
+}
+
+func blobPath(hash string) string {
+	return filepath.Join(BlobDir, hash[:2], hash)
+}
+
+func uploadPath(id snowflake.ID) string {
+	return filepath.Join(BlobDir, uploadsDir, id.String())
+}
+
+func blobExists(hash string) (bool, error) {
+	_, err := os.Stat(blobPath(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// isValidHash checks that the hash is a lowercase hex encoded sha256
+func isValidHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune(hex[:16], c) {
+			return false
+		}
+	}
+	return true
+}
+
+// AbortUpload discards the session's pending upload, if there is one
+func AbortUpload(ctx context.Context, sess *session.Session) {
+	upload := sess.SetUpload(nil)
+	if upload == nil {
+		return
+	}
+	_ = upload.File.Close()
+	if err := os.Remove(upload.File.Name()); err != nil {
+		slog.ErrorContext(ctx, "failed removing aborted upload", "error", err, "upload", upload.ID)
+	}
+}
+
+// finishUpload moves a fully written upload into the blob store
+func finishUpload(ctx context.Context, sess *session.Session, upload *session.Upload) packet.Payload {
+	if fmt.Sprintf("%x", upload.Hasher.Sum(nil)) != upload.Hash {
+		AbortUpload(ctx, sess)
+		return &packet.Error{Error: "attachment content doesn't match its hash"}
+	}
+
+	blobsMu.Lock()
+	defer blobsMu.Unlock()
+
+	// Identical content is only stored once, which is safe to do now
+	// that the client proved it has the content by uploading all of it
+	sess.SetUpload(nil)
+	exists, err := blobExists(upload.Hash)
+	if err == nil {
+		err = upload.File.Close()
+	}
+	if err == nil && exists {
+		err = os.Remove(upload.File.Name())
+	}
+	if err == nil && !exists {
+		err = os.MkdirAll(filepath.Dir(blobPath(upload.Hash)), 0750)
+		if err == nil {
+			err = os.Rename(upload.File.Name(), blobPath(upload.Hash))
+		}
+	}
+	if err != nil {
+		_ = upload.File.Close()
+		_ = os.Remove(upload.File.Name())
+		slog.ErrorContext(ctx, "failed storing attachment", "error", err, "upload", upload.ID)
+		return &ErrInternalError
+	}
+
+	queries := data.New(db)
+	attachment, err := queries.CreateAttachment(ctx, data.CreateAttachmentParams{
+		ID:         upload.ID,
+		UploaderID: sess.ID(),
+		Name:       upload.Name,
+		Size:       upload.Size,
+		Hash:       upload.Hash,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.AttachmentsInfo{
+		Attachments: []data.Attachment{withoutHash(attachment)},
+	}
+}
+
+// withoutHash clears the hash of an attachment that is sent to clients,
+// the hash is only used to locate the blob and is never exposed
+func withoutHash(attachment data.Attachment) data.Attachment {
+	attachment.Hash = ""
+	return attachment
+}
+
+// validateAttachment returns an error payload if the session's user
+// can't send a message with the given attachment, or nil if they can
+func validateAttachment(ctx context.Context, queries *data.Queries, sess *session.Session, id snowflake.ID) (data.Attachment, packet.Payload) {
+	attachment, err := queries.GetAttachmentById(ctx, id)
+	if err == sql.ErrNoRows {
+		return data.Attachment{}, &packet.Error{Error: "attachment doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return data.Attachment{}, &ErrInternalError
+	}
+	if attachment.UploaderID != sess.ID() {
+		return data.Attachment{}, &ErrPermissionDenied
+	}
+	return withoutHash(attachment), nil
+}
+
+// messageAttachments returns the attachments referenced by the messages
+func messageAttachments(ctx context.Context, queries *data.Queries, messages []data.Message) ([]data.Attachment, error) {
+	ids := []snowflake.ID{}
+	for _, message := range messages {
+		if message.AttachmentID != nil {
+			ids = append(ids, *message.AttachmentID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	attachments, err := queries.GetAttachmentsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i] = withoutHash(attachments[i])
+	}
+	return attachments, nil
+}
+
+func readBlobChunk(attachment data.Attachment, offset int64) ([]byte, error) {
+	file, err := os.Open(blobPath(attachment.Hash))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	chunk := make([]byte, min(packet.AttachmentChunkBytes, attachment.Size-offset))
+	if _, err := file.ReadAt(chunk, offset); err != nil {
+		return nil, fmt.Errorf("reading blob %v: %w", attachment.Hash, err)
+	}
+	return chunk, nil
+}
+
+// CollectGarbage removes attachments that aren't referenced by any message
+// after the grace period, and blobs that aren't referenced by any attachment
+func CollectGarbage(ctx context.Context) {
+	queries := data.New(db)
+
+	before := snowflake.FromTime(time.Now().Add(-AttachmentGracePeriod).UnixMilli())
+	attachments, err := queries.DeleteUnreferencedAttachments(ctx, before)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+
+	removed := 0
+	err = filepath.WalkDir(BlobDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == uploadsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isValidHash(entry.Name()) {
+			return nil
+		}
+
+		blobsMu.Lock()
+		defer blobsMu.Unlock()
+
+		referenced, err := queries.IsBlobReferenced(ctx, entry.Name())
+		if err != nil || referenced {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed collecting blobs", "error", err)
+	}
+
+	slog.InfoContext(ctx, "collected attachment garbage",
+		"attachments", attachments, "blobs", removed,
+	)
+}
@@ -0,0 +1,227 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
+)
+
+func AuthorizeDevice(ctx context.Context, sess *session.Session, request *packet.AuthorizeDevice) packet.Payload {
+	if err := request.PubKey.Validate(); err != nil {
+		return &packet.Error{Error: err.Error()}
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return &packet.Error{Error: "device name cannot be empty"}
+	}
+	if len(name) > packet.MaxDeviceNameBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"device name bytes may not exceed %v bytes",
+			packet.MaxDeviceNameBytes,
+		)}
+	}
+
+	payload := packet.DeviceSignaturePayload(sess.ID(), request.PubKey)
+	if !sshkey.Verify(sess.PubKey(), payload, request.Signature) {
+		return &packet.Error{Error: "device must be signed by the current key"}
+	}
+	if !sshkey.Verify(request.PubKey, payload, request.NewSignature) {
+		return &packet.Error{Error: "device must be signed by the device key"}
+	}
+
+	queries := data.New(db)
+
+	if taken, errPayload := isPublicKeyTaken(ctx, queries, request.PubKey); errPayload != nil {
+		return errPayload
+	} else if taken {
+		return &packet.Error{Error: "public key is already taken"}
+	}
+
+	devices, err := queries.GetUserDeviceKeys(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if len(devices) >= packet.MaxDevices {
+		return &packet.Error{Error: fmt.Sprintf(
+			"an account may not have more than %v devices",
+			packet.MaxDevices,
+		)}
+	}
+
+	device, err := queries.CreateDeviceKey(ctx, data.CreateDeviceKeyParams{
+		ID:           sess.Manager().Node().Generate(),
+		UserID:       sess.ID(),
+		PublicKey:    request.PubKey,
+		Name:         name,
+		AuthorizedBy: sess.PubKey(),
+		Signature:    request.Signature,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	info := &packet.DevicesInfo{
+		Devices:        []data.DeviceKey{device},
+		RemovedDevices: nil,
+	}
+	trustingPropagate(ctx, sess, queries, info)
+	return info
+}
+
+func RevokeDevice(ctx context.Context, sess *session.Session, request *packet.RevokeDevice) packet.Payload {
+	queries := data.New(db)
+
+	device, err := queries.GetDeviceKeyById(ctx, data.GetDeviceKeyByIdParams{
+		ID:     request.Device,
+		UserID: sess.ID(),
+	})
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "device doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	devices, err := queries.GetUserDeviceKeys(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	// Devices authorized by a revoked device are revoked too
+	revoked := []data.DeviceKey{device}
+	for i := 0; i < len(revoked); i++ {
+		for _, other := range devices {
+			if bytes.Equal(other.AuthorizedBy, revoked[i].PublicKey) {
+				revoked = append(revoked, other)
+			}
+		}
+	}
+
+	isRevoked := func(publicKey sshkey.PublicKey) bool {
+		return slices.ContainsFunc(revoked, func(device data.DeviceKey) bool {
+			return bytes.Equal(device.PublicKey, publicKey)
+		})
+	}
+	if isRevoked(sess.PubKey()) {
+		return &packet.Error{Error: "cannot revoke the device you are signed in with"}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := queries.WithTx(tx)
+
+	removed := make([]snowflake.ID, 0, len(revoked))
+	for _, device := range revoked {
+		err = qtx.DeleteDeviceKey(ctx, device.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		removed = append(removed, device.ID)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	for _, s := range sess.Manager().Sessions(sess.ID()) {
+		if isRevoked(s.PubKey()) {
+			s.Evict(packet.ErrDeviceRevoked)
+		}
+	}
+
+	info := &packet.DevicesInfo{
+		Devices:        nil,
+		RemovedDevices: removed,
+	}
+	trustingPropagate(ctx, sess, queries, info)
+	return info
+}
+
+func GetDevices(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+
+	devices, err := queries.GetDeviceKeys(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.DevicesInfo{
+		Devices:        devices,
+		RemovedDevices: nil,
+	}
+}
+
+// trustingPropagate sends the payload to all users who trust the session's user
+func trustingPropagate(ctx context.Context, sess *session.Session, queries *data.Queries, payload packet.Payload) {
+	trustingUsers, err := queries.GetTrustingUsers(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+	for _, trustingUser := range trustingUsers {
+		UserPropagate(ctx, sess, trustingUser, payload, false)
+	}
+}
+
+// isPublicKeyTaken returns whether the public key belongs
+// to any user, either as their account key or as a device key
+func isPublicKeyTaken(ctx context.Context, queries *data.Queries, publicKey sshkey.PublicKey) (bool, packet.Payload) {
+	_, err := queries.GetUserByPublicKey(ctx, publicKey)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return false, &ErrInternalError
+	}
+
+	_, err = queries.GetDeviceKeyByPublicKey(ctx, publicKey)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return false, &ErrInternalError
+	}
+
+	return false, nil
+}
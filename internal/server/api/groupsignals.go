@@ -0,0 +1,433 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+func CreateGroupSignal(ctx context.Context, sess *session.Session, request *packet.CreateGroupSignal) packet.Payload {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return &packet.Error{Error: "group name must not be blank"}
+	}
+	if len(name) > packet.MaxGroupSignalNameBytes {
+		return &packet.Error{Error: fmt.Sprintf(
+			"group name must not exceed %v bytes",
+			packet.MaxGroupSignalNameBytes,
+		)}
+	}
+
+	members := []snowflake.ID{sess.ID()}
+	for _, user := range request.Users {
+		if !slices.Contains(members, user) {
+			members = append(members, user)
+		}
+	}
+	if len(members) < packet.MinGroupSignalMembers || len(members) > packet.MaxGroupSignalMembers {
+		return &packet.Error{Error: fmt.Sprintf(
+			"group must have between %v and %v members",
+			packet.MinGroupSignalMembers, packet.MaxGroupSignalMembers,
+		)}
+	}
+
+	queries := data.New(db)
+
+	users, err := queries.GetUsersByIds(ctx, members)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if len(users) != len(members) {
+		return &packet.Error{Error: "user doesn't exist"}
+	}
+	for i, user := range users {
+		if user.IsDeleted {
+			return &packet.Error{Error: "user doesn't exist"}
+		}
+		for _, other := range users[i+1:] {
+			if errPayload := validateGroupSignalPair(ctx, queries, user, other); errPayload != nil {
+				return errPayload
+			}
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := queries.WithTx(tx)
+
+	group, err := qtx.CreateGroupSignal(ctx, data.CreateGroupSignalParams{
+		ID:   sess.Manager().Node().Generate(),
+		Name: name,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	for _, member := range members {
+		err = qtx.AddGroupSignalMember(ctx, data.AddGroupSignalMemberParams{
+			GroupID: group.ID,
+			UserID:  member,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	payload := &packet.GroupSignalsInfo{
+		Groups: []packet.FullGroupSignal{{
+			GroupSignal: group,
+			Members:     members,
+			Users:       users,
+		}},
+		RemovedGroups: nil,
+	}
+	return GroupSignalPropagate(ctx, sess, members, payload)
+}
+
+func AddGroupSignalMember(ctx context.Context, sess *session.Session, request *packet.AddGroupSignalMember) packet.Payload {
+	queries := data.New(db)
+
+	group, err := queries.GetGroupSignalById(ctx, request.Group)
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "group doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	members, err := queries.GetGroupSignalMembers(ctx, group.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if !slices.Contains(members, sess.ID()) {
+		return &ErrPermissionDenied
+	}
+	if slices.Contains(members, request.User) {
+		return &packet.Error{Error: "user is already a member"}
+	}
+	if len(members) >= packet.MaxGroupSignalMembers {
+		return &packet.Error{Error: fmt.Sprintf(
+			"group must not exceed %v members", packet.MaxGroupSignalMembers,
+		)}
+	}
+
+	user, err := queries.GetUserById(ctx, request.User)
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "user doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	users, err := queries.GetUsersByIds(ctx, members)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	for _, member := range users {
+		if errPayload := validateGroupSignalPair(ctx, queries, user, member); errPayload != nil {
+			return errPayload
+		}
+	}
+
+	err = queries.AddGroupSignalMember(ctx, data.AddGroupSignalMemberParams{
+		GroupID: group.ID,
+		UserID:  user.ID,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	members = append(members, user.ID)
+	users = append(users, user)
+	payload := &packet.GroupSignalsInfo{
+		Groups: []packet.FullGroupSignal{{
+			GroupSignal: group,
+			Members:     members,
+			Users:       users,
+		}},
+		RemovedGroups: nil,
+	}
+	return GroupSignalPropagate(ctx, sess, members, payload)
+}
+
+func LeaveGroupSignal(ctx context.Context, sess *session.Session, request *packet.LeaveGroupSignal) packet.Payload {
+	queries := data.New(db)
+
+	group, err := queries.GetGroupSignalById(ctx, request.Group)
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "group doesn't exist"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	members, err := queries.GetGroupSignalMembers(ctx, group.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if !slices.Contains(members, sess.ID()) {
+		return &ErrPermissionDenied
+	}
+	members = slices.DeleteFunc(members, func(member snowflake.ID) bool {
+		return member == sess.ID()
+	})
+
+	if len(members) == 0 {
+		// Last member left, messages are deleted with the group
+		err = queries.DeleteGroupSignal(ctx, group.ID)
+	} else {
+		err = queries.RemoveGroupSignalMember(ctx, data.RemoveGroupSignalMemberParams{
+			GroupID: group.ID,
+			UserID:  sess.ID(),
+		})
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	if len(members) != 0 {
+		users, err := queries.GetUsersByIds(ctx, members)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		GroupSignalPropagate(ctx, sess, members, &packet.GroupSignalsInfo{
+			Groups: []packet.FullGroupSignal{{
+				GroupSignal: group,
+				Members:     members,
+				Users:       users,
+			}},
+			RemovedGroups: nil,
+		})
+	}
+
+	return &packet.GroupSignalsInfo{
+		Groups:        nil,
+		RemovedGroups: []snowflake.ID{group.ID},
+	}
+}
+
+// GetGroupSignalsInfo returns all group signals the session's user is in
+func GetGroupSignalsInfo(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+
+	groups, err := queries.GetUserGroupSignals(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	fullGroups := make([]packet.FullGroupSignal, 0, len(groups))
+	for _, group := range groups {
+		members, err := queries.GetGroupSignalMembers(ctx, group.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		users, err := queries.GetUsersByIds(ctx, members)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		fullGroups = append(fullGroups, packet.FullGroupSignal{
+			GroupSignal: group,
+			Members:     members,
+			Users:       users,
+		})
+	}
+
+	return &packet.GroupSignalsInfo{
+		Groups:        fullGroups,
+		RemovedGroups: nil,
+	}
+}
+
+// GroupSignalPropagate sends the payload to all the given members
+// other than the session's user, returning the payload
+func GroupSignalPropagate(
+	ctx context.Context, sess *session.Session,
+	members []snowflake.ID, payload packet.Payload,
+) packet.Payload {
+	for _, member := range members {
+		if member != sess.ID() {
+			UserPropagate(ctx, sess, member, payload, false)
+		}
+	}
+	return payload
+}
+
+// ChatPropagate sends the payload to the other user of a direct message,
+// or to all other members of a group message
+func ChatPropagate(
+	ctx context.Context, sess *session.Session,
+	message data.Message, payload packet.Payload,
+) packet.Payload {
+	if message.GroupID != nil {
+		queries := data.New(db)
+		members, err := queries.GetGroupSignalMembers(ctx, *message.GroupID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		return GroupSignalPropagate(ctx, sess, members, payload)
+	}
+
+	otherUser := *message.ReceiverID
+	if otherUser == sess.ID() {
+		otherUser = message.SenderID
+	}
+	return UserPropagate(ctx, sess, otherUser, payload, false)
+}
+
+// groupSignalMembers returns the members of the group signal,
+// or an error payload if it doesn't exist or the session's user
+// is not a member of it
+func groupSignalMembers(ctx context.Context, queries *data.Queries, sess *session.Session, group snowflake.ID) ([]snowflake.ID, packet.Payload) {
+	members, err := queries.GetGroupSignalMembers(ctx, group)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return nil, &ErrInternalError
+	}
+	if len(members) == 0 {
+		return nil, &packet.Error{Error: "user or group doesn't exist"}
+	}
+	if !slices.Contains(members, sess.ID()) {
+		return nil, &ErrPermissionDenied
+	}
+	return members, nil
+}
+
+// validateGroupSignalPair returns an error payload if the two users
+// may not share a group signal, or nil if they may.
+// Neither may block the other, and a user with private DMs must trust the other.
+func validateGroupSignalPair(ctx context.Context, queries *data.Queries, a, b data.User) packet.Payload {
+	for _, pair := range [][2]data.User{{a, b}, {b, a}} {
+		user, other := pair[0], pair[1]
+
+		_, err := queries.IsUserBlocked(ctx, data.IsUserBlockedParams{
+			BlockingUserID: user.ID,
+			BlockedUserID:  other.ID,
+		})
+		if err == nil {
+			return &ErrPermissionDenied
+		}
+		if err != sql.ErrNoRows {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+
+		if user.IsPublicDM {
+			continue
+		}
+
+		pubKey, err := queries.GetTrustedPublicKey(ctx, data.GetTrustedPublicKeyParams{
+			TrustingUserID: user.ID,
+			TrustedUserID:  other.ID,
+		})
+		if err == sql.ErrNoRows {
+			return &ErrPermissionDenied
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+		if !bytes.Equal(other.PublicKey, pubKey) {
+			return &ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
+
+func sendGroupSignalMessage(
+	ctx context.Context, sess *session.Session, queries *data.Queries,
+	request *packet.SendMessage, content string, attachments []data.Attachment,
+) packet.Payload {
+	if request.Encrypted {
+		return &packet.Error{Error: "group signals may not be encrypted"}
+	}
+
+	members, errPayload := groupSignalMembers(ctx, queries, sess, *request.ReceiverID)
+	if errPayload != nil {
+		return errPayload
+	}
+
+	message, err := queries.CreateMessage(ctx, data.CreateMessageParams{
+		ID:           sess.Manager().Node().Generate(),
+		Content:      content,
+		SenderID:     sess.ID(),
+		FrequencyID:  nil,
+		ReceiverID:   nil,
+		GroupID:      request.ReceiverID,
+		AttachmentID: request.Attachment,
+		Signature:    request.Signature,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	for _, member := range members {
+		err = queries.InsertLastReadMessage(ctx, data.InsertLastReadMessageParams{
+			UserID:   member,
+			SourceID: *request.ReceiverID,
+			LastRead: 0,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+	}
+
+	return GroupSignalPropagate(ctx, sess, members, &packet.MessagesInfo{
+		Messages:        []data.Message{message},
+		RemovedMessages: nil,
+		Attachments:     attachments,
+	})
+}
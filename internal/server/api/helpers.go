@@ -17,7 +17,11 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
 	"log/slog"
 	"slices"
 	"strings"
@@ -28,6 +32,7 @@ import (
 	"github.com/kyren223/eko/internal/server/ctxkeys"
 	"github.com/kyren223/eko/internal/server/session"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 const hex = "0123456789abcdefABCDEF"
@@ -68,39 +73,30 @@ func NetworkPropagateWithFilter(
 	network snowflake.ID, payload packet.Payload,
 	filter func(userId snowflake.ID) (pass bool),
 ) packet.Payload {
-	var sessions []snowflake.ID
-	sess.Manager().UseSessions(func(s map[snowflake.ID]*session.Session) {
-		sessions = make([]snowflake.ID, 0, len(s)-1)
+	var users []snowflake.ID
+	sess.Manager().UseSessions(func(s map[snowflake.ID][]*session.Session) {
+		users = make([]snowflake.ID, 0, len(s)-1)
 		for key := range s {
 			if key != sess.ID() && filter(key) {
-				sessions = append(sessions, key)
+				users = append(users, key)
 			}
 		}
 	})
 
 	queries := data.New(db)
-	sessions, err := queries.FilterUsersInNetwork(ctx, data.FilterUsersInNetworkParams{
+	users, err := queries.FilterUsersInNetwork(ctx, data.FilterUsersInNetworkParams{
 		NetworkID: network,
-		Users:     sessions,
+		Users:     users,
 	})
 	if err != nil {
 		slog.ErrorContext(ctx, "database error", "error", err)
 		return &ErrInternalError
 	}
 
-	for _, sessionId := range sessions {
-		session := sess.Manager().Session(sessionId)
-		if session == nil {
-			continue
+	for _, userId := range users {
+		for _, session := range sess.Manager().Sessions(userId) {
+			propagate(ctx, session, payload)
 		}
-		timeout := 1 * time.Second
-		context, cancel := context.WithTimeout(context.Background(), timeout)
-		go func() {
-			defer cancel()
-			if ok := session.Write(context, payload); !ok {
-				slog.ErrorContext(ctx, "propagation failed", "session", session.LogValue(), "reason", "write failed")
-			}
-		}()
 	}
 
 	return payload
@@ -131,8 +127,8 @@ func UserPropagate(
 	userId snowflake.ID, payload packet.Payload,
 	errorOnNil bool,
 ) packet.Payload {
-	session := sess.Manager().Session(userId)
-	if session == nil {
+	sessions := sess.Manager().Sessions(userId)
+	if len(sessions) == 0 {
 		if errorOnNil {
 			slog.ErrorContext(ctx, "propagation failed", ctxkeys.UserID.String(), userId, "reason", "session is nil")
 		} else {
@@ -140,6 +136,25 @@ func UserPropagate(
 		}
 		return payload
 	}
+	for _, session := range sessions {
+		propagate(ctx, session, payload)
+	}
+
+	return payload
+}
+
+// DevicesPropagate sends the payload to the other sessions of the session's
+// user, so changes made on one device show up on the user's other devices
+func DevicesPropagate(ctx context.Context, sess *session.Session, payload packet.Payload) packet.Payload {
+	for _, session := range sess.Manager().Sessions(sess.ID()) {
+		if session != sess {
+			propagate(ctx, session, payload)
+		}
+	}
+	return payload
+}
+
+func propagate(ctx context.Context, session *session.Session, payload packet.Payload) {
 	timeout := 1 * time.Second
 	context, cancel := context.WithTimeout(context.Background(), timeout)
 	go func() {
@@ -148,8 +163,6 @@ func UserPropagate(
 			slog.ErrorContext(ctx, "propagation failed", "session", session.LogValue(), "reason", "write failed")
 		}
 	}()
-
-	return payload
 }
 
 const getNotificationsQuery = `-- name: GetNotifications :many
@@ -172,8 +185,10 @@ SELECT
   e.source_id, e.last_read,
   CASE
     WHEN COUNT(m.id) = 0 THEN NULL
-    ELSE SUM(CASE WHEN (m.frequency_id IS NULL OR
-	m.ping = 0 OR (m.ping = 1 AND pf.is_admin = true) OR m.ping = ?) THEN 1 ELSE 0 END)
+    ELSE SUM(CASE WHEN (m.frequency_id IS NULL OR EXISTS (
+      SELECT 1 FROM message_mentions mm WHERE mm.message_id = m.id AND
+      (mm.mention = 0 OR (mm.mention = 1 AND pf.is_admin = true) OR mm.mention = ?)
+    )) THEN 1 ELSE 0 END)
 	-- 0 is @everyone, 1 is @admins, otherwise it's user_id
   END AS pings
 FROM entries e
@@ -181,13 +196,16 @@ LEFT JOIN permitted_frequencies pf ON e.source_id = pf.id
 LEFT JOIN messages m ON m.id > e.last_read
   AND ((m.frequency_id = e.source_id AND pf.id IS NOT NULL) OR
     (m.receiver_id = e.source_id AND m.sender_id = ?) OR
-    (m.sender_id = e.source_id AND m.receiver_id = ?))
+    (m.sender_id = e.source_id AND m.receiver_id = ?) OR
+    (m.group_id = e.source_id AND e.source_id IN (
+      SELECT group_id FROM group_signal_members WHERE user_id = ?
+    )))
 GROUP BY e.source_id, e.last_read;
 `
 
 func getNotifications(ctx context.Context, userId snowflake.ID) (packet.NotificationsInfo, error) {
 	query := getNotificationsQuery
-	rows, err := db.QueryContext(ctx, query, userId, userId, userId, userId, userId)
+	rows, err := db.QueryContext(ctx, query, userId, userId, userId, userId, userId, userId)
 	if err != nil {
 		return packet.NotificationsInfo{}, err
 	}
@@ -213,6 +231,216 @@ func getNotifications(ctx context.Context, userId snowflake.ID) (packet.Notifica
 	return items, nil
 }
 
+// validateMentions returns an error payload if any of the mentions
+// is not allowed, or nil if all of them are valid.
+// Mentioned users must be members of the network
+func validateMentions(ctx context.Context, queries *data.Queries, networkId snowflake.ID, mentions []snowflake.ID, isAdmin bool) packet.Payload {
+	if len(mentions) > packet.MaxMentions {
+		return &packet.Error{Error: fmt.Sprintf(
+			"message must not contain more than %v mentions",
+			packet.MaxMentions,
+		)}
+	}
+
+	users := make([]snowflake.ID, 0, len(mentions))
+	for _, mention := range mentions {
+		switch mention {
+		case packet.PingEveryone:
+			if !isAdmin {
+				return &packet.Error{Error: "only admins can mention everyone"}
+			}
+		case packet.PingAdmins:
+		default:
+			users = append(users, mention)
+		}
+	}
+
+	if len(users) == 0 {
+		return nil
+	}
+
+	existingUsers, err := queries.GetUsersByIds(ctx, users)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	for _, user := range existingUsers {
+		if user.IsDeleted {
+			return &packet.Error{Error: "mentioned user doesn't exist"}
+		}
+	}
+	if len(existingUsers) != len(users) {
+		return &packet.Error{Error: "mentioned user doesn't exist"}
+	}
+
+	for _, user := range users {
+		member, err := queries.GetMemberById(ctx, data.GetMemberByIdParams{
+			NetworkID: networkId,
+			UserID:    user,
+		})
+		if err == sql.ErrNoRows || (err == nil && !member.IsMember) {
+			return &packet.Error{Error: "mentioned user is not a member of this network"}
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database error", "error", err)
+			return &ErrInternalError
+		}
+	}
+
+	return nil
+}
+
+// firstMention returns the first mention, stored as the message's ping
+// for older clients, or nil if there are none
+func firstMention(mentions []snowflake.ID) *snowflake.ID {
+	if len(mentions) == 0 {
+		return nil
+	}
+	return &mentions[0]
+}
+
+// validateDirectMessage returns an error payload if the session's user
+// is not allowed to message the given user, or nil if they are
+func validateDirectMessage(ctx context.Context, queries *data.Queries, sess *session.Session, user data.User) packet.Payload {
+	if errPayload := validateNotBlocked(ctx, queries, sess, user); errPayload != nil {
+		return errPayload
+	}
+
+	allowed, errPayload := isDirectMessageAllowed(ctx, queries, sess, user)
+	if errPayload != nil {
+		return errPayload
+	}
+	if !allowed {
+		return &ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// validateNotBlocked returns an error payload if either the session's user
+// or the given user blocked the other
+func validateNotBlocked(ctx context.Context, queries *data.Queries, sess *session.Session, user data.User) packet.Payload {
+	// Session user blocked the user he tried to message
+	_, err := queries.IsUserBlocked(ctx, data.IsUserBlockedParams{
+		BlockingUserID: sess.ID(),
+		BlockedUserID:  user.ID,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if err != sql.ErrNoRows {
+		// Can't message a user if you blocked them
+		return &ErrPermissionDenied
+	}
+
+	// Session user was blocked by the user they tried to message
+	_, err = queries.IsUserBlocked(ctx, data.IsUserBlockedParams{
+		BlockingUserID: user.ID,
+		BlockedUserID:  sess.ID(),
+	})
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	if err != sql.ErrNoRows {
+		// Can't message a user if they blocked you
+		return &ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// isDirectMessageAllowed returns whether the given user accepts direct
+// messages from the session's user, either because their DMs are public
+// or because they trust the session's user's current account key
+func isDirectMessageAllowed(ctx context.Context, queries *data.Queries, sess *session.Session, user data.User) (bool, packet.Payload) {
+	if user.IsPublicDM {
+		return true, nil
+	}
+
+	pubKey, err := queries.GetTrustedPublicKey(ctx, data.GetTrustedPublicKeyParams{
+		TrustingUserID: user.ID,
+		TrustedUserID:  sess.ID(),
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return false, &ErrInternalError
+	}
+
+	// Trust pins the account key, device sessions sign in with a device key
+	// so the session's key can't be compared directly
+	sender, err := queries.GetUserById(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return false, &ErrInternalError
+	}
+	return bytes.Equal(sender.PublicKey, pubKey), nil
+}
+
+// validateSignature returns an error payload if the message has a signature
+// that wasn't made by the session's key over the message
+func validateSignature(sess *session.Session, chat snowflake.ID, attachment *snowflake.ID, content string, signature []byte) packet.Payload {
+	if signature == nil {
+		return nil
+	}
+	payload := packet.MessageSignaturePayload(chat, attachment, content)
+	if !sshkey.Verify(sess.PubKey(), payload, signature) {
+		return &packet.Error{Error: "invalid message signature"}
+	}
+	return nil
+}
+
+// validateCiphertext returns an error payload if the content
+// of an encrypted message is not valid base64 encoded ciphertext
+func validateCiphertext(content string) packet.Payload {
+	ciphertext, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return &packet.Error{Error: "encrypted message content must be base64 encoded"}
+	}
+	if len(ciphertext) <= packet.EncryptionOverheadBytes {
+		return &packet.Error{Error: "encrypted message content is too short"}
+	}
+	return nil
+}
+
+// viewers returns the users, other than the session's, that are viewing
+// the given frequency, or the DM with the session's user
+func viewers(sess *session.Session, chat snowflake.ID) []snowflake.ID {
+	var users []snowflake.ID
+	sess.Manager().UseSessions(func(sessions map[snowflake.ID][]*session.Session) {
+		for id, userSessions := range sessions {
+			if id == sess.ID() {
+				continue
+			}
+			for _, s := range userSessions {
+				viewing := s.Viewing()
+				if viewing != nil && *viewing == chat {
+					users = append(users, id)
+					break
+				}
+			}
+		}
+	})
+	return users
+}
+
+func insertMentions(ctx context.Context, queries *data.Queries, messageId snowflake.ID, mentions []snowflake.ID) error {
+	for _, mention := range mentions {
+		err := queries.InsertMessageMention(ctx, data.InsertMessageMentionParams{
+			MessageID: messageId,
+			Mention:   mention,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var (
 	ValidOs        = []string{"linux", "darwin", "windows", "android", ""}
 	ValidArch      = []string{"amd64", "arm64", "386", ""}
@@ -220,6 +448,20 @@ var (
 	ValidColorterm = []string{"truecolor", "24bit", ""}
 )
 
+// ftsQuery converts a user's search query into an FTS5 query.
+// Each word is quoted so the FTS5 query syntax can't be used (or broken),
+// and the last word is a prefix so results show up while typing.
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	if len(words) != 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
 func IsValidAnalytics(ctx context.Context, analytics *packet.DeviceAnalytics) bool {
 	if analytics == nil {
 		return false
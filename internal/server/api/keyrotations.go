@@ -0,0 +1,133 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/sshkey"
+)
+
+func RotateKey(ctx context.Context, sess *session.Session, request *packet.RotateKey) packet.Payload {
+	if err := request.PubKey.Validate(); err != nil {
+		return &packet.Error{Error: err.Error()}
+	}
+
+	oldKey := sess.PubKey()
+	if bytes.Equal(oldKey, request.PubKey) {
+		return &packet.Error{Error: "new public key must differ from the current one"}
+	}
+
+	payload := packet.KeyRotationSignaturePayload(sess.ID(), oldKey, request.PubKey)
+	if !sshkey.Verify(oldKey, payload, request.Signature) {
+		return &packet.Error{Error: "key rotation must be signed by the current key"}
+	}
+	if !sshkey.Verify(request.PubKey, payload, request.NewSignature) {
+		return &packet.Error{Error: "key rotation must be signed by the new key"}
+	}
+
+	queries := data.New(db)
+
+	_, err := queries.GetDeviceKeyByPublicKey(ctx, oldKey)
+	if err == nil {
+		return &packet.Error{Error: "only the account key may be rotated, not a device key"}
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	if taken, errPayload := isPublicKeyTaken(ctx, queries, request.PubKey); errPayload != nil {
+		return errPayload
+	} else if taken {
+		return &packet.Error{Error: "public key is already taken"}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := queries.WithTx(tx)
+
+	user, err := qtx.RotateUserPublicKey(ctx, data.RotateUserPublicKeyParams{
+		NewPublicKey: request.PubKey,
+		ID:           sess.ID(),
+		OldPublicKey: oldKey,
+	})
+	if err == sql.ErrNoRows {
+		return &packet.Error{Error: "public key was already rotated"}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	rotation, err := qtx.CreateKeyRotation(ctx, data.CreateKeyRotationParams{
+		ID:           sess.Manager().Node().Generate(),
+		UserID:       sess.ID(),
+		OldPublicKey: oldKey,
+		NewPublicKey: request.PubKey,
+		Signature:    request.Signature,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	sess.SetPubKey(request.PubKey)
+	slog.InfoContext(ctx, "public key rotated")
+
+	trustingUsers, err := queries.GetTrustingUsers(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &packet.UsersInfo{Users: []data.User{user}}
+	}
+
+	info := &packet.KeyRotationsInfo{Rotations: []data.KeyRotation{rotation}}
+	UserPropagate(ctx, sess, sess.ID(), info, true)
+	for _, trustingUser := range trustingUsers {
+		UserPropagate(ctx, sess, trustingUser, info, false)
+	}
+
+	return &packet.UsersInfo{Users: []data.User{user}}
+}
+
+func GetKeyRotations(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+
+	rotations, err := queries.GetKeyRotations(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.KeyRotationsInfo{Rotations: rotations}
+}
@@ -0,0 +1,154 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+// sendMessageRequest sends the message as a message request to a user who
+// doesn't accept direct messages from the session's user.
+// Only the first message is sent until the request is accepted.
+func sendMessageRequest(
+	ctx context.Context, sess *session.Session, queries *data.Queries, user data.User,
+	request *packet.SendMessage, content string, attachments []data.Attachment,
+) packet.Payload {
+	_, err := queries.GetMessageRequest(ctx, data.GetMessageRequestParams{
+		SenderID:   sess.ID(),
+		ReceiverID: user.ID,
+	})
+	if err == nil {
+		// Ignored requests look pending so the sender doesn't know
+		return &packet.Error{Error: fmt.Sprintf(
+			"%v didn't accept your message request yet", user.Name,
+		)}
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	self, err := queries.GetUserById(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := queries.WithTx(tx)
+
+	message, err := qtx.CreateMessage(ctx, data.CreateMessageParams{
+		ID:           sess.Manager().Node().Generate(),
+		Content:      content,
+		SenderID:     sess.ID(),
+		FrequencyID:  nil,
+		ReceiverID:   &user.ID,
+		AttachmentID: request.Attachment,
+		Signature:    request.Signature,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	err = qtx.CreateMessageRequest(ctx, data.CreateMessageRequestParams{
+		SenderID:   sess.ID(),
+		ReceiverID: user.ID,
+		MessageID:  message.ID,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	UserPropagate(ctx, sess, user.ID, &packet.MessageRequestsInfo{
+		Requests:        []data.Message{message},
+		Users:           []data.User{self},
+		RemovedRequests: nil,
+	}, false)
+
+	return &packet.MessagesInfo{
+		Messages:        []data.Message{message},
+		RemovedMessages: nil,
+		Attachments:     attachments,
+	}
+}
+
+func IgnoreMessageRequest(ctx context.Context, sess *session.Session, request *packet.IgnoreMessageRequest) packet.Payload {
+	queries := data.New(db)
+
+	err := queries.IgnoreMessageRequest(ctx, data.IgnoreMessageRequestParams{
+		SenderID:   request.User,
+		ReceiverID: sess.ID(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.MessageRequestsInfo{
+		Requests:        nil,
+		Users:           nil,
+		RemovedRequests: []snowflake.ID{request.User},
+	}
+}
+
+// GetMessageRequests returns all pending message requests sent to the session's user
+func GetMessageRequests(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+
+	requests, err := queries.GetMessageRequests(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	senders := make([]snowflake.ID, 0, len(requests))
+	for _, request := range requests {
+		senders = append(senders, request.SenderID)
+	}
+	users, err := queries.GetUsersByIds(ctx, senders)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	return &packet.MessageRequestsInfo{
+		Requests:        requests,
+		Users:           users,
+		RemovedRequests: nil,
+	}
+}
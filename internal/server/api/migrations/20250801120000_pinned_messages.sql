@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE messages ADD COLUMN pinned_at INTEGER DEFAULT NULL;
+-- Unix millis of when the message was pinned, null if not pinned
+
+-- +goose Down
+ALTER TABLE messages DROP COLUMN pinned_at;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS message_mentions (
+  message_id INT NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+  mention INT NOT NULL,
+  -- 0 - @everyone, 1 - @admins, otherwise references userId
+  PRIMARY KEY (message_id, mention)
+);
+
+INSERT INTO message_mentions (message_id, mention)
+SELECT id, ping FROM messages WHERE ping IS NOT NULL;
+
+-- Mentions are now part of the message content, older messages are left
+-- untouched and clients render their ping as a mention instead.
+-- ping is kept for older clients, it holds the first mention
+DROP TRIGGER IF EXISTS on_message_delete;
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_message_delete
+AFTER DELETE ON messages
+BEGIN
+  DELETE FROM message_mentions WHERE message_id = OLD.id;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS on_message_delete;
+DROP TABLE IF EXISTS message_mentions;
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5 (
+  content,
+  content = 'messages',
+  content_rowid = 'id'
+);
+
+INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert
+AFTER INSERT ON messages
+BEGIN
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete
+AFTER DELETE ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_update
+AFTER UPDATE OF content ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS messages_fts_update;
+DROP TRIGGER IF EXISTS messages_fts_delete;
+DROP TRIGGER IF EXISTS messages_fts_insert;
+DROP TABLE IF EXISTS messages_fts;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS attachments (
+  id INTEGER PRIMARY KEY,
+  uploader_id INT NOT NULL REFERENCES users (id),
+  name TEXT NOT NULL,
+  size INT NOT NULL,
+  hash TEXT NOT NULL -- hex encoded sha256 of the content, the blob's name
+);
+
+ALTER TABLE messages ADD COLUMN attachment_id INT REFERENCES attachments (id) DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_attachment_id ON messages (attachment_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_messages_attachment_id;
+ALTER TABLE messages DROP COLUMN attachment_id;
+DROP TABLE IF EXISTS attachments;
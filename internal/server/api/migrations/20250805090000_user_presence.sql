@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN is_dnd BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN is_presence_public BOOLEAN NOT NULL DEFAULT true;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_presence_public;
+ALTER TABLE users DROP COLUMN is_dnd;
+ALTER TABLE users DROP COLUMN status;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_read_receipts BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_read_receipts;
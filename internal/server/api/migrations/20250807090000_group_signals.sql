@@ -0,0 +1,175 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS group_signals (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_signal_members (
+  group_id INT NOT NULL REFERENCES group_signals (id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users (id),
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_signal_members_user ON group_signal_members (user_id);
+
+-- Group messages have a group_id instead of a frequency_id or receiver_id,
+-- the check constraint can't be altered so the table is rebuilt
+CREATE TABLE messages_new (
+  id INTEGER PRIMARY KEY,
+  sender_id INT NOT NULL REFERENCES users (id),
+  content TEXT NOT NULL,
+  edited BOOLEAN NOT NULL CHECK (edited IN (false, true)) DEFAULT false,
+
+  frequency_id INT REFERENCES frequencies (id) ON DELETE CASCADE,
+  receiver_id INT REFERENCES users (id),
+  group_id INT REFERENCES group_signals (id) ON DELETE CASCADE,
+  ping INTEGER DEFAULT NULL,
+  pinned_at INTEGER DEFAULT NULL,
+  attachment_id INT REFERENCES attachments (id) DEFAULT NULL,
+
+  CHECK (
+    (frequency_id IS NOT NULL) + (receiver_id IS NOT NULL) + (group_id IS NOT NULL) = 1
+  )
+);
+
+INSERT INTO messages_new (
+  id, sender_id, content, edited, frequency_id, receiver_id, ping, pinned_at, attachment_id
+)
+SELECT
+  id, sender_id, content, edited, frequency_id, receiver_id, ping, pinned_at, attachment_id
+FROM messages;
+
+DROP TABLE messages;
+ALTER TABLE messages_new RENAME TO messages;
+
+CREATE INDEX IF NOT EXISTS idx_frequency_messages ON messages (frequency_id);
+CREATE INDEX IF NOT EXISTS idx_direct_messages ON messages (sender_id, receiver_id);
+CREATE INDEX IF NOT EXISTS idx_group_messages ON messages (group_id);
+CREATE INDEX IF NOT EXISTS idx_messages_attachment_id ON messages (attachment_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_message_delete
+AFTER DELETE ON messages
+BEGIN
+  DELETE FROM message_mentions WHERE message_id = OLD.id;
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert
+AFTER INSERT ON messages
+BEGIN
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete
+AFTER DELETE ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_update
+AFTER UPDATE OF content ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_group_signal_delete
+AFTER DELETE ON group_signals
+BEGIN
+  DELETE FROM group_signal_members WHERE group_id = OLD.id;
+  DELETE FROM messages WHERE group_id = OLD.id;
+  DELETE FROM last_read_messages WHERE source_id = OLD.id;
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_user_delete_group_signals
+AFTER UPDATE OF is_deleted ON users
+WHEN NEW.is_deleted = true
+BEGIN
+  DELETE FROM group_signal_members WHERE user_id = NEW.id;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS on_user_delete_group_signals;
+DROP TRIGGER IF EXISTS on_group_signal_delete;
+
+DELETE FROM messages WHERE group_id IS NOT NULL;
+
+CREATE TABLE messages_old (
+  id INTEGER PRIMARY KEY,
+  sender_id INT NOT NULL REFERENCES users (id),
+  content TEXT NOT NULL,
+  edited BOOLEAN NOT NULL CHECK (edited IN (false, true)) DEFAULT false,
+
+  frequency_id INT REFERENCES frequencies (id) ON DELETE CASCADE,
+  receiver_id INT REFERENCES users (id),
+  ping INTEGER DEFAULT NULL,
+  pinned_at INTEGER DEFAULT NULL,
+  attachment_id INT REFERENCES attachments (id) DEFAULT NULL,
+
+  CHECK (
+    (frequency_id IS NOT NULL AND receiver_id IS NULL) OR
+    (frequency_id IS NULL AND receiver_id IS NOT NULL)
+  )
+);
+
+INSERT INTO messages_old (
+  id, sender_id, content, edited, frequency_id, receiver_id, ping, pinned_at, attachment_id
+)
+SELECT
+  id, sender_id, content, edited, frequency_id, receiver_id, ping, pinned_at, attachment_id
+FROM messages;
+
+DROP TABLE messages;
+ALTER TABLE messages_old RENAME TO messages;
+
+CREATE INDEX IF NOT EXISTS idx_frequency_messages ON messages (frequency_id);
+CREATE INDEX IF NOT EXISTS idx_direct_messages ON messages (sender_id, receiver_id);
+CREATE INDEX IF NOT EXISTS idx_messages_attachment_id ON messages (attachment_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_message_delete
+AFTER DELETE ON messages
+BEGIN
+  DELETE FROM message_mentions WHERE message_id = OLD.id;
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert
+AFTER INSERT ON messages
+BEGIN
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete
+AFTER DELETE ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS messages_fts_update
+AFTER UPDATE OF content ON messages
+BEGIN
+  INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', OLD.id, OLD.content);
+  INSERT INTO messages_fts (rowid, content) VALUES (NEW.id, NEW.content);
+END
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_group_signal_members_user;
+DROP TABLE IF EXISTS group_signal_members;
+DROP TABLE IF EXISTS group_signals;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS message_requests (
+  sender_id INT NOT NULL REFERENCES users (id),
+  receiver_id INT NOT NULL REFERENCES users (id),
+  message_id INT NOT NULL REFERENCES messages (id),
+  is_ignored BOOLEAN NOT NULL DEFAULT false,
+  PRIMARY KEY (sender_id, receiver_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_requests_receiver ON message_requests (receiver_id);
+
+-- Ignored requests are kept so the sender can't send another one
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_message_delete_message_requests
+AFTER DELETE ON messages
+BEGIN
+  DELETE FROM message_requests WHERE message_id = OLD.id AND is_ignored = false;
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_user_delete_message_requests
+AFTER UPDATE OF is_deleted ON users
+WHEN NEW.is_deleted = true
+BEGIN
+  DELETE FROM message_requests WHERE sender_id = NEW.id OR receiver_id = NEW.id;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS on_user_delete_message_requests;
+DROP TRIGGER IF EXISTS on_message_delete_message_requests;
+DROP INDEX IF EXISTS idx_message_requests_receiver;
+DROP TABLE IF EXISTS message_requests;
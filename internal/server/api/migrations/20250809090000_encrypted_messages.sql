@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE messages ADD COLUMN is_encrypted BOOLEAN NOT NULL DEFAULT false;
+-- Encrypted messages are end-to-end encrypted DMs, content is the ciphertext
+
+-- +goose Down
+ALTER TABLE messages DROP COLUMN is_encrypted;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE messages ADD COLUMN signature BLOB DEFAULT NULL;
+-- ed25519 signature of the sender over packet.MessageSignaturePayload
+
+-- +goose Down
+ALTER TABLE messages DROP COLUMN signature;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS key_rotations (
+  id INT PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id),
+  old_public_key BLOB NOT NULL,
+  new_public_key BLOB NOT NULL,
+  signature BLOB NOT NULL
+);
+-- signature is of the old key over packet.KeyRotationSignaturePayload
+
+CREATE INDEX IF NOT EXISTS idx_key_rotations_user ON key_rotations (user_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_user_delete_key_rotations
+AFTER UPDATE OF is_deleted ON users
+WHEN NEW.is_deleted = true
+BEGIN
+  DELETE FROM key_rotations WHERE user_id = NEW.id;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS on_user_delete_key_rotations;
+DROP INDEX IF EXISTS idx_key_rotations_user;
+DROP TABLE IF EXISTS key_rotations;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS device_keys (
+  id INT PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id),
+  public_key BLOB NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  authorized_by BLOB NOT NULL,
+  signature BLOB NOT NULL
+);
+-- signature is of authorized_by over packet.DeviceSignaturePayload
+
+CREATE INDEX IF NOT EXISTS idx_device_keys_user ON device_keys (user_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS on_user_delete_device_keys
+AFTER UPDATE OF is_deleted ON users
+WHEN NEW.is_deleted = true
+BEGIN
+  DELETE FROM device_keys WHERE user_id = NEW.id;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS on_user_delete_device_keys;
+DROP INDEX IF EXISTS idx_device_keys_user;
+DROP TABLE IF EXISTS device_keys;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- Public keys were raw ed25519 keys, they are now stored in the SSH wire
+-- format (sshkey.PublicKey) so ECDSA and RSA keys can be stored as well.
+-- The ed25519 wire prefix is the type "ssh-ed25519" and the key's length.
+UPDATE users SET public_key = unhex('0000000B7373682D65643235353139' || '00000020' || hex(public_key))
+WHERE length(public_key) = 32;
+UPDATE trusted_users SET trusted_public_key = unhex('0000000B7373682D65643235353139' || '00000020' || hex(trusted_public_key))
+WHERE length(trusted_public_key) = 32;
+UPDATE key_rotations SET old_public_key = unhex('0000000B7373682D65643235353139' || '00000020' || hex(old_public_key))
+WHERE length(old_public_key) = 32;
+UPDATE key_rotations SET new_public_key = unhex('0000000B7373682D65643235353139' || '00000020' || hex(new_public_key))
+WHERE length(new_public_key) = 32;
+UPDATE device_keys SET public_key = unhex('0000000B7373682D65643235353139' || '00000020' || hex(public_key))
+WHERE length(public_key) = 32;
+UPDATE device_keys SET authorized_by = unhex('0000000B7373682D65643235353139' || '00000020' || hex(authorized_by))
+WHERE length(authorized_by) = 32;
+
+-- +goose Down
+-- ECDSA and RSA keys have no raw ed25519 form, so they are left as is
+UPDATE users SET public_key = substr(public_key, 20)
+WHERE length(public_key) = 51 AND substr(public_key, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
+UPDATE trusted_users SET trusted_public_key = substr(trusted_public_key, 20)
+WHERE length(trusted_public_key) = 51 AND substr(trusted_public_key, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
+UPDATE key_rotations SET old_public_key = substr(old_public_key, 20)
+WHERE length(old_public_key) = 51 AND substr(old_public_key, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
+UPDATE key_rotations SET new_public_key = substr(new_public_key, 20)
+WHERE length(new_public_key) = 51 AND substr(new_public_key, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
+UPDATE device_keys SET public_key = substr(public_key, 20)
+WHERE length(public_key) = 51 AND substr(public_key, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
+UPDATE device_keys SET authorized_by = substr(authorized_by, 20)
+WHERE length(authorized_by) = 51 AND substr(authorized_by, 1, 19) = unhex('0000000B7373682D6564323535313900000020');
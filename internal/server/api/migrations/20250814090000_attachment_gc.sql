@@ -0,0 +1,6 @@
+-- +goose Up
+-- Blobs are removed once no attachment has their hash
+CREATE INDEX IF NOT EXISTS idx_attachments_hash ON attachments (hash);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_attachments_hash;
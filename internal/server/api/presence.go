@@ -0,0 +1,185 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+// How long without any requests until an online user is considered idle
+const IdleTimeout = 10 * time.Minute
+
+// presence returns the user's presence as seen by other users
+func presence(sess *session.Session, user data.User, online bool) packet.Presence {
+	presence := packet.Presence{
+		Status: user.Status,
+		User:   user.ID,
+		State:  packet.PresenceOffline,
+	}
+
+	switch {
+	case !user.IsPresencePublic:
+		presence.Status = "" // Hidden users always appear offline
+	case !online:
+		// Offline is the default
+	case user.IsDND:
+		presence.State = packet.PresenceDoNotDisturb
+	case time.Since(lastActivity(sess)) >= IdleTimeout:
+		presence.State = packet.PresenceIdle
+	default:
+		presence.State = packet.PresenceOnline
+	}
+
+	return presence
+}
+
+// lastActivity returns the most recent activity
+// across all sessions of the session's user
+func lastActivity(sess *session.Session) time.Time {
+	last := sess.LastActivity()
+	for _, s := range sess.Manager().Sessions(sess.ID()) {
+		if activity := s.LastActivity(); activity.After(last) {
+			last = activity
+		}
+	}
+	return last
+}
+
+// UpdatePresence recomputes the presence of the session's user,
+// propagating it to everyone who may see it if it changed.
+func UpdatePresence(ctx context.Context, sess *session.Session, online bool) {
+	queries := data.New(db)
+	user, err := queries.GetUserById(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+
+	updatePresence(ctx, sess, user, online, false)
+}
+
+func updatePresence(ctx context.Context, sess *session.Session, user data.User, online, force bool) {
+	newPresence := presence(sess, user, online)
+	if newPresence == sess.Presence() && !force {
+		return
+	}
+	sess.SetPresence(newPresence)
+	for _, s := range sess.Manager().Sessions(sess.ID()) {
+		s.SetPresence(newPresence)
+	}
+
+	queries := data.New(db)
+	audience, err := queries.GetPresenceAudience(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+
+	payload := &packet.PresenceInfo{
+		Presences: []packet.Presence{newPresence},
+	}
+	for _, userId := range audience {
+		if len(sess.Manager().Sessions(userId)) != 0 {
+			UserPropagate(ctx, sess, userId, payload, false)
+		}
+	}
+}
+
+// SyncPresence sends the user's presence to everyone who may see it,
+// and sends the user the presence of everyone they may see, used when
+// the set of users that can see each other changes.
+func SyncPresence(ctx context.Context, sess *session.Session) {
+	queries := data.New(db)
+	user, err := queries.GetUserById(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+
+	updatePresence(ctx, sess, user, true, true)
+
+	if payload := GetPresences(ctx, sess); payload != &ErrInternalError {
+		UserPropagate(ctx, sess, sess.ID(), payload, false)
+	}
+}
+
+// GetPresences returns the presence of all online users
+// the session's user may see
+func GetPresences(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+	users, err := queries.GetVisiblePresenceUsers(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	presences := []packet.Presence{}
+	sess.Manager().UseSessions(func(sessions map[snowflake.ID][]*session.Session) {
+		for id, userSessions := range sessions {
+			if !slices.Contains(users, id) || len(userSessions) == 0 {
+				continue
+			}
+			// All sessions of a user share the same presence
+			presence := userSessions[0].Presence()
+			if presence.State != packet.PresenceOffline || presence.Status != "" {
+				presences = append(presences, presence)
+			}
+		}
+	})
+
+	return &packet.PresenceInfo{
+		Presences: presences,
+	}
+}
+
+// UpdateIdlePresences recomputes the presence of users who
+// went idle or came back since their presence was last propagated.
+func UpdateIdlePresences(ctx context.Context, manager session.SessionManager) {
+	var changed []*session.Session
+	manager.UseSessions(func(sessions map[snowflake.ID][]*session.Session) {
+		for _, userSessions := range sessions {
+			if len(userSessions) == 0 {
+				continue
+			}
+			s := userSessions[0]
+			state := s.Presence().State
+			last := s.LastActivity()
+			for _, other := range userSessions[1:] {
+				if activity := other.LastActivity(); activity.After(last) {
+					last = activity
+				}
+			}
+			idle := time.Since(last) >= IdleTimeout
+			if (state == packet.PresenceOnline && idle) || (state == packet.PresenceIdle && !idle) {
+				changed = append(changed, s)
+			}
+		}
+	})
+
+	// NOTE: must be outside of UseSessions as propagation acquires the lock
+	for _, s := range changed {
+		UpdatePresence(ctx, s, true)
+	}
+}
@@ -0,0 +1,87 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kyren223/eko/internal/data"
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+// GetReadReceipts returns how far other users have read their DM
+// with the session's user, if both of them enabled read receipts
+func GetReadReceipts(ctx context.Context, sess *session.Session) packet.Payload {
+	queries := data.New(db)
+	receipts, err := queries.GetReadReceipts(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return &ErrInternalError
+	}
+
+	users := make([]snowflake.ID, 0, len(receipts))
+	lastRead := make([]int64, 0, len(receipts))
+	for _, receipt := range receipts {
+		users = append(users, receipt.UserID)
+		lastRead = append(lastRead, receipt.LastRead)
+	}
+
+	return &packet.ReadReceiptsInfo{
+		Users:        users,
+		LastRead:     lastRead,
+		RemovedUsers: nil,
+	}
+}
+
+// syncReadReceipts is called after the session's user toggled read
+// receipts, it sends them the receipts they may now see and tells users
+// with read receipts enabled where the user read up to, or to forget it.
+func syncReadReceipts(ctx context.Context, sess *session.Session, enabled bool) {
+	if payload := GetReadReceipts(ctx, sess); payload != &ErrInternalError {
+		UserPropagate(ctx, sess, sess.ID(), payload, false)
+	}
+
+	queries := data.New(db)
+	positions, err := queries.GetReadPositions(ctx, sess.ID())
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "error", err)
+		return
+	}
+
+	for _, position := range positions {
+		if len(sess.Manager().Sessions(position.SourceID)) == 0 {
+			continue
+		}
+
+		payload := &packet.ReadReceiptsInfo{
+			Users:        nil,
+			LastRead:     nil,
+			RemovedUsers: []snowflake.ID{sess.ID()},
+		}
+		if enabled {
+			payload = &packet.ReadReceiptsInfo{
+				Users:        []snowflake.ID{sess.ID()},
+				LastRead:     []int64{position.LastRead},
+				RemovedUsers: nil,
+			}
+		}
+		UserPropagate(ctx, sess, position.SourceID, payload, false)
+	}
+}
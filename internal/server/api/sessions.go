@@ -0,0 +1,73 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+func GetSessions(ctx context.Context, sess *session.Session, request *packet.GetSessions) packet.Payload {
+	return &packet.SessionsInfo{Sessions: sessions(sess, nil)}
+}
+
+func TerminateSession(ctx context.Context, sess *session.Session, request *packet.TerminateSession) packet.Payload {
+	if request.Session == sess.SessionID() {
+		return &packet.Error{Error: "cannot terminate the current session, sign out instead"}
+	}
+
+	if !sess.Manager().EvictSession(sess.ID(), request.Session, packet.ErrSessionTerminated) {
+		return &packet.Error{Error: "session doesn't exist"}
+	}
+
+	// The evicted session is removed only once its connection closes
+	return &packet.SessionsInfo{Sessions: sessions(sess, &request.Session)}
+}
+
+// sessions returns all sessions of the session's user, except for the excluded one
+func sessions(sess *session.Session, excluded *snowflake.ID) []packet.Session {
+	var sessions []packet.Session
+	sess.Manager().UseSessions(func(userSessions map[snowflake.ID][]*session.Session) {
+		for _, s := range userSessions[sess.ID()] {
+			if excluded != nil && s.SessionID() == *excluded {
+				continue
+			}
+
+			info := packet.Session{
+				PubKey:   s.PubKey(),
+				Addr:     s.Addr().IP.String(),
+				OS:       "",
+				Arch:     "",
+				Term:     "",
+				Start:    s.Start().UnixMilli(),
+				Duration: s.Duration(),
+				ID:       s.SessionID(),
+				Current:  s == sess,
+			}
+			if analytics := s.Analytics(); analytics != nil {
+				info.OS = analytics.OS
+				info.Arch = analytics.Arch
+				info.Term = analytics.Term
+			}
+			sessions = append(sessions, info)
+		}
+	})
+	return sessions
+}
@@ -0,0 +1,233 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package connlimit decides whether new connections are accepted,
+// based on how often the same client connected recently.
+package connlimit
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/cidr"
+)
+
+type Decision int
+
+const (
+	Allowed Decision = iota
+	Allowlisted
+	Denylisted
+	// The client exceeded the limit in the current window
+	RateLimited
+	// The client exceeded the limit recently and is still penalized
+	Penalized
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Allowed:
+		return "allowed"
+	case Allowlisted:
+		return "allowlisted"
+	case Denylisted:
+		return "denylisted"
+	case RateLimited:
+		return "rate_limited"
+	case Penalized:
+		return "penalized"
+	default:
+		return "unknown"
+	}
+}
+
+func (d Decision) Accept() bool {
+	return d == Allowed || d == Allowlisted
+}
+
+type Config struct {
+	// Connections allowed per client in each window
+	Limit  int
+	Window time.Duration
+
+	// Exceeding the limit rejects all connections for Penalty,
+	// which doubles with every repeated offense up to MaxPenalty
+	Penalty    time.Duration
+	MaxPenalty time.Duration
+
+	// Clients idle for TTL are forgotten, including their past offenses
+	TTL time.Duration
+	// Least recently seen clients are forgotten when there are more than MaxEntries
+	MaxEntries int
+
+	// Allow bypasses the limit, Deny rejects all connections, Deny takes priority
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Limit:      3,
+		Window:     1 * time.Second,
+		Penalty:    10 * time.Second,
+		MaxPenalty: 1 * time.Hour,
+		TTL:        24 * time.Hour,
+		MaxEntries: 100_000,
+		Allow:      nil,
+		Deny:       nil,
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Limit <= 0 || c.Window <= 0 || c.Penalty <= 0 || c.MaxEntries <= 0 {
+		return errors.New("limit, window, penalty and max entries must be positive")
+	}
+	if c.MaxPenalty < c.Penalty {
+		return errors.New("max penalty must be at least the penalty")
+	}
+	if c.TTL < c.MaxPenalty {
+		// Otherwise a penalized client could be forgotten before its penalty is over
+		return errors.New("ttl must be at least the max penalty")
+	}
+	return nil
+}
+
+type entry struct {
+	key          netip.Prefix
+	windowStart  time.Time
+	count        int
+	offenses     uint
+	penaltyUntil time.Time
+	lastSeen     time.Time
+}
+
+type Limiter struct {
+	config Config
+	now    func() time.Time
+
+	mu sync.Mutex
+	// Key is the address block of the client, see [session.AddrPrefix]
+	entries map[netip.Prefix]*list.Element
+	// Ordered by lastSeen, most recent at the front
+	lru *list.List
+}
+
+func New(config Config) (*Limiter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Limiter{
+		config:  config,
+		now:     func() time.Time { return time.Now().UTC() },
+		entries: map[netip.Prefix]*list.Element{},
+		lru:     list.New(),
+	}, nil
+}
+
+// Check records a connection from ip and decides whether to accept it
+func (l *Limiter) Check(ip net.IP) Decision {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return Denylisted
+	}
+	addr = addr.Unmap()
+	if cidr.Contains(l.config.Deny, addr) {
+		return Denylisted
+	}
+	if cidr.Contains(l.config.Allow, addr) {
+		return Allowlisted
+	}
+
+	key := session.AddrPrefix(ip)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evictExpired(now)
+
+	element, ok := l.entries[key]
+	if !ok {
+		if l.lru.Len() >= l.config.MaxEntries {
+			l.remove(l.lru.Back())
+		}
+		element = l.lru.PushFront(&entry{key: key, windowStart: now})
+		l.entries[key] = element
+	}
+	l.lru.MoveToFront(element)
+
+	e := element.Value.(*entry)
+	e.lastSeen = now
+
+	if now.Before(e.penaltyUntil) {
+		return Penalized
+	}
+
+	if now.Sub(e.windowStart) >= l.config.Window {
+		e.windowStart = now
+		e.count = 0
+	}
+	e.count++
+	if e.count <= l.config.Limit {
+		return Allowed
+	}
+
+	penalty := l.config.Penalty
+	for i := uint(0); i < e.offenses && penalty < l.config.MaxPenalty; i++ {
+		penalty *= 2
+	}
+	penalty = min(penalty, l.config.MaxPenalty)
+	e.offenses++
+	e.penaltyUntil = now.Add(penalty)
+	return RateLimited
+}
+
+// Penalty returns how long the client of ip is penalized for, if at all
+func (l *Limiter) Penalty(ip net.IP) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	element, ok := l.entries[session.AddrPrefix(ip)]
+	if !ok {
+		return 0
+	}
+	return max(element.Value.(*entry).penaltyUntil.Sub(l.now()), 0)
+}
+
+// Len returns the number of clients currently remembered
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
+
+func (l *Limiter) evictExpired(now time.Time) {
+	for element := l.lru.Back(); element != nil; element = l.lru.Back() {
+		if now.Sub(element.Value.(*entry).lastSeen) < l.config.TTL {
+			return
+		}
+		l.remove(element)
+	}
+}
+
+func (l *Limiter) remove(element *list.Element) {
+	delete(l.entries, element.Value.(*entry).key)
+	l.lru.Remove(element)
+}
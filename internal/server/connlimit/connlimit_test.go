@@ -0,0 +1,212 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connlimit
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(t *testing.T, config Config) (*Limiter, *clock) {
+	t.Helper()
+
+	limiter, err := New(config)
+	require.NoError(t, err)
+
+	clock := &clock{now: time.Unix(0, 0).UTC()}
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func connect(limiter *Limiter, ip string, n int) []Decision {
+	decisions := make([]Decision, n)
+	for i := range decisions {
+		decisions[i] = limiter.Check(net.ParseIP(ip))
+	}
+	return decisions
+}
+
+func TestWithinLimit(t *testing.T) {
+	limiter, clock := newTestLimiter(t, DefaultConfig())
+
+	require.Equal(t, []Decision{Allowed}, connect(limiter, "10.0.0.1", 1))
+	clock.Advance(1100 * time.Millisecond)
+
+	require.Equal(t, []Decision{Allowed, Allowed}, connect(limiter, "10.0.0.1", 2))
+	clock.Advance(1100 * time.Millisecond)
+	require.Equal(t, []Decision{Allowed, Allowed}, connect(limiter, "10.0.0.1", 2))
+	clock.Advance(1100 * time.Millisecond)
+
+	require.Equal(t, []Decision{Allowed, Allowed, Allowed}, connect(limiter, "10.0.0.1", 3))
+}
+
+func TestEscalatingPenalty(t *testing.T) {
+	config := DefaultConfig()
+	limiter, clock := newTestLimiter(t, config)
+	ip := net.ParseIP("10.0.0.1")
+
+	require.Equal(t,
+		[]Decision{Allowed, Allowed, Allowed, RateLimited, Penalized},
+		connect(limiter, "10.0.0.1", 5),
+	)
+	require.Equal(t, config.Penalty, limiter.Penalty(ip))
+
+	clock.Advance(1100 * time.Millisecond)
+	require.Equal(t, []Decision{Penalized}, connect(limiter, "10.0.0.1", 1))
+
+	// Penalty expires, the next offense is penalized for twice as long
+	clock.Advance(config.Penalty)
+	require.Equal(t,
+		[]Decision{Allowed, Allowed, Allowed, RateLimited, Penalized},
+		connect(limiter, "10.0.0.1", 5),
+	)
+	require.Equal(t, 2*config.Penalty, limiter.Penalty(ip))
+
+	// Repeated offenses never exceed the max penalty
+	for range 20 {
+		clock.Advance(limiter.Penalty(ip))
+		connect(limiter, "10.0.0.1", 15)
+	}
+	require.Equal(t, config.MaxPenalty, limiter.Penalty(ip))
+}
+
+func TestOffensesExpire(t *testing.T) {
+	config := DefaultConfig()
+	limiter, clock := newTestLimiter(t, config)
+	ip := net.ParseIP("10.0.0.1")
+
+	connect(limiter, "10.0.0.1", 15)
+	clock.Advance(config.Penalty)
+	connect(limiter, "10.0.0.1", 15)
+	require.Equal(t, 2*config.Penalty, limiter.Penalty(ip))
+
+	// Idle for longer than the TTL, past offenses are forgotten
+	clock.Advance(config.TTL)
+	require.Equal(t, []Decision{Allowed}, connect(limiter, "10.0.0.2", 1))
+	require.Equal(t, 1, limiter.Len())
+
+	connect(limiter, "10.0.0.1", 15)
+	require.Equal(t, config.Penalty, limiter.Penalty(ip))
+}
+
+func TestAddressBlocks(t *testing.T) {
+	limiter, _ := newTestLimiter(t, DefaultConfig())
+
+	// Same /64, counted as the same client
+	require.Equal(t, []Decision{Allowed, Allowed}, connect(limiter, "2001:db8:1:2::1", 2))
+	require.Equal(t, []Decision{Allowed, RateLimited}, connect(limiter, "2001:db8:1:2::ffff", 2))
+
+	// Different /64 and IPv4 addresses are separate clients
+	require.Equal(t, []Decision{Allowed}, connect(limiter, "2001:db8:1:3::1", 1))
+	require.Equal(t, []Decision{Allowed, Allowed, Allowed}, connect(limiter, "10.0.0.1", 3))
+	require.Equal(t, []Decision{Allowed}, connect(limiter, "10.0.0.2", 1))
+
+	// IPv4 mapped IPv6 addresses are the same as IPv4
+	require.Equal(t, []Decision{RateLimited}, connect(limiter, "::ffff:10.0.0.1", 1))
+}
+
+func TestAllowAndDenyLists(t *testing.T) {
+	config := DefaultConfig()
+	config.Allow = []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	config.Deny = []netip.Prefix{netip.MustParsePrefix("10.6.6.0/24")}
+	limiter, _ := newTestLimiter(t, config)
+
+	for _, decision := range connect(limiter, "10.1.2.3", 15) {
+		require.Equal(t, Allowlisted, decision)
+	}
+	for _, decision := range connect(limiter, "2001:db8::1", 15) {
+		require.Equal(t, Allowlisted, decision)
+	}
+	require.Equal(t, []Decision{Denylisted}, connect(limiter, "10.6.6.6", 1))
+	require.Equal(t, []Decision{Denylisted}, connect(limiter, "::ffff:10.6.6.6", 1))
+
+	require.False(t, Denylisted.Accept())
+	require.True(t, Allowlisted.Accept())
+	require.Equal(t, 0, limiter.Len())
+}
+
+func TestBounded(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxEntries = 10
+	limiter, clock := newTestLimiter(t, config)
+
+	connect(limiter, "10.0.0.0", 15)
+	for i := 1; i <= 10; i++ {
+		clock.Advance(time.Millisecond)
+		ip := netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})
+		connect(limiter, ip.String(), 1)
+	}
+	require.Equal(t, config.MaxEntries, limiter.Len())
+
+	// The least recently seen client was evicted
+	require.Equal(t, time.Duration(0), limiter.Penalty(net.ParseIP("10.0.0.0")))
+}
+
+func TestConcurrent(t *testing.T) {
+	limiter, _ := newTestLimiter(t, DefaultConfig())
+
+	var wg sync.WaitGroup
+	decisions := make([][]Decision, 8)
+	for i := range decisions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decisions[i] = connect(limiter, "10.0.0.1", 10)
+		}()
+	}
+	wg.Wait()
+
+	counts := map[Decision]int{}
+	for _, d := range decisions {
+		for _, decision := range d {
+			counts[decision]++
+		}
+	}
+	require.Equal(t, map[Decision]int{Allowed: 3, RateLimited: 1, Penalized: 76}, counts)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.TTL = config.MaxPenalty - time.Second
+	_, err := New(config)
+	require.Error(t, err)
+
+	config = DefaultConfig()
+	config.Limit = 0
+	_, err = New(config)
+	require.Error(t, err)
+}
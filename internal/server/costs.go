@@ -0,0 +1,295 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/api"
+	"github.com/kyren223/eko/internal/server/metrics"
+	"github.com/kyren223/eko/internal/server/session"
+	"github.com/kyren223/eko/pkg/rate"
+	"github.com/kyren223/eko/pkg/snowflake"
+)
+
+const (
+	// Path to a JSON object of request type (like "SendMessage") to cost in tokens
+	RequestCostsFile = "EKO_SERVER_REQUEST_COSTS_FILE"
+	// Set to "true" to derive costs from measured processing time
+	RequestCostsAutotune = "EKO_SERVER_REQUEST_COSTS_AUTOTUNE"
+)
+
+const (
+	CostTuneInterval   = 1 * time.Minute
+	CostTuneMinSamples = 100
+	MinRequestCost     = 0.05
+	// Anything more couldn't ever be afforded by an authenticated session
+	MaxRequestCost = session.AuthenticatedLimit
+
+	UserBucketCleanupInterval = 5 * time.Minute
+)
+
+var (
+	// Key is the request type, configured costs take priority over tuned ones,
+	// types in neither use TokensPerRequest
+	configuredCosts = map[packet.PacketType]float64{}
+	tunedCosts      = map[packet.PacketType]float64{}
+	costsMu         sync.RWMutex
+)
+
+// RequestCost returns how many tokens a request of the given type takes
+// from the session's rate limiter, 1 token is roughly 1ms of server time
+func RequestCost(requestType packet.PacketType) float64 {
+	costsMu.RLock()
+	defer costsMu.RUnlock()
+
+	if cost, ok := configuredCosts[requestType]; ok {
+		return cost
+	}
+	if cost, ok := tunedCosts[requestType]; ok {
+		return cost
+	}
+	return TokensPerRequest(requestType)
+}
+
+// ReloadRequestCosts loads the configured costs from RequestCostsFile,
+// returns false if the file is invalid, in which case the costs are unchanged
+func ReloadRequestCosts() bool {
+	path := os.Getenv(RequestCostsFile)
+	if path == "" {
+		return true
+	}
+
+	contents, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		slog.Error("error reading request costs file", "error", err)
+		return false
+	}
+
+	var costs map[string]float64
+	if err := json.Unmarshal(contents, &costs); err != nil {
+		slog.Error("error parsing request costs file", "error", err)
+		return false
+	}
+
+	configured, err := parseRequestCosts(costs)
+	if err != nil {
+		slog.Error("invalid request costs file", "error", err)
+		return false
+	}
+
+	costsMu.Lock()
+	configuredCosts = configured
+	costsMu.Unlock()
+
+	slog.Info("request costs loaded", "path", path, "count", len(configured))
+	return true
+}
+
+func parseRequestCosts(costs map[string]float64) (map[packet.PacketType]float64, error) {
+	types := map[string]packet.PacketType{}
+	for requestType := packet.PacketType(0); requestType < packet.PacketMax; requestType++ {
+		types[strings.TrimPrefix(requestType.String(), "Packet")] = requestType
+	}
+
+	parsed := map[packet.PacketType]float64{}
+	for name, cost := range costs {
+		requestType, ok := types[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown request type %q", name)
+		}
+		if cost < MinRequestCost || cost > MaxRequestCost {
+			return nil, fmt.Errorf(
+				"cost of %v must be between %v and %v, got %v",
+				name, MinRequestCost, MaxRequestCost, cost,
+			)
+		}
+		parsed[requestType] = cost
+	}
+	return parsed, nil
+}
+
+// tuneRequestCosts periodically sets the cost of each request type to its
+// mean processing time in ms, as recorded by RequestProcessingDuration
+func (s *server) tuneRequestCosts() {
+	ticker := time.NewTicker(CostTuneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			tuned := measuredCosts()
+			costsMu.Lock()
+			tunedCosts = tuned
+			costsMu.Unlock()
+			slog.Info("request costs tuned", "count", len(tuned))
+		}
+	}
+}
+
+func measuredCosts() map[packet.PacketType]float64 {
+	names := map[string]packet.PacketType{}
+	for requestType := packet.PacketType(0); requestType < packet.PacketMax; requestType++ {
+		names[requestType.String()] = requestType
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		metrics.RequestProcessingDuration.Collect(ch)
+		close(ch)
+	}()
+
+	costs := map[packet.PacketType]float64{}
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			slog.Error("failed reading request processing duration", "error", err)
+			continue
+		}
+
+		labels := map[string]string{}
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		requestType, ok := names[labels["request_type"]]
+		if !ok || labels["dropped"] != "false" {
+			continue
+		}
+
+		histogram := m.GetHistogram()
+		if histogram.GetSampleCount() < CostTuneMinSamples {
+			continue
+		}
+		meanMs := histogram.GetSampleSum() / float64(histogram.GetSampleCount()) * 1000
+		costs[requestType] = min(max(meanMs, MinRequestCost), MaxRequestCost)
+	}
+
+	return costs
+}
+
+type userBucketKey struct {
+	userId      snowflake.ID
+	requestType packet.PacketType
+}
+
+var (
+	// Expensive requests also take from a bucket shared by all sessions of the user,
+	// so a user can't multiply their budget by opening more sessions.
+	// These buckets count requests rather than tokens
+	expensiveRequests = map[packet.PacketType]struct{ rate, limit float64 }{
+		packet.PacketDeleteNetwork:   {rate: 1.0 / 60, limit: 3},
+		packet.PacketRequestMessages: {rate: 2, limit: 20},
+		packet.PacketSetMember:       {rate: 0.5, limit: 10},
+	}
+
+	userBuckets   = map[userBucketKey]*rate.Limiter{}
+	userBucketsMu sync.Mutex
+)
+
+// takeRequest takes the request's tokens from the session's bucket and, for
+// expensive request types, a request from the user's bucket.
+// Returns how long to wait if either bucket can't afford it
+func takeRequest(sess *session.Session, requestType packet.PacketType) (time.Duration, bool) {
+	// Never more than the limit, otherwise the request could never be afforded
+	tokens := min(RequestCost(requestType), sess.RateLimiter().Limit())
+
+	bucket, ok := expensiveRequests[requestType]
+	if !ok || !sess.IsAuthenticated() {
+		return takeTokens(sess.RateLimiter(), tokens, nil)
+	}
+
+	key := userBucketKey{userId: sess.ID(), requestType: requestType}
+
+	userBucketsMu.Lock()
+	defer userBucketsMu.Unlock()
+
+	limiter, ok := userBuckets[key]
+	if !ok {
+		l := rate.NewLimiter(bucket.rate, bucket.limit)
+		limiter = &l
+		userBuckets[key] = limiter
+	}
+
+	return takeTokens(sess.RateLimiter(), tokens, limiter)
+}
+
+// takeTokens takes tokens from the session's limiter and a request from the
+// user's limiter if there is one, taking from neither unless both can afford
+// it, so a request rejected by one bucket doesn't drain the other
+func takeTokens(sessionLimiter *rate.Limiter, tokens float64, userLimiter *rate.Limiter) (time.Duration, bool) {
+	retryAfter := sessionLimiter.Until(tokens)
+	if userLimiter != nil {
+		retryAfter = max(retryAfter, userLimiter.Until(1))
+	}
+	if retryAfter > 0 {
+		return retryAfter, false
+	}
+
+	sessionLimiter.Take(tokens)
+	if userLimiter != nil {
+		userLimiter.Take(1)
+	}
+	return 0, true
+}
+
+// cleanupUserBuckets periodically removes full buckets,
+// which behave exactly like new ones
+func (s *server) cleanupUserBuckets() {
+	ticker := time.NewTicker(UserBucketCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			userBucketsMu.Lock()
+			for key, limiter := range userBuckets {
+				if limiter.Full() {
+					delete(userBuckets, key)
+				}
+			}
+			userBucketsMu.Unlock()
+		}
+	}
+}
+
+// rateLimited returns the rate limited error, with retryAfter rounded up
+// to the next millisecond so retrying right after is never too early
+func rateLimited(retryAfter time.Duration) *packet.Error {
+	rounded := retryAfter.Truncate(time.Millisecond)
+	// A limiter that never refills returns the longest duration,
+	// which can't be rounded up without overflowing
+	if rounded < retryAfter && rounded <= time.Duration(math.MaxInt64)-time.Millisecond {
+		rounded += time.Millisecond
+	}
+	return &packet.Error{
+		Error:      api.ErrRateLimited.Error,
+		RetryAfter: rounded,
+	}
+}
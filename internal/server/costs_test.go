@@ -0,0 +1,219 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package server
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kyren223/eko/internal/packet"
+	"github.com/kyren223/eko/internal/server/api"
+	"github.com/kyren223/eko/internal/server/metrics"
+	"github.com/kyren223/eko/pkg/rate"
+)
+
+// withCosts replaces the configured and tuned costs for the duration of the test
+func withCosts(t *testing.T, configured, tuned map[packet.PacketType]float64) {
+	costsMu.Lock()
+	oldConfigured, oldTuned := configuredCosts, tunedCosts
+	configuredCosts, tunedCosts = configured, tuned
+	costsMu.Unlock()
+
+	t.Cleanup(func() {
+		costsMu.Lock()
+		configuredCosts, tunedCosts = oldConfigured, oldTuned
+		costsMu.Unlock()
+	})
+}
+
+func TestRequestCost(t *testing.T) {
+	withCosts(t,
+		map[packet.PacketType]float64{packet.PacketSendMessage: 7},
+		map[packet.PacketType]float64{packet.PacketSendMessage: 3, packet.PacketGetNonce: 4},
+	)
+
+	require.Equal(t, 7.0, RequestCost(packet.PacketSendMessage), "configured takes priority")
+	require.Equal(t, 4.0, RequestCost(packet.PacketGetNonce), "tuned takes priority over the default")
+	require.Equal(t, TokensPerRequest(packet.PacketAcceptTos), RequestCost(packet.PacketAcceptTos))
+}
+
+func TestParseRequestCosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		costs   map[string]float64
+		want    map[packet.PacketType]float64
+		wantErr bool
+	}{
+		{"empty", map[string]float64{}, map[packet.PacketType]float64{}, false},
+		{
+			"valid",
+			map[string]float64{"SendMessage": 2, "GetNonce": MinRequestCost, "DeleteNetwork": MaxRequestCost},
+			map[packet.PacketType]float64{
+				packet.PacketSendMessage:   2,
+				packet.PacketGetNonce:      MinRequestCost,
+				packet.PacketDeleteNetwork: MaxRequestCost,
+			},
+			false,
+		},
+		{"unknown type", map[string]float64{"Nope": 1}, nil, true},
+		{"packet prefix", map[string]float64{"PacketSendMessage": 1}, nil, true},
+		{"too cheap", map[string]float64{"SendMessage": MinRequestCost / 2}, nil, true},
+		{"too expensive", map[string]float64{"SendMessage": MaxRequestCost + 1}, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			costs, err := parseRequestCosts(test.costs)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, costs)
+		})
+	}
+}
+
+func TestReloadRequestCosts(t *testing.T) {
+	withCosts(t, map[packet.PacketType]float64{packet.PacketGetNonce: 1}, nil)
+
+	path := filepath.Join(t.TempDir(), "costs.json")
+	t.Setenv(RequestCostsFile, path)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"SendMessage": 2.5}`), 0o600))
+	require.True(t, ReloadRequestCosts())
+	require.Equal(t, 2.5, RequestCost(packet.PacketSendMessage))
+	require.Equal(t, TokensPerRequest(packet.PacketGetNonce), RequestCost(packet.PacketGetNonce))
+
+	// Invalid files leave the costs unchanged
+	for _, contents := range []string{`{"SendMessage": 0}`, `{"Nope": 1}`, `not json`} {
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+		require.False(t, ReloadRequestCosts(), contents)
+		require.Equal(t, 2.5, RequestCost(packet.PacketSendMessage))
+	}
+
+	require.NoError(t, os.Remove(path))
+	require.False(t, ReloadRequestCosts())
+}
+
+func TestMeasuredCosts(t *testing.T) {
+	duration := metrics.RequestProcessingDuration
+	duration.Reset()
+	t.Cleanup(duration.Reset)
+
+	for range CostTuneMinSamples {
+		duration.WithLabelValues(packet.PacketGetNonce.String(), "false").Observe(0.002)
+		duration.WithLabelValues(packet.PacketGetNonce.String(), "true").Observe(10)
+		duration.WithLabelValues(packet.PacketDeleteNetwork.String(), "false").Observe(MaxRequestCost)
+	}
+	for range CostTuneMinSamples - 1 {
+		duration.WithLabelValues(packet.PacketSendMessage.String(), "false").Observe(0.002)
+	}
+
+	costs := measuredCosts()
+	require.InDelta(t, 2, costs[packet.PacketGetNonce], 1e-9, "dropped requests are ignored")
+	require.Equal(t, float64(MaxRequestCost), costs[packet.PacketDeleteNetwork])
+	require.NotContains(t, costs, packet.PacketSendMessage, "too few samples")
+}
+
+// newLimiter returns a limiter that practically doesn't refill during a test
+func newLimiter(limit, taken float64) *rate.Limiter {
+	limiter := rate.NewLimiter(1e-9, limit)
+	limiter.Take(taken)
+	return &limiter
+}
+
+// requireTokens requires the limiter to have exactly the given tokens left
+func requireTokens(t *testing.T, limiter *rate.Limiter, tokens float64) {
+	t.Helper()
+	require.True(t, limiter.Has(tokens), "less than %v tokens", tokens)
+	require.False(t, limiter.Has(tokens+0.01), "more than %v tokens", tokens)
+}
+
+func TestTakeTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		session     *rate.Limiter
+		tokens      float64
+		user        *rate.Limiter
+		want        bool
+		sessionLeft float64
+		userLeft    float64
+	}{
+		{"session only", newLimiter(5, 0), 4, nil, true, 1, 0},
+		{"session empty", newLimiter(5, 3), 4, nil, false, 2, 0},
+		{"both", newLimiter(5, 0), 4, newLimiter(2, 0), true, 1, 1},
+		{"session empty keeps user request", newLimiter(5, 3), 4, newLimiter(2, 0), false, 2, 2},
+		{"user empty keeps session tokens", newLimiter(5, 0), 4, newLimiter(2, 2), false, 5, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retryAfter, ok := takeTokens(test.session, test.tokens, test.user)
+			require.Equal(t, test.want, ok)
+			if ok {
+				require.Zero(t, retryAfter)
+			} else {
+				require.Positive(t, retryAfter)
+			}
+
+			requireTokens(t, test.session, test.sessionLeft)
+			if test.user != nil {
+				requireTokens(t, test.user, test.userLeft)
+			}
+		})
+	}
+}
+
+func TestTakeTokensWaitsForBoth(t *testing.T) {
+	session := rate.NewLimiter(1, 5) // 1 second per token
+	user := rate.NewLimiter(0.1, 1)  // 10 seconds per request
+	session.Take(5)
+	user.Take(1)
+
+	retryAfter, ok := takeTokens(&session, 1, &user)
+	require.False(t, ok)
+	require.InDelta(t, 10*time.Second, retryAfter, float64(time.Second))
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{"zero", 0, 0},
+		{"whole milliseconds", 3 * time.Millisecond, 3 * time.Millisecond},
+		{"rounded up", 2*time.Millisecond + 1, 3 * time.Millisecond},
+		{"below a millisecond", time.Nanosecond, time.Millisecond},
+		{"never", time.Duration(math.MaxInt64), time.Duration(math.MaxInt64).Truncate(time.Millisecond)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := rateLimited(test.retryAfter)
+			require.Equal(t, api.ErrRateLimited.Error, err.Error)
+			require.Equal(t, test.want, err.RetryAfter)
+			require.GreaterOrEqual(t, err.RetryAfter, time.Duration(0))
+		})
+	}
+}
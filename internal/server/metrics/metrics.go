@@ -48,12 +48,26 @@ var RequestProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpt
 	NativeHistogramBucketFactor: 1.00271,
 }, []string{"request_type", "dropped"})
 
+var ConnectionDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "connection_decisions_total",
+	Help:      "The total number of connection limiter decisions",
+}, []string{"decision"})
+
+// Deprecated: use ConnectionDecisions, kept so existing dashboards keep working.
+// Rate limited connections are "suspicious", penalized connections are "malicious".
 var ConnectionsRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
 	Namespace: namespace,
 	Name:      "connections_rate_limited_total",
-	Help:      "The total number of rate limited connections",
+	Help:      "The total number of rate limited connections (deprecated, see connection_decisions_total)",
 }, []string{"category"})
 
+var ConnectionLimiterEntries = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "connection_limiter_entries",
+	Help:      "The number of clients remembered by the connection limiter",
+})
+
 var ConnectionsEstablished = promauto.NewCounter(prometheus.CounterOpts{
 	Namespace: namespace,
 	Name:      "connections_established_total",
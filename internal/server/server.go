@@ -18,13 +18,11 @@ package server
 
 import (
 	"context"
-	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/binary"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -32,7 +30,9 @@ import (
 	"log/slog"
 	"math/big"
 	"net"
+	"net/netip"
 	"os"
+	"slices"
 	"strconv"
 	"sync"
 	"syscall"
@@ -41,23 +41,35 @@ import (
 	"github.com/kyren223/eko/embeds"
 	"github.com/kyren223/eko/internal/packet"
 	"github.com/kyren223/eko/internal/server/api"
+	"github.com/kyren223/eko/internal/server/connlimit"
 	"github.com/kyren223/eko/internal/server/ctxkeys"
 	"github.com/kyren223/eko/internal/server/metrics"
 	"github.com/kyren223/eko/internal/server/session"
 	"github.com/kyren223/eko/pkg/assert"
+	"github.com/kyren223/eko/pkg/cidr"
+	"github.com/kyren223/eko/pkg/proxyproto"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var nodeId int64 = 0
 
-const CertFile = "EKO_SERVER_CERT_FILE"
+const (
+	CertFile       = "EKO_SERVER_CERT_FILE"
+	TrustedProxies = "EKO_SERVER_TRUSTED_PROXIES"
+
+	ConnLimit     = "EKO_SERVER_CONN_LIMIT"
+	ConnPenalty   = "EKO_SERVER_CONN_PENALTY"
+	ConnAllowlist = "EKO_SERVER_CONN_ALLOWLIST"
+	ConnDenylist  = "EKO_SERVER_CONN_DENYLIST"
+)
 
 const (
-	ReadCheckCancelledInterval       = 1 * time.Second
-	RateLimitWindowSize              = 1 * time.Second
-	RateLimitCountThresholdSus       = 3
-	RateLimitCountThresholdMalicious = 10
+	ReadCheckCancelledInterval = 1 * time.Second
+	IdlePresenceCheckInterval  = 1 * time.Minute
+	ProxyHeaderTimeout         = 5 * time.Second
+	GarbageCollectionInterval  = 1 * time.Hour
 )
 
 func getTLSConfig() *tls.Config {
@@ -93,6 +105,55 @@ func getTLSConfig() *tls.Config {
 	}
 }
 
+// getTrustedProxies returns the addresses allowed to send a PROXY protocol
+// header with the real client address, such as a HAProxy or nginx load balancer
+func getTrustedProxies() []netip.Prefix {
+	proxies, err := cidr.Parse(os.Getenv(TrustedProxies))
+	if err != nil {
+		slog.Error("invalid trusted proxies", "env", TrustedProxies, "error", err)
+		assert.Abort("see logs")
+	}
+	return proxies
+}
+
+// getConnLimitConfig returns the default connection limits,
+// overridden by any of the connection limit environment variables
+func getConnLimitConfig() connlimit.Config {
+	config := connlimit.DefaultConfig()
+
+	if limit, ok := os.LookupEnv(ConnLimit); ok {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			slog.Error("invalid connection limit", "env", ConnLimit, "value", limit)
+			assert.Abort("see logs")
+		}
+		config.Limit = n
+	}
+
+	if penalty, ok := os.LookupEnv(ConnPenalty); ok {
+		d, err := time.ParseDuration(penalty)
+		if err != nil {
+			slog.Error("invalid connection penalty", "env", ConnPenalty, "value", penalty)
+			assert.Abort("see logs")
+		}
+		config.Penalty = d
+	}
+
+	var err error
+	config.Allow, err = cidr.Parse(os.Getenv(ConnAllowlist))
+	if err != nil {
+		slog.Error("invalid connection allowlist", "env", ConnAllowlist, "error", err)
+		assert.Abort("see logs")
+	}
+	config.Deny, err = cidr.Parse(os.Getenv(ConnDenylist))
+	if err != nil {
+		slog.Error("invalid connection denylist", "env", ConnDenylist, "error", err)
+		assert.Abort("see logs")
+	}
+
+	return config
+}
+
 func generateDummyCert() (tls.Certificate, error) {
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -122,15 +183,12 @@ func generateDummyCert() (tls.Certificate, error) {
 }
 
 type server struct {
-	ctx      context.Context
-	node     *snowflake.Node
-	sessions map[snowflake.ID]*session.Session
-	sessMu   sync.RWMutex
-	Port     uint16
-	ipConns  map[uint32]struct {
-		start time.Time
-		count uint8
-	}
+	ctx         context.Context
+	node        *snowflake.Node
+	sessions    map[snowflake.ID][]*session.Session
+	sessMu      sync.RWMutex
+	Port        uint16
+	connLimiter *connlimit.Limiter
 }
 
 // NewServer creates a new server on the given port.
@@ -140,90 +198,125 @@ func NewServer(ctx context.Context, port uint16) server {
 	node := snowflake.NewNode(nodeId)
 	nodeId++
 
+	connLimiter, err := connlimit.New(getConnLimitConfig())
+	if err != nil {
+		slog.Error("invalid connection limit config", "error", err)
+		assert.Abort("see logs")
+	}
+
 	return server{
-		ctx:      ctx,
-		node:     node,
-		sessions: map[snowflake.ID]*session.Session{},
-		Port:     port,
-		sessMu:   sync.RWMutex{},
-		ipConns: map[uint32]struct {
-			start time.Time
-			count uint8
-		}{},
+		ctx:         ctx,
+		node:        node,
+		sessions:    map[snowflake.ID][]*session.Session{},
+		Port:        port,
+		sessMu:      sync.RWMutex{},
+		connLimiter: connLimiter,
 	}
 }
 
-func (s *server) AddSession(session *session.Session, userId snowflake.ID, pubKey ed25519.PublicKey) {
+func (s *server) AddSession(session *session.Session, userId snowflake.ID, pubKey sshkey.PublicKey) {
 	s.sessMu.Lock()
 	defer s.sessMu.Unlock()
 
 	session.Promote(userId, pubKey)
 
-	if sess, ok := s.sessions[session.ID()]; ok {
-		EvictSession(sess) // last connection wins
-		metrics.UsersActive.Dec()
-		slog.Info("closed due to new connection from another location",
-			ctxkeys.IpAddr.String(), sess.Addr(),
-			ctxkeys.UserID.String(), sess.ID(),
-			"evicted_by", session.Addr(),
-		)
-		slog.Info("this session evicted another session",
-			ctxkeys.IpAddr.String(), session.Addr(),
-			ctxkeys.UserID.String(), session.ID(),
-			"evicted", sess.Addr(),
-		)
-	}
-
-	s.sessions[session.ID()] = session
-	metrics.UsersActive.Inc()
-}
-
-func EvictSession(sess *session.Session) {
-	timeout := 10 * time.Millisecond
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	payload := &packet.Error{
-		Error: "new connection from another location, closing this one",
+	if len(s.sessions[userId]) == 0 {
+		metrics.UsersActive.Inc()
 	}
-	sess.Write(ctx, payload)
-
-	sess.Close()
+	s.sessions[userId] = append(s.sessions[userId], session)
 }
 
-func (s *server) RemoveSession(id snowflake.ID) {
+func (s *server) RemoveSession(sess *session.Session) {
 	s.sessMu.Lock()
 	defer s.sessMu.Unlock()
-	delete(s.sessions, id)
-	metrics.UsersActive.Dec()
+
+	id := sess.ID()
+	s.sessions[id] = slices.DeleteFunc(s.sessions[id], func(other *session.Session) bool {
+		return other == sess
+	})
+	if len(s.sessions[id]) == 0 {
+		delete(s.sessions, id)
+		metrics.UsersActive.Dec()
+	}
 }
 
-func (s *server) Session(id snowflake.ID) *session.Session {
+func (s *server) Sessions(id snowflake.ID) []*session.Session {
 	s.sessMu.RLock()
 	defer s.sessMu.RUnlock()
-	session := s.sessions[id]
-	return session
+	return slices.Clone(s.sessions[id])
 }
 
-func (s *server) UseSessions(f func(map[snowflake.ID]*session.Session)) {
+func (s *server) UseSessions(f func(map[snowflake.ID][]*session.Session)) {
 	s.sessMu.RLock()
 	defer s.sessMu.RUnlock()
 	f(s.sessions)
 }
 
+func (s *server) EvictSession(userId, sessionId snowflake.ID, reason string) bool {
+	s.sessMu.RLock()
+	i := slices.IndexFunc(s.sessions[userId], func(sess *session.Session) bool {
+		return sess.SessionID() == sessionId
+	})
+	var sess *session.Session
+	if i != -1 {
+		sess = s.sessions[userId][i]
+	}
+	s.sessMu.RUnlock()
+
+	if sess == nil {
+		return false
+	}
+	// Outside of the lock, as evicting waits for the write
+	sess.Evict(reason)
+	return true
+}
+
 func (s *server) Node() *snowflake.Node {
 	return s.node
 }
 
+func (s *server) checkIdlePresences() {
+	ticker := time.NewTicker(IdlePresenceCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			api.UpdateIdlePresences(s.ctx, s)
+		}
+	}
+}
+
+func (s *server) collectGarbage() {
+	ticker := time.NewTicker(GarbageCollectionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			api.CollectGarbage(s.ctx)
+		}
+	}
+}
+
 // Run starts listening and accepting clients,
 // blocking until it gets terminated by cancelling the context.
 func (s *server) Run() {
 	slog.Info("starting eko-server...")
 
-	listener, err := tls.Listen("tcp4", ":"+strconv.Itoa(int(s.Port)), getTLSConfig())
+	// Dual-stack, accepts both IPv4 and IPv6 connections
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(int(s.Port)))
 	if err != nil {
 		slog.Error("error starting server", "error", err)
 		assert.Abort("see logs")
 	}
+	if proxies := getTrustedProxies(); len(proxies) != 0 {
+		listener = proxyproto.NewListener(listener, proxies, ProxyHeaderTimeout)
+		slog.Info("accepting proxy protocol headers", "trusted_proxies", proxies)
+	}
+	listener = tls.NewListener(listener, getTLSConfig())
 
 	assert.AddFlush(listener)
 	defer listener.Close()
@@ -231,6 +324,13 @@ func (s *server) Run() {
 		<-s.ctx.Done()
 		_ = listener.Close()
 	}()
+	go s.checkIdlePresences()
+	go s.cleanupUserBuckets()
+	go s.collectGarbage()
+	if os.Getenv(RequestCostsAutotune) == "true" {
+		go s.tuneRequestCosts()
+		slog.Info("request costs autotune enabled", "interval", CostTuneInterval.String())
+	}
 
 	slog.Info("server started accepting new connections", "port", s.Port)
 	var wg sync.WaitGroup
@@ -249,8 +349,7 @@ func (s *server) Run() {
 			continue // Ignore and skip (don't connect)
 		}
 
-		ip := binary.BigEndian.Uint32(conn.RemoteAddr().(*net.TCPAddr).IP.To4())
-		if s.isRateLimited(ip) {
+		if !s.acceptConnection(conn.RemoteAddr().(*net.TCPAddr).IP) {
 			_ = conn.Close()
 			continue
 		}
@@ -294,13 +393,13 @@ func (server *server) handleConnection(conn net.Conn) {
 		<-ctx.Done()
 		if sess.IsAuthenticated() {
 			server.handleSessionMetrics(ctx, sess)
+			api.AbortUpload(ctx, sess)
 
-			// Remove session after cancellation
-			sameAddress := addr.String() == server.Session(sess.ID()).Addr().String()
-			// false if the user signed in from a different connection
-			if sameAddress {
-				server.RemoveSession(sess.ID())
-			}
+			// Remove session after cancellation, the user is
+			// still online if they have sessions on other devices
+			server.RemoveSession(sess)
+			online := len(server.Sessions(sess.ID())) != 0
+			api.UpdatePresence(context.WithoutCancel(ctx), sess, online)
 		}
 	}()
 
@@ -410,10 +509,9 @@ func (server *server) handleConnection(conn net.Conn) {
 }
 
 func processPacket(ctx context.Context, sess *session.Session, pkt packet.Packet) bool {
-	tokens := TokensPerRequest(pkt.Type())
-	if !sess.RateLimiter().Take(tokens) {
-		_ = sess.Write(ctx, &api.ErrRateLimited)
-		return false // Rate limit was hit
+	if retryAfter, ok := takeRequest(sess, pkt.Type()); !ok {
+		_ = sess.Write(ctx, rateLimited(retryAfter))
+		return false // Rate limit of the session or user was hit
 	}
 
 	var response packet.Payload
@@ -517,16 +615,49 @@ func processAuthenticatedRequests(ctx context.Context, sess *session.Session, re
 		response = timeout(5*time.Millisecond, api.EditMessage, ctx, sess, request)
 	case *packet.DeleteMessage:
 		response = timeout(5*time.Millisecond, api.DeleteMessage, ctx, sess, request)
+	case *packet.PinMessage:
+		response = timeout(5*time.Millisecond, api.PinMessage, ctx, sess, request)
 	case *packet.RequestMessages:
 		response = timeout(50*time.Millisecond, api.RequestMessages, ctx, sess, request)
+	case *packet.SearchMessages:
+		response = timeout(100*time.Millisecond, api.SearchMessages, ctx, sess, request)
+
+	case *packet.UploadAttachment:
+		response = timeout(20*time.Millisecond, api.UploadAttachment, ctx, sess, request)
+	case *packet.AttachmentChunk:
+		response = timeout(50*time.Millisecond, api.AttachmentChunk, ctx, sess, request)
+
+	case *packet.ViewChat:
+		response = timeout(5*time.Millisecond, api.ViewChat, ctx, sess, request)
+	case *packet.Typing:
+		response = timeout(20*time.Millisecond, api.Typing, ctx, sess, request)
 
 	case *packet.GetBannedMembers:
 		response = timeout(10*time.Millisecond, api.GetBannedMembers, ctx, sess, request)
 	case *packet.SetMember:
 		response = timeout(50*time.Millisecond, api.SetMember, ctx, sess, request)
 
+	case *packet.CreateGroupSignal:
+		response = timeout(50*time.Millisecond, api.CreateGroupSignal, ctx, sess, request)
+	case *packet.AddGroupSignalMember:
+		response = timeout(20*time.Millisecond, api.AddGroupSignalMember, ctx, sess, request)
+	case *packet.LeaveGroupSignal:
+		response = timeout(20*time.Millisecond, api.LeaveGroupSignal, ctx, sess, request)
+
 	case *packet.TrustUser:
 		response = timeout(10*time.Millisecond, api.TrustUser, ctx, sess, request)
+	case *packet.RotateKey:
+		response = timeout(20*time.Millisecond, api.RotateKey, ctx, sess, request)
+	case *packet.AuthorizeDevice:
+		response = timeout(20*time.Millisecond, api.AuthorizeDevice, ctx, sess, request)
+	case *packet.RevokeDevice:
+		response = timeout(20*time.Millisecond, api.RevokeDevice, ctx, sess, request)
+	case *packet.GetSessions:
+		response = timeout(5*time.Millisecond, api.GetSessions, ctx, sess, request)
+	case *packet.TerminateSession:
+		response = timeout(20*time.Millisecond, api.TerminateSession, ctx, sess, request)
+	case *packet.IgnoreMessageRequest:
+		response = timeout(5*time.Millisecond, api.IgnoreMessageRequest, ctx, sess, request)
 
 	case *packet.SetLastReadMessages:
 		response = timeout(50*time.Millisecond, api.SetLastReadMessages, ctx, sess, request)
@@ -541,12 +672,36 @@ func processAuthenticatedRequests(ctx context.Context, sess *session.Session, re
 		response = nil
 	}
 
+	if _, isError := response.(*packet.Error); response != nil && !isError && SyncsDevices(request.Type()) {
+		api.DevicesPropagate(ctx, sess, response)
+	}
+
 	// TODO: add a timeout for this (even tho it should be super fast)
 	api.SetLastUserActivity(ctx, sess)
 
 	return response
 }
 
+// SyncsDevices returns whether the response to the request describes a
+// change that the user's other devices must see as well
+func SyncsDevices(requestType packet.PacketType) bool {
+	switch requestType {
+	case packet.PacketCreateNetwork, packet.PacketUpdateNetwork,
+		packet.PacketTransferNetwork, packet.PacketDeleteNetwork,
+		packet.PacketCreateFrequency, packet.PacketUpdateFrequency,
+		packet.PacketDeleteFrequency, packet.PacketSwapFrequencies,
+		packet.PacketSendMessage, packet.PacketEditMessage,
+		packet.PacketDeleteMessage, packet.PacketPinMessage,
+		packet.PacketSetMember, packet.PacketTrustUser, packet.PacketBlockUser,
+		packet.PacketCreateGroupSignal, packet.PacketAddGroupSignalMember,
+		packet.PacketLeaveGroupSignal, packet.PacketIgnoreMessageRequest,
+		packet.PacketSetUserData, packet.PacketAuthorizeDevice, packet.PacketRevokeDevice:
+		return true
+	default:
+		return false
+	}
+}
+
 func timeout[T packet.Payload](
 	timeoutDuration time.Duration,
 	apiRequest func(context.Context, *session.Session, T) packet.Payload,
@@ -586,6 +741,8 @@ func sendTosInfo(ctx context.Context, sess *session.Session) bool {
 	return sess.Write(ctx, payload)
 }
 
+// TokensPerRequest returns the default cost of a request type,
+// used when it's not configured or tuned, see RequestCost
 func TokensPerRequest(requestType packet.PacketType) float64 {
 	// 1 token means 1 token per second, which is equivalent  to 1ms
 	// The idea is that for 1000 users, each user has 1ms of server time
@@ -601,97 +758,75 @@ func TokensPerRequest(requestType packet.PacketType) float64 {
 		return 1.5
 	case packet.PacketDeviceAnalytics:
 		return 0.2 // arbitrary
+	case packet.PacketSearchMessages:
+		return 5 // arbitrary, full-text search is expensive
+	case packet.PacketAttachmentChunk:
+		return 0.1 // arbitrary, cheap enough to allow ~320KiB/s transfers
+	case packet.PacketViewChat:
+		return 0.1 // arbitrary, sent on every chat switch
+	case packet.PacketRotateKey:
+		return 3 // arbitrary, verifies two signatures
+	case packet.PacketAuthorizeDevice:
+		return 1.5 // arbitrary, verifies a signature
 
 	// TODO(kyren): once I get more data for these, add them
+	// Until then they can be calibrated with RequestCostsFile or RequestCostsAutotune
+	case packet.PacketAddGroupSignalMember:
 	case packet.PacketBlockUser:
 	case packet.PacketCreateFrequency:
+	case packet.PacketCreateGroupSignal:
 	case packet.PacketCreateNetwork:
 	case packet.PacketDeleteFrequency:
 	case packet.PacketDeleteMessage:
 	case packet.PacketDeleteNetwork:
 	case packet.PacketEditMessage:
 	case packet.PacketGetBannedMembers:
+	case packet.PacketGetSessions:
 	case packet.PacketGetUserData:
 	case packet.PacketGetUsers:
+	case packet.PacketIgnoreMessageRequest:
+	case packet.PacketLeaveGroupSignal:
+	case packet.PacketPinMessage:
 	case packet.PacketRequestMessages:
+	case packet.PacketRevokeDevice:
 	case packet.PacketSendMessage:
 	case packet.PacketSetLastReadMessages:
 	case packet.PacketSetMember:
 	case packet.PacketSetUserData:
 	case packet.PacketSwapFrequencies:
+	case packet.PacketTerminateSession:
 	case packet.PacketTransferNetwork:
 	case packet.PacketTrustUser:
+	case packet.PacketTyping:
 	case packet.PacketUpdateFrequency:
 	case packet.PacketUpdateNetwork:
+	case packet.PacketUploadAttachment:
 
 	}
 
 	return 1
 }
 
-func (s *server) isRateLimited(ip uint32) bool {
-	if entry, ok := s.ipConns[ip]; ok {
-		outsideWindow := time.Since(entry.start) > RateLimitWindowSize
-		notMalicious := entry.count < RateLimitCountThresholdMalicious
-		if outsideWindow && notMalicious {
-			entry.start = time.Now().UTC()
-			entry.count = 1
-
-			s.ipConns[ip] = entry
-			return false
-		}
-
-		ipStr := formatIPv4(ip)
-
-		if entry.count < RateLimitCountThresholdSus {
-			slog.Info("connection activity", "ip", ipStr, "count", entry.count)
-			entry.count++
-			s.ipConns[ip] = entry
-			return false
-		} else if entry.count < RateLimitCountThresholdMalicious {
-			metrics.ConnectionsRateLimited.WithLabelValues("suspicious").Inc()
-			if entry.count == RateLimitCountThresholdSus {
-				slog.Warn("suspicious connection activity", "ip", ipStr, "count", entry.count)
-				// Only log the first one
-			}
-			entry.count++
-			s.ipConns[ip] = entry
-			return true
-		} else {
-			metrics.ConnectionsRateLimited.WithLabelValues("malicious").Inc()
-			if entry.count == RateLimitCountThresholdMalicious {
-				slog.Warn("potential malicious connection behavior", "ip", ipStr, "count", entry.count)
-				// Only log the first one
-				entry.count++
-				s.ipConns[ip] = entry
-				// Update so it doesn't spam
-			}
-			// Don't bother to update counts, save resources
-			return true
-		}
+func (s *server) acceptConnection(ip net.IP) bool {
+	decision := s.connLimiter.Check(ip)
+	metrics.ConnectionDecisions.WithLabelValues(decision.String()).Inc()
+	metrics.ConnectionLimiterEntries.Set(float64(s.connLimiter.Len()))
+	switch decision {
+	case connlimit.RateLimited:
+		metrics.ConnectionsRateLimited.WithLabelValues("suspicious").Inc()
+	case connlimit.Penalized:
+		metrics.ConnectionsRateLimited.WithLabelValues("malicious").Inc()
 	}
 
-	s.ipConns[ip] = struct {
-		start time.Time
-		count uint8
-	}{
-		start: time.Now().UTC(),
-		count: 1,
+	if decision == connlimit.RateLimited {
+		// Only logged once per offense, later connections are just penalized
+		slog.Warn("connection rate limited",
+			"ip", session.AddrPrefix(ip).String(),
+			"penalty", s.connLimiter.Penalty(ip).String(),
+		)
 	}
 
-	return false
-}
-
-func formatIPv4(ip uint32) string {
-	var b [15]byte // max len for "255.255.255.255"
-	n := strconv.AppendUint(b[:0], uint64(ip>>24), 10)
-	n = append(n, '.')
-	n = strconv.AppendUint(n, uint64((ip>>16)&0xFF), 10)
-	n = append(n, '.')
-	n = strconv.AppendUint(n, uint64((ip>>8)&0xFF), 10)
-	n = append(n, '.')
-	n = strconv.AppendUint(n, uint64(ip&0xFF), 10)
-	return string(n)
+	return decision.Accept()
 }
 
 func (s *server) handleSessionMetrics(ctx context.Context, sess *session.Session) {
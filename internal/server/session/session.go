@@ -18,10 +18,12 @@ package session
 
 import (
 	"context"
-	"crypto/ed25519"
 	"crypto/rand"
+	"hash"
 	"log/slog"
 	"net"
+	"net/netip"
+	"os"
 	"sync"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/kyren223/eko/pkg/assert"
 	"github.com/kyren223/eko/pkg/rate"
 	"github.com/kyren223/eko/pkg/snowflake"
+	"github.com/kyren223/eko/pkg/sshkey"
 )
 
 const (
@@ -43,14 +46,29 @@ const (
 )
 
 type SessionManager interface {
-	AddSession(session *Session, userId snowflake.ID, pubKey ed25519.PublicKey)
-	RemoveSession(id snowflake.ID)
-	Session(id snowflake.ID) *Session
-	UseSessions(f func(map[snowflake.ID]*Session))
+	AddSession(session *Session, userId snowflake.ID, pubKey sshkey.PublicKey)
+	RemoveSession(session *Session)
+	// Sessions returns all sessions of the user, one per connected device
+	Sessions(id snowflake.ID) []*Session
+	UseSessions(f func(map[snowflake.ID][]*Session))
+	// EvictSession closes the user's session with the given session id,
+	// returns false if no such session exists
+	EvictSession(userId, sessionId snowflake.ID, reason string) bool
 
 	Node() *snowflake.Node
 }
 
+// Upload is an attachment that is being uploaded in chunks
+type Upload struct {
+	File    *os.File
+	Hasher  hash.Hash
+	Name    string
+	Hash    string
+	Size    int64
+	Written int64
+	ID      snowflake.ID
+}
+
 type Session struct {
 	manager SessionManager
 	addr    *net.TCPAddr
@@ -65,11 +83,16 @@ type Session struct {
 	challengeMu sync.Mutex
 
 	isTosAccepted bool
-	pubKey        ed25519.PublicKey
+	pubKey        sshkey.PublicKey
 	id            snowflake.ID
+	sessionId     snowflake.ID
 	rl            rate.Limiter
 	start         time.Time
 	analytics     *packet.DeviceAnalytics
+	upload        *Upload
+	viewing       *snowflake.ID
+	lastActivity  time.Time
+	presence      packet.Presence
 
 	mu sync.RWMutex
 }
@@ -90,13 +113,18 @@ func NewSession(
 		writeMu:       sync.RWMutex{},
 		issuedTime:    time.Time{},
 		challenge:     make([]byte, NonceSize),
-		pubKey:        ed25519.PublicKey{},
+		pubKey:        sshkey.PublicKey{},
 		id:            snowflake.InvalidID,
+		sessionId:     snowflake.InvalidID,
 		challengeMu:   sync.Mutex{},
 		isTosAccepted: false,
 		rl:            rate.NewLimiter(DefaultRate, DefaultLimit),
 		start:         time.Time{},
 		analytics:     nil,
+		upload:        nil,
+		viewing:       nil,
+		lastActivity:  time.Time{},
+		presence:      packet.Presence{},
 		mu:            sync.RWMutex{},
 	}
 	return session
@@ -107,6 +135,25 @@ func (s *Session) Addr() *net.TCPAddr {
 	return s.addr
 }
 
+// AddrPrefix returns the address block of the session, see [AddrPrefix]
+func (s *Session) AddrPrefix() netip.Prefix {
+	return AddrPrefix(s.Addr().IP)
+}
+
+// AddrPrefix returns the address block treated as a single client,
+// the full address for IPv4 or the /64 for IPv6 (usually one subscriber)
+func AddrPrefix(ip net.IP) netip.Prefix {
+	addr, ok := netip.AddrFromSlice(ip)
+	assert.Assert(ok, "tcp address should always be a valid ip", "ip", ip)
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return netip.PrefixFrom(addr, 32)
+	}
+	prefix, err := addr.Prefix(64)
+	assert.NoError(err, "64 is always a valid prefix length for IPv6")
+	return prefix
+}
+
 func (s *Session) RateLimiter() *rate.Limiter {
 	return &s.rl
 }
@@ -136,23 +183,47 @@ func (s *Session) ID() snowflake.ID {
 	return s.id
 }
 
-func (s *Session) PubKey() ed25519.PublicKey {
+// SessionID uniquely identifies the session among the user's other sessions
+func (s *Session) SessionID() snowflake.ID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Not IsAuthenticated, taking the read lock twice deadlocks if a writer is waiting
+	assert.Assert(s.id != snowflake.InvalidID, "use of SessionID in an unauthenticated session", "addr", s.addr)
+	return s.sessionId
+}
+
+func (s *Session) PubKey() sshkey.PublicKey {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	assert.Assert(s.IsAuthenticated(), "use of PubKey in an unauthenticated session", "addr", s.addr)
 	return s.pubKey
 }
 
-func (s *Session) Promote(userId snowflake.ID, pubKey ed25519.PublicKey) {
+// SetPubKey replaces the public key after the user rotated it
+func (s *Session) SetPubKey(pubKey sshkey.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pubKey = pubKey
+}
+
+func (s *Session) Promote(userId snowflake.ID, pubKey sshkey.PublicKey) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.id = userId
+	s.sessionId = s.manager.Node().Generate()
 	s.pubKey = pubKey
 	s.rl.SetLimit(AuthenticatedLimit)
 	s.rl.SetRate(AuthenticatedRate)
 	s.start = time.Now().UTC()
 }
 
+func (s *Session) Start() time.Time {
+	assert.Assert(s.IsAuthenticated(), "tried accessing session start in an unauthenticated session")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.start
+}
+
 func (s *Session) Duration() time.Duration {
 	assert.Assert(s.IsAuthenticated(), "tried accessing session duration in an authenticated session")
 	s.mu.RLock()
@@ -172,6 +243,61 @@ func (s *Session) SetAnalytics(analytics *packet.DeviceAnalytics) {
 	s.analytics = analytics
 }
 
+func (s *Session) Upload() *Upload {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.upload
+}
+
+// SetUpload replaces the pending upload, returning the previous one
+func (s *Session) SetUpload(upload *Upload) *Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.upload
+	s.upload = upload
+	return previous
+}
+
+// Viewing returns the frequency id or the DM's user id the session's
+// user is currently viewing, nil if neither
+func (s *Session) Viewing() *snowflake.ID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.viewing
+}
+
+func (s *Session) SetViewing(viewing *snowflake.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.viewing = viewing
+}
+
+func (s *Session) LastActivity() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastActivity
+}
+
+func (s *Session) SetLastActivity(lastActivity time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActivity = lastActivity
+}
+
+// Presence returns the last presence of the session's user
+// that was propagated to other users
+func (s *Session) Presence() packet.Presence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.presence
+}
+
+func (s *Session) SetPresence(presence packet.Presence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presence = presence
+}
+
 func (s *Session) Manager() SessionManager {
 	return s.manager
 }
@@ -220,6 +346,16 @@ func (s *Session) Close() {
 	s.cancel()
 }
 
+// Evict notifies the client with the given reason and closes the session
+func (s *Session) Evict(reason string) {
+	timeout := 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	s.Write(ctx, &packet.Error{Error: reason})
+
+	s.Close()
+}
+
 func (s *Session) LogValue() slog.Value {
 	if s.IsAuthenticated() {
 		return slog.GroupValue(
@@ -0,0 +1,72 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cidr
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"only separators", " , ,", nil, false},
+		{"ipv4 address", "192.0.2.1", []string{"192.0.2.1/32"}, false},
+		{"ipv6 address", "2001:db8::1", []string{"2001:db8::1/128"}, false},
+		{"cidr", "10.0.0.0/8", []string{"10.0.0.0/8"}, false},
+		{"cidr is masked", "10.1.2.3/8", []string{"10.0.0.0/8"}, false},
+		{"list", " 10.0.0.0/8 ,2001:db8::/32,", []string{"10.0.0.0/8", "2001:db8::/32"}, false},
+		{"invalid address", "10.0.0", nil, true},
+		{"invalid cidr", "10.0.0.0/33", nil, true},
+		{"hostname", "localhost", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefixes, err := Parse(test.s)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var got []string
+			for _, prefix := range prefixes {
+				got = append(got, prefix.String())
+			}
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	prefixes, err := Parse("10.0.0.0/8,2001:db8::1")
+	require.NoError(t, err)
+
+	require.True(t, Contains(prefixes, netip.MustParseAddr("10.1.2.3")))
+	require.True(t, Contains(prefixes, netip.MustParseAddr("2001:db8::1")))
+	require.False(t, Contains(prefixes, netip.MustParseAddr("11.0.0.1")))
+	require.False(t, Contains(prefixes, netip.MustParseAddr("2001:db8::2")))
+	require.False(t, Contains(nil, netip.MustParseAddr("10.1.2.3")))
+}
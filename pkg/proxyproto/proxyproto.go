@@ -0,0 +1,290 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package proxyproto implements the receiving side of the PROXY protocol
+// (v1 and v2), as sent by HAProxy and nginx, so the real client address
+// is available behind a load balancer.
+// See https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyren223/eko/pkg/cidr"
+)
+
+const (
+	v1Prefix    = "PROXY "
+	v1MaxLength = 107 // Including the CRLF
+	v2HeaderLen = 16
+)
+
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+var ErrInvalidHeader = errors.New("invalid proxy protocol header")
+
+// Conn is a connection whose remote address is the one sent by the proxy
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	remote net.Addr
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// ReadHeader reads a v1 or v2 header from r, returning the source address.
+// The address is nil for connections the proxy made on its own behalf
+// (health checks), in which case the proxy's own address should be used.
+func ReadHeader(r *bufio.Reader) (*net.TCPAddr, error) {
+	signature, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(signature, v2Signature) {
+		return readV2(r)
+	}
+	if strings.HasPrefix(string(signature), v1Prefix) {
+		return readV1(r)
+	}
+	return nil, fmt.Errorf("%w: missing signature", ErrInvalidHeader)
+}
+
+func readV1(r *bufio.Reader) (*net.TCPAddr, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(line) > v1MaxLength {
+		return nil, fmt.Errorf("%w: v1 header too long", ErrInvalidHeader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: v1 header must end with CRLF", ErrInvalidHeader)
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: malformed v1 header", ErrInvalidHeader)
+	}
+
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil || addr.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("%w: invalid v1 source address", ErrInvalidHeader)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid v1 source port", ErrInvalidHeader)
+	}
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+func readV2(r *bufio.Reader) (*net.TCPAddr, error) {
+	header := make([]byte, v2HeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	version, command := header[12]>>4, header[12]&0x0F
+	family := header[13]
+	length := binary.BigEndian.Uint16(header[14:16])
+	if version != 2 {
+		return nil, fmt.Errorf("%w: unsupported version %v", ErrInvalidHeader, version)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	const (
+		commandLocal = 0x0
+		commandProxy = 0x1
+		tcpOverIPv4  = 0x11
+		tcpOverIPv6  = 0x21
+	)
+
+	switch command {
+	case commandLocal:
+		return nil, nil
+	case commandProxy:
+	default:
+		return nil, fmt.Errorf("%w: unsupported command %v", ErrInvalidHeader, command)
+	}
+
+	var size int
+	switch family {
+	case tcpOverIPv4:
+		size = net.IPv4len
+	case tcpOverIPv6:
+		size = net.IPv6len
+	default:
+		// Unix sockets or UDP, nothing useful to report
+		return nil, nil
+	}
+
+	// Source address, destination address, source port, destination port
+	// followed by optional TLVs which are ignored
+	if len(payload) < 2*size+4 {
+		return nil, fmt.Errorf("%w: v2 address block too short", ErrInvalidHeader)
+	}
+	addr, _ := netip.AddrFromSlice(payload[:size])
+	port := binary.BigEndian.Uint16(payload[2*size:])
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, port)), nil
+}
+
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+// Listener reads a PROXY protocol header from connections of trusted proxies.
+// Connections from other addresses are passed through untouched, so clients
+// can't spoof their address.
+type Listener struct {
+	inner   net.Listener
+	trusted []netip.Prefix
+	timeout time.Duration
+
+	accepted chan accepted
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewListener starts accepting from inner, trusted proxies must send a header
+// within timeout, headers are read concurrently so a slow proxy connection
+// doesn't block the others.
+func NewListener(inner net.Listener, trusted []netip.Prefix, timeout time.Duration) *Listener {
+	l := &Listener{
+		inner:    inner,
+		trusted:  trusted,
+		timeout:  timeout,
+		accepted: make(chan accepted),
+		done:     make(chan struct{}),
+	}
+	go l.acceptForever()
+	return l
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case a := <-l.accepted:
+		return a.conn, a.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.inner.Close()
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.inner.Addr()
+}
+
+func (l *Listener) acceptForever() {
+	for {
+		conn, err := l.inner.Accept()
+		if err != nil {
+			l.deliver(accepted{err: err})
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		if !l.isTrusted(conn.RemoteAddr()) {
+			l.deliver(accepted{conn: conn})
+			continue
+		}
+
+		go func() {
+			proxied, err := l.readHeader(conn)
+			if err != nil {
+				_ = conn.Close()
+				err = fmt.Errorf("proxy %v: %w", conn.RemoteAddr(), err)
+				l.deliver(accepted{err: err})
+				return
+			}
+			l.deliver(accepted{conn: proxied})
+		}()
+	}
+}
+
+func (l *Listener) deliver(a accepted) {
+	select {
+	case l.accepted <- a:
+	case <-l.done:
+		if a.conn != nil {
+			_ = a.conn.Close()
+		}
+	}
+}
+
+func (l *Listener) readHeader(conn net.Conn) (net.Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(l.timeout)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	addr, err := ReadHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	proxied := &Conn{Conn: conn, reader: reader, remote: conn.RemoteAddr()}
+	if addr != nil {
+		proxied.remote = addr
+	}
+	return proxied, nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcpAddr.IP)
+	if !ok {
+		return false
+	}
+	return cidr.Contains(l.trusted, ip.Unmap())
+}
@@ -0,0 +1,233 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// v2Header builds a v2 header with the given version and command byte,
+// address family and payload, the length is taken from the payload
+func v2Header(versionCommand, family byte, payload []byte) []byte {
+	return v2HeaderWithLength(versionCommand, family, uint16(len(payload)), payload)
+}
+
+func v2HeaderWithLength(versionCommand, family byte, length uint16, payload []byte) []byte {
+	header := append([]byte(nil), v2Signature...)
+	header = append(header, versionCommand, family)
+	header = binary.BigEndian.AppendUint16(header, length)
+	return append(header, payload...)
+}
+
+// v2Addresses builds the address block for the given source and destination
+func v2Addresses(src, dst netip.AddrPort) []byte {
+	var block []byte
+	block = append(block, src.Addr().AsSlice()...)
+	block = append(block, dst.Addr().AsSlice()...)
+	block = binary.BigEndian.AppendUint16(block, src.Port())
+	block = binary.BigEndian.AppendUint16(block, dst.Port())
+	return block
+}
+
+func TestReadHeader(t *testing.T) {
+	src4 := netip.MustParseAddrPort("192.0.2.1:5678")
+	dst4 := netip.MustParseAddrPort("198.51.100.1:7223")
+	src6 := netip.MustParseAddrPort("[2001:db8::1]:5678")
+	dst6 := netip.MustParseAddrPort("[2001:db8::2]:7223")
+	addrs4 := v2Addresses(src4, dst4)
+	addrs6 := v2Addresses(src6, dst6)
+	tlv := []byte{0x04, 0x00, 0x03, 'a', 'b', 'c'}
+
+	tests := []struct {
+		name    string
+		header  []byte
+		want    *net.TCPAddr
+		wantErr error
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 5678 7223\r\n"), net.TCPAddrFromAddrPort(src4), nil},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 5678 7223\r\n"), net.TCPAddrFromAddrPort(src6), nil},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), nil, nil},
+		{"v1 unknown with addresses", []byte("PROXY UNKNOWN 192.0.2.1 198.51.100.1 5678 7223\r\n"), nil, nil},
+		{"v1 tcp4 with ipv6 address", []byte("PROXY TCP4 2001:db8::1 2001:db8::2 5678 7223\r\n"), nil, ErrInvalidHeader},
+		{"v1 tcp6 with ipv4 address", []byte("PROXY TCP6 192.0.2.1 198.51.100.1 5678 7223\r\n"), nil, ErrInvalidHeader},
+		{"v1 udp", []byte("PROXY UDP4 192.0.2.1 198.51.100.1 5678 7223\r\n"), nil, ErrInvalidHeader},
+		{"v1 invalid address", []byte("PROXY TCP4 192.0.2 198.51.100.1 5678 7223\r\n"), nil, ErrInvalidHeader},
+		{"v1 invalid port", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 65536 7223\r\n"), nil, ErrInvalidHeader},
+		{"v1 missing field", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 5678\r\n"), nil, ErrInvalidHeader},
+		{"v1 missing CR", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 5678 7223\n"), nil, ErrInvalidHeader},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", v1MaxLength) + "\r\n"), nil, ErrInvalidHeader},
+		{"v1 truncated", []byte("PROXY TCP4 192.0.2.1 198.51.100.1"), nil, io.EOF},
+
+		{"v2 tcp4", v2Header(0x21, 0x11, addrs4), net.TCPAddrFromAddrPort(src4), nil},
+		{"v2 tcp6", v2Header(0x21, 0x21, addrs6), net.TCPAddrFromAddrPort(src6), nil},
+		{"v2 tlvs are ignored", v2Header(0x21, 0x11, append(addrs4, tlv...)), net.TCPAddrFromAddrPort(src4), nil},
+		{"v2 local", v2Header(0x20, 0x00, nil), nil, nil},
+		{"v2 local ignores addresses", v2Header(0x20, 0x11, addrs4), nil, nil},
+		{"v2 unspecified family", v2Header(0x21, 0x00, nil), nil, nil},
+		{"v2 udp over ipv4", v2Header(0x21, 0x12, addrs4), nil, nil},
+		{"v2 unix stream", v2Header(0x21, 0x31, make([]byte, 216)), nil, nil},
+		{"v2 unsupported version", v2Header(0x11, 0x11, addrs4), nil, ErrInvalidHeader},
+		{"v2 unsupported command", v2Header(0x22, 0x11, addrs4), nil, ErrInvalidHeader},
+		{"v2 tcp4 address block too short", v2Header(0x21, 0x11, addrs4[:11]), nil, ErrInvalidHeader},
+		{"v2 tcp6 with ipv4 addresses", v2Header(0x21, 0x21, addrs4), nil, ErrInvalidHeader},
+		{"v2 truncated header", v2Header(0x21, 0x11, nil)[:14], nil, io.ErrUnexpectedEOF},
+		{"v2 truncated addresses", v2HeaderWithLength(0x21, 0x11, 12, addrs4[:6]), nil, io.ErrUnexpectedEOF},
+		{"v2 length past the data", v2HeaderWithLength(0x21, 0x11, 0xFFFF, addrs4), nil, io.ErrUnexpectedEOF},
+
+		{"bad signature", []byte("GET / HTTP/1.1\r\n\r\n"), nil, ErrInvalidHeader},
+		{"bad v2 signature", append([]byte("\r\n\r\n\x00\r\nQUIT\r"), 0x21, 0x11, 0, 0), nil, ErrInvalidHeader},
+		{"lowercase v1 prefix", []byte("proxy TCP4 192.0.2.1 198.51.100.1 5678 7223\r\n"), nil, ErrInvalidHeader},
+		{"shorter than a signature", []byte("PROXY"), nil, io.EOF},
+		{"empty", nil, nil, io.EOF},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, err := ReadHeader(bufio.NewReader(bytes.NewReader(test.header)))
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, addr)
+		})
+	}
+}
+
+func TestReadHeaderLeavesData(t *testing.T) {
+	src := netip.MustParseAddrPort("192.0.2.1:5678")
+	dst := netip.MustParseAddrPort("198.51.100.1:7223")
+
+	headers := map[string][]byte{
+		"v1": []byte("PROXY TCP4 192.0.2.1 198.51.100.1 5678 7223\r\n"),
+		"v2": v2Header(0x21, 0x11, v2Addresses(src, dst)),
+	}
+	for name, header := range headers {
+		t.Run(name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(append(header, "hello"...)))
+			_, err := ReadHeader(r)
+			require.NoError(t, err)
+
+			rest, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, "hello", string(rest))
+		})
+	}
+}
+
+// listen returns a listener on loopback that trusts the given prefixes
+func listen(t *testing.T, trusted ...netip.Prefix) *Listener {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l := NewListener(inner, trusted, 100*time.Millisecond)
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+// dial connects to the listener and writes data
+func dial(t *testing.T, l *Listener, data []byte) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+	return conn
+}
+
+func TestListener(t *testing.T) {
+	loopback := netip.MustParsePrefix("127.0.0.0/8")
+	elsewhere := netip.MustParsePrefix("10.0.0.0/8")
+	header := "PROXY TCP4 192.0.2.1 198.51.100.1 5678 7223\r\n"
+
+	tests := []struct {
+		name     string
+		trusted  []netip.Prefix
+		data     string
+		wantAddr string // empty for the dialer's own address
+		wantData string
+		wantErr  bool
+	}{
+		{"trusted with header", []netip.Prefix{loopback}, header + "hello", "192.0.2.1:5678", "hello", false},
+		{"trusted with local header", []netip.Prefix{loopback}, "PROXY UNKNOWN\r\nhello", "", "hello", false},
+		{"trusted without header", []netip.Prefix{loopback}, "hello world, no header", "", "", true},
+		{"untrusted with header", []netip.Prefix{elsewhere}, header + "hello", "", header + "hello", false},
+		{"untrusted without header", []netip.Prefix{elsewhere}, "hello", "", "hello", false},
+		{"nothing trusted", nil, header + "hello", "", header + "hello", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := listen(t, test.trusted...)
+			client := dial(t, l, []byte(test.data))
+
+			conn, err := l.Accept()
+			if test.wantErr {
+				require.ErrorIs(t, err, ErrInvalidHeader)
+				return
+			}
+			require.NoError(t, err)
+			defer conn.Close()
+
+			wantAddr := client.LocalAddr().String()
+			if test.wantAddr != "" {
+				wantAddr = test.wantAddr
+			}
+			require.Equal(t, wantAddr, conn.RemoteAddr().String())
+
+			data := make([]byte, len(test.wantData))
+			_, err = io.ReadFull(conn, data)
+			require.NoError(t, err)
+			require.Equal(t, test.wantData, string(data))
+		})
+	}
+}
+
+func TestListenerHeaderTimeout(t *testing.T) {
+	l := listen(t, netip.MustParsePrefix("127.0.0.0/8"))
+	slow := dial(t, l, []byte("PROXY TCP4"))
+
+	_, err := l.Accept()
+	require.Error(t, err)
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	require.True(t, netErr.Timeout())
+
+	// The slow connection is closed, not handed out
+	_ = slow.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = slow.Read(make([]byte, 1))
+	require.Error(t, err)
+}
+
+func TestListenerClose(t *testing.T) {
+	l := listen(t)
+	require.NoError(t, l.Close())
+
+	_, err := l.Accept()
+	require.ErrorIs(t, err, net.ErrClosed)
+}
@@ -16,7 +16,10 @@
 
 package rate
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 type Limiter struct {
 	limit float64
@@ -24,15 +27,19 @@ type Limiter struct {
 
 	lastRefill time.Time
 	tokens     float64
+
+	now func() time.Time
 }
 
 // rate refills limiter rate tokens per second
 func NewLimiter(rate float64, limit float64) Limiter {
+	now := func() time.Time { return time.Now().UTC() }
 	return Limiter{
 		limit:      limit,
 		rate:       rate,
-		lastRefill: time.Now().UTC(),
+		lastRefill: now(),
 		tokens:     limit,
+		now:        now,
 	}
 }
 
@@ -51,6 +58,10 @@ func (rl *Limiter) SetLimit(limit float64) {
 	rl.limit = limit
 }
 
+func (rl *Limiter) Limit() float64 {
+	return rl.limit
+}
+
 func (rl *Limiter) Take(tokens float64) bool {
 	rl.update()
 	has := rl.Has(tokens)
@@ -66,9 +77,35 @@ func (rl *Limiter) Has(tokens float64) bool {
 	return rl.tokens >= tokens
 }
 
+// Until returns how long until the given tokens can be taken,
+// zero if they can be taken now, tokens above the limit are capped at the
+// limit and the longest duration is returned if they are never refilled
+func (rl *Limiter) Until(tokens float64) time.Duration {
+	rl.update()
+	missing := min(tokens, rl.limit) - rl.tokens
+	if missing <= 0 {
+		return 0
+	}
+	if rl.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	until := missing / rl.rate * float64(time.Second)
+	if until >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(until)
+}
+
+// Full returns whether the limiter is at its limit,
+// in which case it's indistinguishable from a new limiter
+func (rl *Limiter) Full() bool {
+	rl.update()
+	return rl.tokens >= rl.limit
+}
+
 func (rl *Limiter) update() {
 	lastRefill := rl.lastRefill
-	rl.lastRefill = time.Now().UTC()
+	rl.lastRefill = rl.now()
 
-	rl.tokens += min(time.Since(lastRefill).Seconds()*rl.rate, rl.limit)
+	rl.tokens = min(rl.tokens+rl.lastRefill.Sub(lastRefill).Seconds()*rl.rate, rl.limit)
 }
@@ -0,0 +1,141 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(rate, limit float64) (*Limiter, *clock) {
+	clock := &clock{now: time.Unix(0, 0).UTC()}
+	limiter := NewLimiter(rate, limit)
+	limiter.now = clock.Now
+	limiter.lastRefill = clock.Now()
+	return &limiter, clock
+}
+
+func TestTake(t *testing.T) {
+	limiter, clock := newTestLimiter(2, 5)
+
+	require.True(t, limiter.Take(3))
+	require.True(t, limiter.Take(2))
+	require.False(t, limiter.Take(0.5))
+
+	clock.Advance(time.Second)
+	require.False(t, limiter.Take(3), "a failed take takes nothing")
+	require.True(t, limiter.Take(2))
+	require.False(t, limiter.Has(0.5))
+}
+
+func TestUpdateCapsAtLimit(t *testing.T) {
+	limiter, clock := newTestLimiter(2, 5)
+	require.True(t, limiter.Take(5))
+
+	// Idle for much longer than it takes to refill
+	clock.Advance(time.Hour)
+	require.True(t, limiter.Full())
+	require.True(t, limiter.Take(5))
+	require.False(t, limiter.Take(0.5), "tokens accumulated past the limit")
+
+	// Partial refills add up
+	clock.Advance(500 * time.Millisecond)
+	clock.Advance(500 * time.Millisecond)
+	require.True(t, limiter.Take(2))
+	require.False(t, limiter.Has(0.5))
+}
+
+func TestUntil(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   float64
+		limit  float64
+		taken  float64
+		tokens float64
+		want   time.Duration
+	}{
+		{"available", 2, 5, 0, 5, 0},
+		{"exactly available", 2, 5, 3, 2, 0},
+		{"missing one token", 2, 5, 5, 1, 500 * time.Millisecond},
+		{"missing some tokens", 2, 5, 4, 3, time.Second},
+		{"above limit waits until full", 2, 5, 5, 100, 2500 * time.Millisecond},
+		{"never refilled", 0, 5, 5, 1, time.Duration(math.MaxInt64)},
+		{"too slow to represent", 1e-12, 5, 5, 1, time.Duration(math.MaxInt64)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter, clock := newTestLimiter(test.rate, test.limit)
+			require.True(t, limiter.Take(test.taken))
+
+			until := limiter.Until(test.tokens)
+			require.Equal(t, test.want, until)
+			if until == 0 || until == time.Duration(math.MaxInt64) {
+				return
+			}
+
+			clock.Advance(until - time.Millisecond)
+			require.False(t, limiter.Has(min(test.tokens, test.limit)))
+			clock.Advance(time.Millisecond)
+			require.True(t, limiter.Has(min(test.tokens, test.limit)))
+		})
+	}
+}
+
+func TestFull(t *testing.T) {
+	limiter, clock := newTestLimiter(2, 5)
+	require.True(t, limiter.Full())
+
+	require.True(t, limiter.Take(1))
+	require.False(t, limiter.Full())
+
+	clock.Advance(499 * time.Millisecond)
+	require.False(t, limiter.Full())
+	clock.Advance(time.Millisecond)
+	require.True(t, limiter.Full())
+
+	require.True(t, limiter.Take(5))
+	limiter.Fill()
+	require.True(t, limiter.Full())
+}
+
+func TestSetRateKeepsRefilledTokens(t *testing.T) {
+	limiter, clock := newTestLimiter(2, 5)
+	require.True(t, limiter.Take(5))
+
+	// Tokens refilled at the old rate are kept
+	clock.Advance(time.Second)
+	limiter.SetRate(0)
+	clock.Advance(time.Hour)
+	require.True(t, limiter.Take(2))
+	require.False(t, limiter.Has(0.5))
+}
@@ -49,6 +49,12 @@ func (id ID) Time() (msSinceEpoch int64) {
 	return (int64(id) >> timeShift) + Epoch
 }
 
+// FromTime returns the smallest ID that could be generated at the given
+// unix time in milliseconds, useful for filtering IDs by time
+func FromTime(unixMillis int64) ID {
+	return ID(max(unixMillis-Epoch, 0) << timeShift)
+}
+
 func (id ID) Node() int64 {
 	return int64(id) & nodeMask >> nodeShift
 }
@@ -0,0 +1,210 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package sshkey provides typed public keys, stored in the SSH wire format
+// so ed25519, ECDSA and RSA identities can be told apart and verified.
+package sshkey
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// MaxSize is the largest wire format size of an accepted public key
+	MaxSize = 2048
+	// MinRSABits is the smallest accepted RSA modulus
+	MinRSABits = 2048
+)
+
+var (
+	ErrInvalid     = errors.New("invalid public key")
+	ErrUnsupported = errors.New("unsupported key type, must be ed25519, ecdsa or rsa")
+	ErrWeakRSA     = fmt.Errorf("rsa keys must be at least %v bits", MinRSABits)
+)
+
+// PublicKey is a public key in the SSH wire format, which starts with its type
+type PublicKey []byte
+
+func FromEd25519(key ed25519.PublicKey) PublicKey {
+	sshKey, err := ssh.NewPublicKey(key)
+	if err != nil {
+		return nil
+	}
+	return sshKey.Marshal()
+}
+
+func FromSSH(key ssh.PublicKey) PublicKey {
+	return key.Marshal()
+}
+
+// Parse parses a key in the authorized keys format, a key without
+// a type prefix is assumed to be an ed25519 key
+func Parse(s string) (PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if len(strings.Fields(s)) == 1 {
+		s = ssh.KeyAlgoED25519 + " " + s
+	}
+
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	key := FromSSH(sshKey)
+	return key, key.Validate()
+}
+
+func (k PublicKey) SSH() (ssh.PublicKey, error) {
+	return ssh.ParsePublicKey(k)
+}
+
+// Validate returns an error if the key is malformed or of an unsupported type
+func (k PublicKey) Validate() error {
+	if len(k) > MaxSize {
+		return ErrInvalid
+	}
+	sshKey, err := k.SSH()
+	if err != nil {
+		return ErrInvalid
+	}
+
+	switch sshKey.Type() {
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return nil
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return ErrInvalid
+		}
+		rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalid
+		}
+		if rsaKey.N.BitLen() < MinRSABits {
+			return ErrWeakRSA
+		}
+		return nil
+	default:
+		return ErrUnsupported
+	}
+}
+
+// Type returns the SSH key type, such as ssh-ed25519, or "" if malformed
+func (k PublicKey) Type() string {
+	sshKey, err := k.SSH()
+	if err != nil {
+		return ""
+	}
+	return sshKey.Type()
+}
+
+// Ed25519 returns the raw ed25519 key, or nil if it isn't an ed25519 key
+func (k PublicKey) Ed25519() ed25519.PublicKey {
+	sshKey, err := k.SSH()
+	if err != nil || sshKey.Type() != ssh.KeyAlgoED25519 {
+		return nil
+	}
+	cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil
+	}
+	key, _ := cryptoKey.CryptoPublicKey().(ed25519.PublicKey)
+	return key
+}
+
+// Compact returns the raw key for ed25519 keys, which were used before keys
+// were typed, and the wire format otherwise. Signed payloads embed keys in
+// this form, so signatures made before keys were typed remain valid.
+func (k PublicKey) Compact() []byte {
+	if key := k.Ed25519(); key != nil {
+		return key
+	}
+	return k
+}
+
+// Fingerprint returns the SHA256 fingerprint, as displayed by ssh-keygen -l
+func (k PublicKey) Fingerprint() string {
+	sshKey, err := k.SSH()
+	if err != nil {
+		return "invalid key"
+	}
+	return ssh.FingerprintSHA256(sshKey)
+}
+
+// String returns the key in the authorized keys format, without a comment
+func (k PublicKey) String() string {
+	sshKey, err := k.SSH()
+	if err != nil {
+		return "invalid key"
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshKey)))
+}
+
+func (k PublicKey) Equal(other PublicKey) bool {
+	return bytes.Equal(k, other)
+}
+
+// signatureFormat returns the format of signatures made by the key type,
+// RSA keys sign with SHA-256 rather than the deprecated SHA-1
+func signatureFormat(keyType string) string {
+	if keyType == ssh.KeyAlgoRSA {
+		return ssh.KeyAlgoRSASHA256
+	}
+	return keyType
+}
+
+// Verify returns whether the signature, as returned by Sign,
+// is a valid signature of the message by the key
+func Verify(key PublicKey, message, signature []byte) bool {
+	sshKey, err := key.SSH()
+	if err != nil {
+		return false
+	}
+	err = sshKey.Verify(message, &ssh.Signature{
+		Format: signatureFormat(sshKey.Type()),
+		Blob:   signature,
+	})
+	return err == nil
+}
+
+// Sign signs the message, the returned signature is the
+// signature blob, its format is implied by the signer's key type
+func Sign(signer ssh.Signer, message []byte) ([]byte, error) {
+	format := signatureFormat(signer.PublicKey().Type())
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, message, format)
+	} else {
+		signature, err = signer.Sign(rand.Reader, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if signature.Format != format {
+		return nil, fmt.Errorf("unexpected signature format: %v", signature.Format)
+	}
+	return signature.Blob, nil
+}
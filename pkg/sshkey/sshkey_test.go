@@ -0,0 +1,245 @@
+// Eko: A terminal-native social media platform
+// Copyright (C) 2025 Kyren223
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sshkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+type testKey struct {
+	name   string
+	signer ssh.Signer
+}
+
+func newSigner(t *testing.T, privateKey any) ssh.Signer {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+	return signer
+}
+
+// testKeys returns a signer for each supported key type
+func testKeys(t *testing.T) []testKey {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSABits)
+	require.NoError(t, err)
+
+	return []testKey{
+		{"ed25519", newSigner(t, edKey)},
+		{"ecdsa p256", newSigner(t, ecKey)},
+		{"rsa 2048", newSigner(t, rsaKey)},
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	keys := testKeys(t)
+	message := []byte("hello")
+
+	for i, key := range keys {
+		t.Run(key.name, func(t *testing.T) {
+			publicKey := FromSSH(key.signer.PublicKey())
+			require.NoError(t, publicKey.Validate())
+
+			signature, err := Sign(key.signer, message)
+			require.NoError(t, err)
+			require.True(t, Verify(publicKey, message, signature))
+
+			require.False(t, Verify(publicKey, []byte("hellO"), signature))
+			tampered := append([]byte(nil), signature...)
+			tampered[len(tampered)-1] ^= 0x01
+			require.False(t, Verify(publicKey, message, tampered))
+			require.False(t, Verify(publicKey, message, nil))
+			require.False(t, Verify(nil, message, signature))
+
+			// A signature by one key isn't valid for any other key
+			for j, other := range keys {
+				if i != j {
+					require.False(t, Verify(FromSSH(other.signer.PublicKey()), message, signature), other.name)
+				}
+			}
+		})
+	}
+}
+
+func TestSignRSA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSABits)
+	require.NoError(t, err)
+	signer := newSigner(t, rsaKey).(ssh.AlgorithmSigner)
+	publicKey := FromSSH(signer.PublicKey())
+	message := []byte("hello")
+
+	signature, err := Sign(signer, message)
+	require.NoError(t, err)
+	err = signer.PublicKey().Verify(message, &ssh.Signature{Format: ssh.KeyAlgoRSASHA256, Blob: signature})
+	require.NoError(t, err, "rsa signatures must be rsa-sha2-256")
+
+	sha1, err := signer.SignWithAlgorithm(rand.Reader, message, ssh.KeyAlgoRSA)
+	require.NoError(t, err)
+	require.Equal(t, ssh.KeyAlgoRSA, sha1.Format)
+	require.False(t, Verify(publicKey, message, sha1.Blob), "ssh-rsa signatures use SHA-1")
+
+	sha512, err := signer.SignWithAlgorithm(rand.Reader, message, ssh.KeyAlgoRSASHA512)
+	require.NoError(t, err)
+	require.False(t, Verify(publicKey, message, sha512.Blob))
+}
+
+// sha1Signer only signs with ssh-rsa, like old agents
+type sha1Signer struct {
+	ssh.Signer
+}
+
+func TestSignRejectsSHA1Signer(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSABits)
+	require.NoError(t, err)
+
+	_, err = Sign(sha1Signer{newSigner(t, rsaKey)}, []byte("hello"))
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024) // #nosec G403 -- must be rejected
+	require.NoError(t, err)
+	weakPublicKey := FromSSH(newSigner(t, weakKey).PublicKey())
+
+	_, edKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	edSigner := newSigner(t, edKey)
+	certificate := &ssh.Certificate{
+		Key:         edSigner.PublicKey(),
+		CertType:    ssh.UserCert,
+		ValidBefore: ssh.CertTimeInfinity,
+	}
+	require.NoError(t, certificate.SignCert(rand.Reader, edSigner))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		key     PublicKey
+		wantErr error
+	}{
+		{"ed25519", FromSSH(edSigner.PublicKey()), nil},
+		{"ecdsa p384", FromSSH(newSigner(t, ecKey).PublicKey()), nil},
+		{"weak rsa", weakPublicKey, ErrWeakRSA},
+		{"certificate", FromSSH(certificate), ErrUnsupported},
+		{"empty", nil, ErrInvalid},
+		{"malformed", PublicKey("not a key"), ErrInvalid},
+		{"truncated", FromSSH(edSigner.PublicKey())[:20], ErrInvalid},
+		{"too large", make(PublicKey, MaxSize+1), ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.key.Validate(), test.wantErr)
+			if test.wantErr != nil || test.key == nil {
+				return
+			}
+
+			// Parse validates too
+			parsed, err := Parse(test.key.String())
+			require.NoError(t, err)
+			require.Equal(t, test.key, parsed)
+		})
+	}
+
+	_, err = Parse(weakPublicKey.String())
+	require.ErrorIs(t, err, ErrWeakRSA)
+	_, err = Parse(string(ssh.MarshalAuthorizedKey(certificate)))
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestParse(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	key := FromEd25519(edKey.Public().(ed25519.PublicKey))
+	authorized := key.String()
+	_, encoded, _ := strings.Cut(authorized, " ")
+
+	tests := []struct {
+		name    string
+		s       string
+		wantErr bool
+	}{
+		{"authorized key", authorized, false},
+		{"with comment", authorized + " user@host", false},
+		{"surrounding whitespace", "  " + authorized + "\n", false},
+		{"without type", encoded, false},
+		{"empty", "", true},
+		{"garbage", "ssh-ed25519 AAAA", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := Parse(test.s)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, key, parsed)
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	for _, key := range testKeys(t) {
+		t.Run(key.name, func(t *testing.T) {
+			publicKey := FromSSH(key.signer.PublicKey())
+			compact := publicKey.Compact()
+
+			if edKey := publicKey.Ed25519(); edKey != nil {
+				// Untyped ed25519 keys, as stored before keys were typed
+				require.Equal(t, []byte(edKey), compact)
+				require.Equal(t, publicKey, FromEd25519(compact))
+			} else {
+				require.Equal(t, []byte(publicKey), compact)
+			}
+
+			parsed, err := Parse(publicKey.String())
+			require.NoError(t, err)
+			require.Equal(t, compact, parsed.Compact())
+			require.True(t, publicKey.Equal(parsed))
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	keys := testKeys(t)
+	for _, key := range keys {
+		publicKey := FromSSH(key.signer.PublicKey())
+		require.Equal(t, ssh.FingerprintSHA256(key.signer.PublicKey()), publicKey.Fingerprint())
+		require.True(t, strings.HasPrefix(publicKey.Fingerprint(), "SHA256:"))
+		require.Equal(t, key.signer.PublicKey().Type(), publicKey.Type())
+	}
+	require.NotEqual(t, FromSSH(keys[0].signer.PublicKey()).Fingerprint(), FromSSH(keys[1].signer.PublicKey()).Fingerprint())
+	require.Equal(t, "invalid key", PublicKey("nope").Fingerprint())
+	require.Equal(t, "", PublicKey("nope").Type())
+}
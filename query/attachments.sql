@@ -0,0 +1,48 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (
+  id, uploader_id, name, size, hash
+) VALUES (
+  ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetAttachmentById :one
+SELECT * FROM attachments
+WHERE id = ?;
+
+-- name: GetAttachmentsByIds :many
+SELECT * FROM attachments
+WHERE id IN (sqlc.slice('ids'));
+
+-- name: CanAccessAttachment :one
+SELECT CAST(EXISTS (
+  SELECT 1 FROM attachments
+  WHERE id = @attachment_id AND uploader_id = @user_id
+) OR EXISTS (
+  SELECT 1 FROM messages
+  WHERE messages.attachment_id = @attachment_id AND (
+    (messages.frequency_id IS NOT NULL AND EXISTS (
+      SELECT 1 FROM frequencies f
+      JOIN members m ON m.network_id = f.network_id
+      WHERE f.id = messages.frequency_id AND m.user_id = @user_id
+      AND m.is_member = true AND (f.perms != 0 OR m.is_admin = true)
+    )) OR
+    (messages.receiver_id IS NOT NULL AND
+      (messages.sender_id = @user_id OR messages.receiver_id = @user_id)) OR
+    (messages.group_id IS NOT NULL AND EXISTS (
+      SELECT 1 FROM group_signal_members gm
+      WHERE gm.group_id = messages.group_id AND gm.user_id = @user_id
+    ))
+  )
+) AS BOOLEAN) AS can_access;
+
+-- name: DeleteUnreferencedAttachments :execrows
+DELETE FROM attachments
+WHERE id < @before AND NOT EXISTS (
+  SELECT 1 FROM messages WHERE messages.attachment_id = attachments.id
+);
+
+-- name: IsBlobReferenced :one
+SELECT CAST(EXISTS (
+  SELECT 1 FROM attachments WHERE hash = ?
+) AS BOOLEAN) AS is_referenced;
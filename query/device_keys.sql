@@ -0,0 +1,32 @@
+-- name: CreateDeviceKey :one
+INSERT INTO device_keys (
+  id, user_id, public_key, name, authorized_by, signature
+) VALUES (
+  ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetDeviceKeyByPublicKey :one
+SELECT * FROM device_keys
+WHERE public_key = ?;
+
+-- name: GetDeviceKeyById :one
+SELECT * FROM device_keys
+WHERE id = ? AND user_id = ?;
+
+-- name: GetUserDeviceKeys :many
+SELECT * FROM device_keys
+WHERE user_id = ?
+ORDER BY id;
+
+-- name: GetDeviceKeys :many
+SELECT * FROM device_keys
+WHERE user_id = @user_id OR user_id IN (
+  SELECT trusted_user_id FROM trusted_users
+  WHERE trusting_user_id = @user_id
+)
+ORDER BY id;
+
+-- name: DeleteDeviceKey :exec
+DELETE FROM device_keys
+WHERE id = ?;
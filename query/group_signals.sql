@@ -0,0 +1,40 @@
+-- name: CreateGroupSignal :one
+INSERT INTO group_signals (
+  id, name
+) VALUES (
+  ?, ?
+)
+RETURNING *;
+
+-- name: GetGroupSignalById :one
+SELECT * FROM group_signals
+WHERE id = ?;
+
+-- name: DeleteGroupSignal :exec
+DELETE FROM group_signals
+WHERE id = ?;
+
+-- name: GetUserGroupSignals :many
+SELECT group_signals.* FROM group_signals
+JOIN group_signal_members ON group_signals.id = group_signal_members.group_id
+WHERE group_signal_members.user_id = ?;
+
+-- name: GetGroupSignalMembers :many
+SELECT user_id FROM group_signal_members
+WHERE group_id = ?;
+
+-- name: AddGroupSignalMember :exec
+INSERT OR IGNORE INTO group_signal_members (
+  group_id, user_id
+) VALUES (
+  ?, ?
+);
+
+-- name: RemoveGroupSignalMember :exec
+DELETE FROM group_signal_members
+WHERE group_id = ? AND user_id = ?;
+
+-- name: GetGroupSignalMessages :many
+SELECT * FROM messages
+WHERE group_id = ?
+ORDER BY id;
@@ -0,0 +1,20 @@
+-- name: RotateUserPublicKey :one
+UPDATE users SET public_key = @new_public_key
+WHERE id = @id AND public_key = @old_public_key AND is_deleted = false
+RETURNING *;
+
+-- name: CreateKeyRotation :one
+INSERT INTO key_rotations (
+  id, user_id, old_public_key, new_public_key, signature
+) VALUES (
+  ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetKeyRotations :many
+SELECT * FROM key_rotations
+WHERE user_id = @user_id OR user_id IN (
+  SELECT trusted_user_id FROM trusted_users
+  WHERE trusting_user_id = @user_id
+)
+ORDER BY id;
@@ -0,0 +1,23 @@
+-- name: CreateMessageRequest :exec
+INSERT INTO message_requests (
+  sender_id, receiver_id, message_id
+) VALUES (
+  ?, ?, ?
+);
+
+-- name: GetMessageRequest :one
+SELECT * FROM message_requests
+WHERE sender_id = ? AND receiver_id = ?;
+
+-- name: GetMessageRequests :many
+SELECT messages.* FROM messages
+JOIN message_requests ON messages.id = message_requests.message_id
+WHERE message_requests.receiver_id = ? AND message_requests.is_ignored = false;
+
+-- name: IgnoreMessageRequest :exec
+UPDATE message_requests SET is_ignored = true
+WHERE sender_id = ? AND receiver_id = ?;
+
+-- name: DeleteMessageRequest :exec
+DELETE FROM message_requests
+WHERE sender_id = ? AND receiver_id = ?;
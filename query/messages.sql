@@ -16,19 +16,76 @@ ORDER BY id;
 
 -- name: CreateMessage :one
 INSERT INTO messages (
-  id, content, sender_id, frequency_id, receiver_id, ping
+  id, content, sender_id, frequency_id, receiver_id, group_id, ping, attachment_id, is_encrypted, signature
 ) VALUES (
-  ?, ?, ?, ?, ?, ?
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 )
 RETURNING *;
 
 -- name: EditMessage :one
 UPDATE messages SET
   edited = true,
-  content = ?
+  content = ?,
+  ping = ?,
+  signature = ?
 WHERE id = ?
 RETURNING *;
 
 -- name: DeleteMessage :exec
 DELETE FROM messages
 WHERE id = ?;
+
+-- name: SetMessagePinned :one
+UPDATE messages SET
+  pinned_at = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: InsertMessageMention :exec
+INSERT INTO message_mentions (
+  message_id, mention
+) VALUES (
+  ?, ?
+);
+
+-- name: DeleteMessageMentions :exec
+DELETE FROM message_mentions
+WHERE message_id = ?;
+
+-- name: SearchMessages :many
+SELECT messages.* FROM messages_fts
+JOIN messages ON messages.id = messages_fts.rowid
+WHERE messages_fts MATCH @query
+AND messages.is_encrypted = false -- The server can't search ciphertext
+AND (
+  (messages.frequency_id IS NOT NULL AND EXISTS (
+    SELECT 1 FROM frequencies f
+    JOIN members m ON m.network_id = f.network_id
+    WHERE f.id = messages.frequency_id AND m.user_id = @user_id
+    AND m.is_member = true AND (f.perms != 0 OR m.is_admin = true)
+  )) OR
+  (messages.receiver_id IS NOT NULL AND
+    (messages.sender_id = @user_id OR messages.receiver_id = @user_id)) OR
+  (messages.group_id IN (
+    SELECT group_id FROM group_signal_members WHERE user_id = @user_id
+  ))
+)
+AND (sqlc.narg(frequency_id) IS NULL OR messages.frequency_id = sqlc.narg(frequency_id))
+AND (sqlc.narg(network_id) IS NULL OR messages.frequency_id IN (
+  SELECT id FROM frequencies WHERE network_id = sqlc.narg(network_id)
+))
+AND (sqlc.narg(peer_id) IS NULL OR
+  (messages.sender_id = @user_id AND messages.receiver_id = sqlc.narg(peer_id)) OR
+  (messages.sender_id = sqlc.narg(peer_id) AND messages.receiver_id = @user_id) OR
+  (messages.group_id = sqlc.narg(peer_id) AND messages.group_id IN (
+    SELECT group_id FROM group_signal_members WHERE user_id = @user_id
+  ))
+)
+AND (sqlc.narg(sender_id) IS NULL OR messages.sender_id = sqlc.narg(sender_id))
+AND (sqlc.narg(after) IS NULL OR messages.id >= sqlc.narg(after))
+AND (sqlc.narg(before) IS NULL OR messages.id < sqlc.narg(before))
+AND (CAST(@has_mention AS BOOLEAN) = false OR EXISTS (
+  SELECT 1 FROM message_mentions mm WHERE mm.message_id = messages.id
+))
+ORDER BY messages.id DESC
+LIMIT @max_results;
@@ -11,3 +11,21 @@ WHERE user_id = EXCLUDED.user_id AND source_id = EXCLUDED.source_id;
 INSERT OR IGNORE INTO last_read_messages (
   user_id, source_id, last_read
 ) VALUES (?, ?, ?);
+
+-- name: GetReadReceipts :many
+-- Read positions of other users in their DM with the given user,
+-- only if both users have read receipts enabled
+SELECT last_read_messages.user_id, last_read_messages.last_read
+FROM last_read_messages
+JOIN users AS readers ON readers.id = last_read_messages.user_id
+JOIN users AS self ON self.id = last_read_messages.source_id
+WHERE last_read_messages.source_id = ?
+  AND readers.is_read_receipts = true AND self.is_read_receipts = true;
+
+-- name: GetReadPositions :many
+-- Read positions of the given user in their DMs
+-- with users that have read receipts enabled
+SELECT last_read_messages.source_id, last_read_messages.last_read
+FROM last_read_messages
+JOIN users ON users.id = last_read_messages.source_id
+WHERE last_read_messages.user_id = ? AND users.is_read_receipts = true;
@@ -6,6 +6,10 @@ WHERE trusting_user_id = ?;
 SELECT trusted_public_key FROM trusted_users
 WHERE trusting_user_id = ? AND trusted_user_id = ?;
 
+-- name: GetTrustingUsers :many
+SELECT trusting_user_id FROM trusted_users
+WHERE trusted_user_id = ?;
+
 -- name: TrustUser :exec
 INSERT OR IGNORE INTO trusted_users (
   trusting_user_id, trusted_user_id, trusted_public_key
@@ -33,7 +33,9 @@ RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users SET
-  name = ?, description = ?, is_public_dm = ?
+  name = ?, description = ?, is_public_dm = ?,
+  status = ?, is_dnd = ?, is_presence_public = ?,
+  is_read_receipts = ?
 WHERE id = ?
 RETURNING *;
 
@@ -47,3 +49,35 @@ WHERE id IN (sqlc.slice('ids'));
 
 -- name: UpdateUserLastActivity :exec
 UPDATE users SET last_activity = ? WHERE id = ?;
+
+-- name: GetPresenceAudience :many
+-- Users that may see the given user's presence
+SELECT others.user_id FROM members AS self
+JOIN members AS others ON others.network_id = self.network_id
+WHERE self.user_id = @user_id AND self.is_member = true
+  AND others.is_member = true AND others.user_id != @user_id
+UNION
+SELECT trusted_user_id FROM trusted_users
+WHERE trusting_user_id = @user_id
+EXCEPT
+SELECT blocked_user_id FROM blocked_users
+WHERE blocking_user_id = @user_id
+EXCEPT
+SELECT blocking_user_id FROM blocked_users
+WHERE blocked_user_id = @user_id;
+
+-- name: GetVisiblePresenceUsers :many
+-- Users whose presence the given user may see
+SELECT others.user_id FROM members AS self
+JOIN members AS others ON others.network_id = self.network_id
+WHERE self.user_id = @user_id AND self.is_member = true
+  AND others.is_member = true AND others.user_id != @user_id
+UNION
+SELECT trusting_user_id FROM trusted_users
+WHERE trusted_user_id = @user_id
+EXCEPT
+SELECT blocked_user_id FROM blocked_users
+WHERE blocking_user_id = @user_id
+EXCEPT
+SELECT blocking_user_id FROM blocked_users
+WHERE blocked_user_id = @user_id;
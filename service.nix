@@ -38,6 +38,24 @@ in
       type = lib.types.path;
     };
 
+    blobDir = lib.mkOption {
+      description = "Eko attachments directory";
+      default = "/var/lib/eko/blobs";
+      type = lib.types.path;
+    };
+
+    maxAttachmentBytes = lib.mkOption {
+      description = "Maximum size of a single attachment in bytes";
+      default = 8 * 1024 * 1024;
+      type = lib.types.ints.unsigned;
+    };
+
+    trustedProxies = lib.mkOption {
+      description = "Addresses or CIDRs of load balancers allowed to send a PROXY protocol header";
+      default = [ ];
+      type = lib.types.listOf lib.types.str;
+    };
+
     tosFile = lib.mkOption {
       description = "Eko terms of service file";
       default = "/etc/eko/tos.md";
@@ -104,6 +122,9 @@ in
       environment = {
         EKO_SERVER_CERT_FILE = cfg.certFile;
         EKO_SERVER_LOG_DIR = cfg.logDir;
+        EKO_SERVER_BLOB_DIR = cfg.blobDir;
+        EKO_SERVER_MAX_ATTACHMENT_BYTES = toString cfg.maxAttachmentBytes;
+        EKO_SERVER_TRUSTED_PROXIES = lib.concatStringsSep "," cfg.trustedProxies;
         EKO_SERVER_TOS_FILE = cfg.tosFile;
         EKO_SERVER_PRIVACY_FILE = cfg.privacyFile;
         USER = cfg.user;